@@ -558,7 +558,11 @@ func main() {
 				panic(fmt.Errorf("-depositMultiplier must be greater than 0, but %v provided. Restart the node with a valid value for -depositMultiplier", *depositMultiplier))
 			}
 
-			n.Sender = pm.NewSender(n.Eth, timeWatcher, senderWatcher, ev, *depositMultiplier)
+			sender, err := pm.NewSender(n.Eth, timeWatcher, senderWatcher, ev, *depositMultiplier, pm.SenderConfig{})
+			if err != nil {
+				panic(fmt.Errorf("Unable to create ticket sender: %v", err))
+			}
+			n.Sender = sender
 
 			if *pixelsPerUnit <= 0 {
 				// Can't divide by 0
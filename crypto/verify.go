@@ -25,6 +25,11 @@ func VerifySig(addr ethcommon.Address, msg, sig []byte) bool {
 	return recovered == addr
 }
 
+// RecoverSig returns the ETH address that produced a ETH ECDSA signature over a given message
+func RecoverSig(msg, sig []byte) (ethcommon.Address, error) {
+	return ecrecover(msg, sig)
+}
+
 func ecrecover(msg, sig []byte) (ethcommon.Address, error) {
 	if len(sig) != 65 {
 		return ethcommon.Address{}, errors.New("invalid signature length")
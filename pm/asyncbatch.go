@@ -0,0 +1,26 @@
+package pm
+
+import "context"
+
+// BatchResult is the result of creating a ticket batch asynchronously
+type BatchResult struct {
+	Batch *TicketBatch
+	Err   error
+}
+
+// CreateTicketBatchAsync creates a ticket batch of the specified size on a new goroutine like CreateTicketBatchCtx and
+// returns a channel that delivers exactly one BatchResult and is then closed. Cancelling the context stops signing the
+// tickets of the batch, in which case the result has the context's error. The channel is buffered so the goroutine
+// exits once the batch is created even if the result is never received
+func (s *sender) CreateTicketBatchAsync(ctx context.Context, sessionID string, size int) <-chan BatchResult {
+	results := make(chan BatchResult, 1)
+
+	go func() {
+		defer close(results)
+
+		batch, err := s.CreateTicketBatchCtx(ctx, sessionID, size)
+		results <- BatchResult{Batch: batch, Err: err}
+	}()
+
+	return results
+}
@@ -0,0 +1,85 @@
+package pm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveResults returns the results delivered by a channel until it is closed
+func receiveResults(t *testing.T, results <-chan BatchResult) []BatchResult {
+	var received []BatchResult
+	for {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				return received
+			}
+			received = append(received, res)
+		case <-time.After(time.Second):
+			t.Fatal("result channel was not closed")
+		}
+	}
+}
+
+func TestCreateTicketBatchAsync(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	results := receiveResults(t, sender.CreateTicketBatchAsync(context.Background(), sessionID, 3))
+	require.Len(results, 1)
+	require.Nil(results[0].Err)
+	require.Len(results[0].Batch.SenderParams, 3)
+	assert.Equal(uint32(1), results[0].Batch.SenderParams[0].SenderNonce)
+
+	// Errors are delivered as results
+	results = receiveResults(t, sender.CreateTicketBatchAsync(context.Background(), "foo", 1))
+	require.Len(results, 1)
+	assert.Nil(results[0].Batch)
+	assert.Equal(ErrUnknownSession, errors.Cause(results[0].Err))
+}
+
+func TestCreateTicketBatchAsync_Cancel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	signer := &hookSigner{onSign: func(calls int) {
+		if calls == 1 {
+			close(started)
+			<-unblock
+		}
+	}}
+	signer.account = sender.signer.Account()
+	sender.signer = signer
+
+	results := sender.CreateTicketBatchAsync(ctx, sessionID, 5)
+
+	// Cancel while the batch is being signed
+	<-started
+	cancel()
+	close(unblock)
+
+	received := receiveResults(t, results)
+	require.Len(received, 1)
+	assert.Nil(received[0].Batch)
+	assert.Equal(context.Canceled, received[0].Err)
+	assert.Equal(1, signer.calls)
+
+	// The nonces of the cancelled batch are given back
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+}
@@ -0,0 +1,157 @@
+package pm
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// atomicBatch is a batch signed by CreateBatchesAtomic that has not been issued yet
+type atomicBatch struct {
+	sessionID string
+	session   *session
+	batch     *TicketBatch
+	tickets   []*Ticket
+	hashes    []ethcommon.Hash
+}
+
+// CreateBatchesAtomic creates a ticket batch for every request or for none of them.
+// All requests are validated before any ticket is signed, and the nonces of the sessions are only advanced
+// after the tickets of every batch were signed. If any request fails, no session's nonce is advanced and
+// no ticket is issued. Ticket creation for the sessions of the requests blocks until the batches are created.
+// Deposit reserved with the DepositCoordinator for the requests is not released if a request fails
+func (s *sender) CreateBatchesAtomic(requests []BatchRequest) ([]*TicketBatch, error) {
+	sessionIDs := make([]string, len(requests))
+	seen := make(map[string]bool)
+	totalTickets := 0
+	for i, req := range requests {
+		if req.Size < 1 {
+			return nil, ErrEmptyBatch
+		}
+
+		if seen[req.SessionID] {
+			return nil, fmt.Errorf("multiple requests for session %v", req.SessionID)
+		}
+		seen[req.SessionID] = true
+
+		sessionIDs[i] = req.SessionID
+		totalTickets += req.Size
+	}
+
+	// Holding the write locks of the sessions ensures that no nonces are allocated for them by other calls
+	// so that the nonces following the current nonce of each session can be signed before they are allocated
+	unlock := s.sessionLocks.lockAll(sessionIDs)
+	defer unlock()
+
+	// A single signing slot is acquired for the tickets of all requests below
+	sessions := make([]*session, len(requests))
+	reservations := make([]*ticketReservation, 0, len(requests))
+	defer func() {
+		for _, reservation := range reservations {
+			reservation.release()
+		}
+	}()
+	for i, req := range requests {
+		session, reservation, err := s.reserveTickets(req.SessionID, req.Size, reserveOpts{skipSigningSlot: true})
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+
+		if err := s.checkNonceLimit(atomic.LoadUint32(&session.senderNonce), req.Size); err != nil {
+			return nil, err
+		}
+
+		sessions[i] = session
+	}
+
+	release := s.acquireSigningSlot(totalTickets)
+	defer release()
+
+	signed := make([]*atomicBatch, len(requests))
+	for i, req := range requests {
+		b, err := s.signAtomicBatch(req.SessionID, sessions[i], req.Size)
+		if err != nil {
+			return nil, err
+		}
+		signed[i] = b
+	}
+
+	for _, b := range signed {
+		lastNonce := atomic.LoadUint32(&b.session.senderNonce) + uint32(len(b.tickets))
+		if err := s.persistNonce(b.sessionID, lastNonce); err != nil {
+			if s.cfg.StrictPersistence {
+				return nil, err
+			}
+			glog.Errorf("error persisting session nonce sessionID=%v nonce=%v err=%v", b.sessionID, lastNonce, err)
+		}
+	}
+
+	for _, reservation := range reservations {
+		reservation.commit()
+	}
+
+	batches := make([]*TicketBatch, len(signed))
+	for i, b := range signed {
+		numTickets := len(b.tickets)
+		firstNonce := b.tickets[0].SenderNonce
+
+		atomic.StoreUint32(&b.session.senderNonce, firstNonce+uint32(numTickets)-1)
+		b.session.recentNonces.add(firstNonce, numTickets)
+		b.session.nonceFilter.add(firstNonce, numTickets)
+		atomic.AddUint64(&b.session.ticketsCreated, uint64(numTickets))
+
+		for j, ticket := range b.tickets {
+			s.recordIssuedTicket(b.sessionID, b.session, ticket, b.hashes[j])
+		}
+		s.observeBatch(b.sessionID, b.batch)
+		s.recordLastBatchTicket(b.session, b.batch)
+
+		batches[i] = b.batch
+	}
+
+	return batches, nil
+}
+
+// signAtomicBatch signs a batch of numTickets tickets for a session using the nonces following the
+// session's current nonce without allocating the nonces
+func (s *sender) signAtomicBatch(sessionID string, session *session, numTickets int) (*atomicBatch, error) {
+	firstNonce := atomic.LoadUint32(&session.senderNonce) + 1
+	if err := session.recentNonces.check(firstNonce, numTickets); err != nil {
+		return nil, err
+	}
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &atomicBatch{
+		sessionID: sessionID,
+		session:   session,
+		batch: &TicketBatch{
+			TicketParams:           &session.ticketParams,
+			TicketExpirationParams: expirationParams,
+			Sender:                 s.sessionSigner(session).Account().Address,
+		},
+	}
+
+	for i := 0; i < numTickets; i++ {
+		senderNonce := firstNonce + uint32(i)
+		ticket := NewTicket(&session.ticketParams, expirationParams, s.sessionSigner(session).Account().Address, senderNonce)
+		hash := s.ticketHash(ticket)
+		sig, err := s.sign(s.sessionSigner(session), hash.Bytes())
+		if err != nil {
+			return nil, SignerError{errors.Wrapf(err, "error signing ticket for session: %v", sessionID)}
+		}
+
+		b.tickets = append(b.tickets, ticket)
+		b.hashes = append(b.hashes, hash)
+		b.batch.SenderParams = append(b.batch.SenderParams, &TicketSenderParams{SenderNonce: senderNonce, Sig: sig})
+	}
+
+	return b, nil
+}
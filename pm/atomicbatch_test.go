@@ -0,0 +1,117 @@
+package pm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatchesAtomic(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	sessionID0 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	sessionID1 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID1, 1)
+	require.Nil(err)
+
+	batches, err := sender.CreateBatchesAtomic([]BatchRequest{
+		{SessionID: sessionID0, Size: 2},
+		{SessionID: sessionID1, Size: 3},
+	})
+	require.Nil(err)
+	require.Len(batches, 2)
+	assert.Equal([]uint32{1, 2}, batchNonces(batches[0]))
+	assert.Equal([]uint32{2, 3, 4}, batchNonces(batches[1]))
+	assert.Equal(int64(5), batches[1].CreationRound)
+
+	assertNonces := func(nonces map[string]uint32) {
+		for _, info := range sender.ListSessions() {
+			assert.Equal(nonces[info.SessionID], info.SenderNonce)
+		}
+	}
+	assertNonces(map[string]uint32{sessionID0: 2, sessionID1: 4})
+
+	records, err := sender.IssuanceLog(sessionID1)
+	require.Nil(err)
+	assert.Len(records, 4)
+
+	// Tickets created afterwards use the following nonces
+	batch, err := sender.CreateTicketBatch(sessionID0, 1)
+	require.Nil(err)
+	assert.Equal([]uint32{3}, batchNonces(batch))
+	assertNonces(map[string]uint32{sessionID0: 3, sessionID1: 4})
+}
+
+func TestCreateBatchesAtomic_Failure_NoNonceAdvanced(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	sessionID0 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	sessionID1 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	sessionID2 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	assertNoNonceAdvanced := func() {
+		for _, info := range sender.ListSessions() {
+			assert.Equal(uint32(0), info.SenderNonce)
+			records, err := sender.IssuanceLog(info.SessionID)
+			assert.Nil(err)
+			assert.Empty(records)
+		}
+	}
+
+	requests := []BatchRequest{
+		{SessionID: sessionID0, Size: 2},
+		{SessionID: sessionID1, Size: 2},
+		{SessionID: sessionID2, Size: 3},
+	}
+
+	// Signing fails for the last request after the tickets of the first two requests were signed
+	sender.signer = &flakySigner{stubSigner: *sender.signer.(*stubSigner), failEvery: 5}
+	_, err := sender.CreateBatchesAtomic(requests)
+	_, ok := err.(SignerError)
+	assert.True(ok)
+	assertNoNonceAdvanced()
+
+	// Validation fails for one of the requests
+	sender.signer = &sender.signer.(*flakySigner).stubSigner
+	sender.maxEV.SetInt64(0)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue.SetInt64(10)
+	ticketParams.WinProb.Set(maxWinProb)
+	sessionID3 := sender.StartSession(ticketParams)
+	_, err = sender.CreateBatchesAtomic(append(requests, BatchRequest{SessionID: sessionID3, Size: 1}))
+	_, ok = err.(ValidationError)
+	assert.True(ok)
+	assertNoNonceAdvanced()
+
+	_, err = sender.CreateBatchesAtomic(append(requests, BatchRequest{SessionID: "foo", Size: 1}))
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	_, err = sender.CreateBatchesAtomic(append(requests, BatchRequest{SessionID: sessionID0, Size: 1}))
+	assert.EqualError(err, fmt.Sprintf("multiple requests for session %v", sessionID0))
+
+	_, err = sender.CreateBatchesAtomic(append(requests, BatchRequest{SessionID: sessionID3, Size: 0}))
+	assert.Equal(ErrEmptyBatch, err)
+
+	sender.cfg.MaxTicketsPerRound = 2
+	_, err = sender.CreateBatchesAtomic(requests)
+	assert.Equal(ErrRoundQuotaExhausted, err)
+	assertNoNonceAdvanced()
+}
+
+func batchNonces(batch *TicketBatch) []uint32 {
+	var nonces []uint32
+	for _, senderParams := range batch.SenderParams {
+		nonces = append(nonces, senderParams.SenderNonce)
+	}
+
+	return nonces
+}
@@ -0,0 +1,97 @@
+package pm
+
+import (
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// defaultAuditQueueSize is the size of the queue of validation decisions waiting to be recorded
+const defaultAuditQueueSize = 100
+
+// ValidationDecision describes the decision made by ValidateTicketParams for a set of ticket params
+type ValidationDecision struct {
+	Accepted bool
+	// Reason is the error that the ticket params were rejected with. Empty if the ticket params were accepted
+	Reason string
+
+	Recipient         ethcommon.Address
+	RecipientRandHash ethcommon.Hash
+	FaceValue         *big.Int
+	WinProb           *big.Int
+
+	// Deposit is the sender's deposit observed during validation. Nil if the sender info could not be fetched
+	Deposit *big.Int
+
+	Timestamp time.Time
+}
+
+// AuditSink records the validation decisions of a sender, e.g. in an append-only audit trail.
+// RecordValidation is called from a single goroutine in the order that the decisions were made
+type AuditSink interface {
+	RecordValidation(decision ValidationDecision)
+}
+
+// newAuditQueue returns the queue of validation decisions for an AuditSink or nil if no sink is configured
+func newAuditQueue(cfg SenderConfig) chan ValidationDecision {
+	if cfg.AuditSink == nil {
+		return nil
+	}
+
+	size := cfg.AuditQueueSize
+	if size <= 0 {
+		size = defaultAuditQueueSize
+	}
+
+	return make(chan ValidationDecision, size)
+}
+
+// audit queues a validation decision for the AuditSink. The decision is dropped instead of
+// blocking validation if the queue is full
+func (s *sender) audit(ticketParams *TicketParams, info *SenderInfo, err error) {
+	if s.audits == nil {
+		return
+	}
+
+	decision := ValidationDecision{
+		Accepted:  err == nil,
+		Timestamp: timeNow(),
+	}
+	if err != nil {
+		decision.Reason = err.Error()
+	}
+	if ticketParams != nil {
+		decision.Recipient = ticketParams.Recipient
+		decision.RecipientRandHash = ticketParams.RecipientRandHash
+		decision.FaceValue = copyBigInt(ticketParams.FaceValue)
+		decision.WinProb = copyBigInt(ticketParams.WinProb)
+	}
+	if info != nil {
+		decision.Deposit = copyBigInt(info.Deposit)
+	}
+
+	select {
+	case s.audits <- decision:
+	default:
+		atomic.AddUint64(&s.droppedAudits, 1)
+	}
+}
+
+// DroppedAudits returns the number of validation decisions that were not recorded because the audit queue was full
+func (s *sender) DroppedAudits() uint64 {
+	return atomic.LoadUint64(&s.droppedAudits)
+}
+
+// startAuditLoop passes queued validation decisions to the AuditSink until the sender is stopped
+func (s *sender) startAuditLoop() {
+	for {
+		select {
+		case decision := <-s.audits:
+			s.cfg.AuditSink.RecordValidation(decision)
+		case <-s.quit:
+			return
+		}
+	}
+}
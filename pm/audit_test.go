@@ -0,0 +1,99 @@
+package pm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAuditSink struct {
+	decisions chan ValidationDecision
+}
+
+func (s *recordingAuditSink) RecordValidation(decision ValidationDecision) {
+	s.decisions <- decision
+}
+
+func TestAuditSink_RecordsDecisionsInOrder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	sink := &recordingAuditSink{decisions: make(chan ValidationDecision, 10)}
+	sender := defaultSender(t)
+	sender.cfg.AuditSink = sink
+	sender.audits = newAuditQueue(sender.cfg)
+	sender.Start()
+	defer sender.Stop()
+
+	accepted := defaultTicketParams(t, RandAddress())
+	tooHigh := defaultTicketParams(t, RandAddress())
+	tooHigh.FaceValue = big.NewInt(50001)
+
+	assert.Nil(sender.ValidateTicketParams(&accepted))
+	assert.NotNil(sender.ValidateTicketParams(&tooHigh))
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.err = errors.New("GetSenderInfo error")
+	assert.NotNil(sender.ValidateTicketParams(&accepted))
+	sm.err = nil
+	assert.Nil(sender.ValidateTicketParams(&accepted))
+
+	var decisions []ValidationDecision
+	for i := 0; i < 4; i++ {
+		select {
+		case decision := <-sink.decisions:
+			decisions = append(decisions, decision)
+		case <-time.After(time.Second):
+			require.FailNow("timed out waiting for validation decision")
+		}
+	}
+
+	assert.True(decisions[0].Accepted)
+	assert.Empty(decisions[0].Reason)
+	assert.Equal(accepted.Recipient, decisions[0].Recipient)
+	assert.Equal(accepted.RecipientRandHash, decisions[0].RecipientRandHash)
+	assert.Equal(big.NewInt(100000), decisions[0].Deposit)
+	assert.Equal(now, decisions[0].Timestamp)
+
+	assert.False(decisions[1].Accepted)
+	assert.Equal(maxFaceValueErrStr(big.NewInt(50001), big.NewInt(50000)), decisions[1].Reason)
+	assert.Equal(tooHigh.Recipient, decisions[1].Recipient)
+	assert.Equal(big.NewInt(50001), decisions[1].FaceValue)
+	assert.Equal(big.NewInt(100000), decisions[1].Deposit)
+
+	assert.False(decisions[2].Accepted)
+	assert.Equal("GetSenderInfo error", decisions[2].Reason)
+	assert.Nil(decisions[2].Deposit)
+
+	assert.True(decisions[3].Accepted)
+	assert.Equal(uint64(0), sender.DroppedAudits())
+}
+
+func TestAuditSink_FullQueue_DropsDecisions(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.AuditSink = &recordingAuditSink{decisions: make(chan ValidationDecision, 10)}
+	sender.cfg.AuditQueueSize = 1
+	sender.audits = newAuditQueue(sender.cfg)
+
+	// Validation does not block while no decisions are recorded
+	ticketParams := defaultTicketParams(t, RandAddress())
+	for i := 0; i < 3; i++ {
+		assert.Nil(sender.ValidateTicketParams(&ticketParams))
+	}
+	assert.Equal(uint64(2), sender.DroppedAudits())
+
+	// No decisions are queued without a sink
+	sender = defaultSender(t)
+	assert.Nil(sender.audits)
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+	assert.Equal(uint64(0), sender.DroppedAudits())
+}
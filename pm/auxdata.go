@@ -0,0 +1,82 @@
+package pm
+
+import (
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// TicketAuxHashVersion is the version of the hashing scheme of tickets created with CreateTicketWithAux.
+// The signed hash of these tickets is HashWithAux, which binds the ticket hash to the ticket's AuxDataHash.
+// The hash differs from the hash computed by the TicketBroker contract so the tickets can only be verified
+// and redeemed by recipients and contracts that implement this version
+const TicketAuxHashVersion = "livepeer-pm-ticket-aux-v1"
+
+// HashWithAux returns keccak256(TicketAuxHashVersion || ticketHash || auxDataHash), the hash signed for
+// tickets bound to an aux data hash. The version is included so that the signature of a ticket bound to
+// aux data can never be mistaken for the signature of a ticket without aux data
+func HashWithAux(ticketHash ethcommon.Hash, auxDataHash ethcommon.Hash) ethcommon.Hash {
+	return crypto.Keccak256Hash([]byte(TicketAuxHashVersion), ticketHash.Bytes(), auxDataHash.Bytes())
+}
+
+// signedHash returns the hash of a ticket signed by a sender that does not use a HashFunc, which is
+// HashWithAux for tickets with an AuxDataHash and Hash otherwise
+func (t *Ticket) signedHash() ethcommon.Hash {
+	if t.AuxDataHash == (ethcommon.Hash{}) {
+		return t.Hash()
+	}
+
+	return HashWithAux(t.Hash(), t.AuxDataHash)
+}
+
+// CreateTicketWithAux creates a signed ticket for a session that is bound to auxDataHash, e.g. the hash of
+// a segment that the ticket pays for. The ticket is signed over HashWithAux of the sender's ticket hash and
+// auxDataHash as described by TicketAuxHashVersion. The session's ticket params are validated and a nonce is
+// allocated for the ticket as for CreateTicketBatch
+func (s *sender) CreateTicketWithAux(sessionID string, auxDataHash ethcommon.Hash) (*Ticket, []byte, error) {
+	if auxDataHash == (ethcommon.Hash{}) {
+		return nil, nil, errors.New("missing aux data hash")
+	}
+
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, reservation, err := s.reserveTickets(sessionID, 1, reserveOpts{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reservation.release()
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		ticket *Ticket
+		hash   ethcommon.Hash
+		sig    []byte
+	)
+	err = s.issueNonces(sessionID, session, 1, func(senderNonce uint32) error {
+		ticket = NewTicket(&session.ticketParams, expirationParams, s.sessionSigner(session).Account().Address, senderNonce)
+		ticket.AuxDataHash = auxDataHash
+		hash = HashWithAux(s.ticketHash(ticket), auxDataHash)
+
+		var err error
+		sig, err = s.sign(s.sessionSigner(session), hash.Bytes())
+		if err != nil {
+			return SignerError{errors.Wrapf(err, "error signing ticket with aux data for session: %v", sessionID)}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reservation.commit()
+	s.recordIssuedTicket(sessionID, session, ticket, hash)
+	s.recordLastTicket(session, ticket, sig)
+
+	return ticket, sig, nil
+}
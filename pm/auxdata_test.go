@@ -0,0 +1,71 @@
+package pm
+
+import (
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashWithAux(t *testing.T) {
+	assert := assert.New(t)
+
+	ticketHash := RandHash()
+	auxDataHash := RandHash()
+
+	hash := HashWithAux(ticketHash, auxDataHash)
+	assert.NotEqual(ticketHash, hash)
+	assert.Equal(hash, HashWithAux(ticketHash, auxDataHash))
+	assert.NotEqual(hash, HashWithAux(ticketHash, RandHash()))
+	assert.NotEqual(hash, HashWithAux(RandHash(), auxDataHash))
+}
+
+func TestCreateTicketWithAux(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	info := sender.senderManager.(*stubSenderManager).info[sender.signer.Account().Address]
+	signer := newStubKeySigner()
+	sender.signer = signer
+	sender.senderManager.(*stubSenderManager).info[signer.Account().Address] = info
+	sender.cfg.IssuanceLogSize = 10
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, _, err := sender.CreateTicketWithAux(sessionID, ethcommon.Hash{})
+	assert.EqualError(err, "missing aux data hash")
+
+	auxDataHash := RandHash()
+	ticket, sig, err := sender.CreateTicketWithAux(sessionID, auxDataHash)
+	require.Nil(err)
+	assert.Equal(uint32(1), ticket.SenderNonce)
+	assert.Equal(auxDataHash, ticket.AuxDataHash)
+	assert.Nil(BindingCheck(ticket, sig))
+
+	// The aux data changes the signed ticket hash
+	plain := *ticket
+	plain.AuxDataHash = ethcommon.Hash{}
+	assert.NotEqual(plain.Hash(), ticket.signedHash())
+	assert.Equal(HashWithAux(plain.Hash(), auxDataHash), ticket.signedHash())
+	assert.Equal(ErrTicketNotBound, errors.Cause(BindingCheck(&plain, sig)))
+
+	// The signature does not match another artifact
+	other := *ticket
+	other.AuxDataHash = RandHash()
+	assert.Equal(ErrTicketNotBound, errors.Cause(BindingCheck(&other, sig)))
+
+	records, err := sender.IssuanceLog(sessionID)
+	require.Nil(err)
+	require.Len(records, 1)
+	assert.Equal(ticket.signedHash(), records[0].Hash)
+
+	// Tickets with aux data use the session's nonces
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(2), batch.SenderParams[0].SenderNonce)
+
+	_, _, err = sender.CreateTicketWithAux("foo", auxDataHash)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+}
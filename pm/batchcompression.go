@@ -0,0 +1,302 @@
+package pm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// compressedBatchVersion is the version of the format produced by CompressBatch
+const compressedBatchVersion = 1
+
+// CompressBatch returns a compact representation of a ticket batch for storage.
+// The shared params of the batch are encoded once and the nonces are encoded as runs of
+// consecutive nonces, so a batch created by the sender is encoded as its params, a single
+// nonce run and its signatures. Amounts in the params must not be negative
+func CompressBatch(batch *TicketBatch) ([]byte, error) {
+	if batch == nil || batch.TicketParams == nil || batch.TicketExpirationParams == nil {
+		return nil, errors.New("unable to compress batch with missing params")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(compressedBatchVersion)
+
+	if err := writeBatchParams(&buf, batch); err != nil {
+		return nil, err
+	}
+
+	// Nonces are encoded as (first nonce, length) runs of consecutive nonces
+	var runs [][2]uint32
+	for i, senderParams := range batch.SenderParams {
+		nonce := senderParams.SenderNonce
+		if i > 0 && nonce == runs[len(runs)-1][0]+runs[len(runs)-1][1] {
+			runs[len(runs)-1][1]++
+			continue
+		}
+		runs = append(runs, [2]uint32{nonce, 1})
+	}
+
+	writeUvarint(&buf, uint64(len(runs)))
+	for _, run := range runs {
+		writeUvarint(&buf, uint64(run[0]))
+		writeUvarint(&buf, uint64(run[1]))
+	}
+
+	for _, senderParams := range batch.SenderParams {
+		writeUvarint(&buf, uint64(len(senderParams.Sig)))
+		buf.Write(senderParams.Sig)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DecompressBatch parses a ticket batch compressed with CompressBatch
+func DecompressBatch(data []byte) (*TicketBatch, error) {
+	batch, err := decompressBatch(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "error decompressing batch")
+	}
+
+	return batch, nil
+}
+
+func decompressBatch(r *bytes.Reader) (*TicketBatch, error) {
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != compressedBatchVersion {
+		return nil, fmt.Errorf("unsupported version %v", version)
+	}
+
+	batch, err := readBatchParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	numRuns, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if numRuns > uint64(r.Len()) {
+		return nil, errors.New("invalid number of nonce runs")
+	}
+
+	var numTickets uint64
+	for i := uint64(0); i < numRuns; i++ {
+		first, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		// Each ticket has a signature length that is at least 1 byte long so the tickets of all runs must fit in the
+		// remaining data. The bound of the last nonce is checked without adding to first so the check cannot overflow
+		if length == 0 || first > math.MaxUint32 || length > math.MaxUint32-first+1 || numTickets+length > uint64(r.Len()) {
+			return nil, errors.New("invalid nonce run")
+		}
+		numTickets += length
+
+		for j := uint64(0); j < length; j++ {
+			batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: uint32(first + j)})
+		}
+	}
+
+	for _, senderParams := range batch.SenderParams {
+		sigLen, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if sigLen > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		senderParams.Sig = make([]byte, sigLen)
+		if err := readBytes(r, senderParams.Sig); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Len() > 0 {
+		return nil, errors.New("unexpected trailing data")
+	}
+
+	return batch, nil
+}
+
+// writeBatchParams writes the params shared by all tickets of a batch
+func writeBatchParams(buf *bytes.Buffer, batch *TicketBatch) error {
+	buf.Write(batch.Recipient.Bytes())
+	buf.Write(batch.Sender.Bytes())
+	buf.Write(batch.RecipientRandHash.Bytes())
+
+	for _, v := range []*big.Int{batch.FaceValue, batch.WinProb, batch.Seed, batch.ExpirationBlock} {
+		if err := writeBigInt(buf, v); err != nil {
+			return err
+		}
+	}
+
+	if batch.PricePerPixel == nil {
+		writeUvarint(buf, 0)
+	} else {
+		if batch.PricePerPixel.Sign() < 0 {
+			return errors.New("unable to compress batch with negative price per pixel")
+		}
+		writeUvarint(buf, 1)
+		writeBigInt(buf, batch.PricePerPixel.Num())
+		writeBigInt(buf, batch.PricePerPixel.Denom())
+	}
+
+	if batch.ExpirationParams == nil {
+		writeUvarint(buf, 0)
+	} else {
+		writeUvarint(buf, 1)
+		writeExpirationParams(buf, batch.ExpirationParams)
+	}
+	writeExpirationParams(buf, batch.TicketExpirationParams)
+
+	return nil
+}
+
+// readBatchParams parses the params written by writeBatchParams into a batch without tickets
+func readBatchParams(r *bytes.Reader) (*TicketBatch, error) {
+	params := &TicketParams{}
+	batch := &TicketBatch{TicketParams: params}
+
+	if err := readBytes(r, params.Recipient[:]); err != nil {
+		return nil, err
+	}
+	if err := readBytes(r, batch.Sender[:]); err != nil {
+		return nil, err
+	}
+	if err := readBytes(r, params.RecipientRandHash[:]); err != nil {
+		return nil, err
+	}
+
+	var err error
+	for _, v := range []**big.Int{&params.FaceValue, &params.WinProb, &params.Seed, &params.ExpirationBlock} {
+		if *v, err = readBigInt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := readFlag(r); err != nil {
+		return nil, err
+	} else if ok {
+		num, err := readBigInt(r)
+		if err != nil {
+			return nil, err
+		}
+		denom, err := readBigInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if num == nil || denom == nil || denom.Sign() == 0 {
+			return nil, errors.New("invalid price per pixel")
+		}
+		params.PricePerPixel = new(big.Rat).SetFrac(num, denom)
+	}
+
+	if ok, err := readFlag(r); err != nil {
+		return nil, err
+	} else if ok {
+		if params.ExpirationParams, err = readExpirationParams(r); err != nil {
+			return nil, err
+		}
+	}
+	if batch.TicketExpirationParams, err = readExpirationParams(r); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	buf.Write(b[:n])
+}
+
+// writeBigInt writes the length of a big.Int plus one followed by its bytes. A nil big.Int is written as 0
+func writeBigInt(buf *bytes.Buffer, v *big.Int) error {
+	if v == nil {
+		writeUvarint(buf, 0)
+		return nil
+	}
+
+	if v.Sign() < 0 {
+		return fmt.Errorf("unable to compress negative value %v", v)
+	}
+
+	b := v.Bytes()
+	writeUvarint(buf, uint64(len(b))+1)
+	buf.Write(b)
+
+	return nil
+}
+
+func writeExpirationParams(buf *bytes.Buffer, params *TicketExpirationParams) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], params.CreationRound)
+	buf.Write(b[:n])
+	buf.Write(params.CreationRoundBlockHash.Bytes())
+}
+
+func readBytes(r *bytes.Reader, b []byte) error {
+	_, err := io.ReadFull(r, b)
+	return err
+}
+
+func readFlag(r *bytes.Reader) (bool, error) {
+	flag, err := binary.ReadUvarint(r)
+	if err != nil {
+		return false, err
+	}
+	if flag > 1 {
+		return false, fmt.Errorf("invalid flag %v", flag)
+	}
+
+	return flag == 1, nil
+}
+
+func readBigInt(r *bytes.Reader) (*big.Int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n-1 > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, n-1)
+	if err := readBytes(r, b); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func readExpirationParams(r *bytes.Reader) (*TicketExpirationParams, error) {
+	round, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var blkHash ethcommon.Hash
+	if err := readBytes(r, blkHash[:]); err != nil {
+		return nil, err
+	}
+
+	return &TicketExpirationParams{CreationRound: round, CreationRoundBlockHash: blkHash}, nil
+}
@@ -0,0 +1,266 @@
+package pm
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressBatch_RoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.signer.(*stubSigner).signResponse = make([]byte, 65)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1000)
+	ticketParams.WinProb = big.NewInt(7)
+	sessionID := sender.StartSession(ticketParams)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1000)
+	require.Nil(err)
+
+	data, err := CompressBatch(batch)
+	require.Nil(err)
+	decompressed, err := DecompressBatch(data)
+	require.Nil(err)
+	assertBatchEqual(t, batch, decompressed)
+
+	raw, err := json.Marshal(batch)
+	require.Nil(err)
+	assert.True(len(data) < len(raw))
+}
+
+func TestCompressBatch_RandomBatches_RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	randBigInt := func() *big.Int {
+		if r.Intn(5) == 0 {
+			return nil
+		}
+		b := make([]byte, r.Intn(33))
+		r.Read(b)
+		return new(big.Int).SetBytes(b)
+	}
+
+	for i := 0; i < 500; i++ {
+		batch := &TicketBatch{
+			TicketParams: &TicketParams{
+				FaceValue:       randBigInt(),
+				WinProb:         randBigInt(),
+				Seed:            randBigInt(),
+				ExpirationBlock: randBigInt(),
+			},
+			TicketExpirationParams: &TicketExpirationParams{CreationRound: r.Int63() - r.Int63()},
+		}
+		r.Read(batch.Recipient[:])
+		r.Read(batch.Sender[:])
+		r.Read(batch.RecipientRandHash[:])
+		r.Read(batch.CreationRoundBlockHash[:])
+
+		if r.Intn(2) == 0 {
+			batch.PricePerPixel = big.NewRat(r.Int63(), r.Int63()+1)
+		}
+		if r.Intn(2) == 0 {
+			batch.ExpirationParams = &TicketExpirationParams{CreationRound: r.Int63()}
+			r.Read(batch.ExpirationParams.CreationRoundBlockHash[:])
+		}
+
+		nonce := r.Uint32()
+		for j := r.Intn(20); j > 0; j-- {
+			// Mix runs of consecutive nonces with gaps and repeated nonces
+			switch r.Intn(4) {
+			case 0:
+				nonce = r.Uint32()
+			case 1:
+			default:
+				nonce++
+			}
+			sig := make([]byte, r.Intn(70))
+			r.Read(sig)
+			batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: nonce, Sig: sig})
+		}
+
+		data, err := CompressBatch(batch)
+		require.Nil(t, err)
+		decompressed, err := DecompressBatch(data)
+		require.Nil(t, err)
+		assertBatchEqual(t, batch, decompressed)
+
+		// Truncated data is rejected
+		_, err = DecompressBatch(data[:r.Intn(len(data))])
+		assert.NotNil(t, err)
+	}
+}
+
+func TestCompressBatch_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := CompressBatch(&TicketBatch{TicketParams: &TicketParams{}})
+	assert.EqualError(err, "unable to compress batch with missing params")
+
+	batch := &TicketBatch{
+		TicketParams:           &TicketParams{FaceValue: big.NewInt(-1)},
+		TicketExpirationParams: &TicketExpirationParams{},
+	}
+	_, err = CompressBatch(batch)
+	assert.EqualError(err, "unable to compress negative value -1")
+
+	batch.FaceValue = nil
+	batch.PricePerPixel = big.NewRat(-1, 2)
+	_, err = CompressBatch(batch)
+	assert.EqualError(err, "unable to compress batch with negative price per pixel")
+
+	batch.PricePerPixel = nil
+	data, err := CompressBatch(batch)
+	assert.Nil(err)
+
+	_, err = DecompressBatch(append(data, 0))
+	assert.EqualError(err, "error decompressing batch: unexpected trailing data")
+
+	data[0] = compressedBatchVersion + 1
+	_, err = DecompressBatch(data)
+	assert.EqualError(err, "error decompressing batch: unsupported version 2")
+	data[0] = compressedBatchVersion
+
+	// Nonce runs that end after the max nonce are rejected, including runs whose end overflows a uint64
+	noRuns := data[:len(data)-1]
+	for _, run := range [][2]uint64{{math.MaxUint32, 2}, {math.MaxUint32 + 1, 1}, {math.MaxUint64, 2}} {
+		var buf bytes.Buffer
+		buf.Write(noRuns)
+		writeUvarint(&buf, 1)
+		writeUvarint(&buf, run[0])
+		writeUvarint(&buf, run[1])
+		buf.Write(make([]byte, 8))
+		_, err = DecompressBatch(buf.Bytes())
+		assert.EqualError(err, "error decompressing batch: invalid nonce run", "first=%v length=%v", run[0], run[1])
+	}
+
+	// Runs that each fit in the remaining data but whose tickets together do not are rejected
+	// before their tickets are allocated
+	var buf bytes.Buffer
+	buf.Write(noRuns)
+	writeUvarint(&buf, 2000)
+	for i := 0; i < 2000; i++ {
+		writeUvarint(&buf, uint64(i)*10000+1)
+		writeUvarint(&buf, 8000)
+	}
+	buf.Write(make([]byte, 8000))
+	_, err = DecompressBatch(buf.Bytes())
+	assert.EqualError(err, "error decompressing batch: invalid nonce run")
+}
+
+// FuzzDecompressBatch feeds arbitrary data into DecompressBatch and checks that:
+//
+// - DecompressBatch does not panic
+// - A decompressed batch can be compressed again and decompresses to an equal batch
+func FuzzDecompressBatch(f *testing.F) {
+	batches := []*TicketBatch{
+		{TicketParams: &TicketParams{}, TicketExpirationParams: &TicketExpirationParams{}},
+		{
+			TicketParams: &TicketParams{
+				Recipient:         RandAddress(),
+				FaceValue:         big.NewInt(1000),
+				WinProb:           maxWinProb,
+				RecipientRandHash: RandHash(),
+				Seed:              big.NewInt(7),
+				ExpirationBlock:   big.NewInt(100),
+				PricePerPixel:     big.NewRat(1, 3),
+				ExpirationParams:  &TicketExpirationParams{CreationRound: 4, CreationRoundBlockHash: RandHash()},
+			},
+			TicketExpirationParams: &TicketExpirationParams{CreationRound: 5, CreationRoundBlockHash: RandHash()},
+			Sender:                 RandAddress(),
+			SenderParams: []*TicketSenderParams{
+				{SenderNonce: 1, Sig: RandBytes(65)},
+				{SenderNonce: 2, Sig: RandBytes(65)},
+				{SenderNonce: math.MaxUint32, Sig: RandBytes(1)},
+			},
+		},
+	}
+	for _, batch := range batches {
+		data, err := CompressBatch(batch)
+		require.Nil(f, err)
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		batch, err := DecompressBatch(data)
+		if err != nil {
+			return
+		}
+
+		recompressed, err := CompressBatch(batch)
+		if err != nil {
+			t.Fatalf("unable to compress decompressed batch: %v", err)
+		}
+
+		roundTripped, err := DecompressBatch(recompressed)
+		if err != nil {
+			t.Fatalf("unable to decompress recompressed batch: %v", err)
+		}
+		assertBatchEqual(t, batch, roundTripped)
+	})
+}
+
+func BenchmarkCompressBatch(b *testing.B) {
+	batch := &TicketBatch{
+		TicketParams: &TicketParams{
+			Recipient:         RandAddress(),
+			FaceValue:         new(big.Int).Lsh(big.NewInt(1), 64),
+			WinProb:           new(big.Int).Rsh(maxWinProb, 10),
+			RecipientRandHash: RandHash(),
+			Seed:              new(big.Int).SetBytes(RandHash().Bytes()),
+			ExpirationBlock:   big.NewInt(10000000),
+			PricePerPixel:     big.NewRat(1, 3),
+		},
+		TicketExpirationParams: &TicketExpirationParams{CreationRound: 1000, CreationRoundBlockHash: RandHash()},
+		Sender:                 RandAddress(),
+	}
+	for i := 0; i < 1000; i++ {
+		batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: uint32(i + 1), Sig: RandBytes(65)})
+	}
+
+	// Raw encoding of each ticket with its signature
+	var rawSize int
+	for i, ticket := range batch.Tickets() {
+		rawSize += len(ticket.flatten()) + len(batch.SenderParams[i].Sig)
+	}
+
+	var data []byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, _ = CompressBatch(batch)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(rawSize), "raw-bytes")
+	b.ReportMetric(float64(len(data)), "compressed-bytes")
+	b.ReportMetric(float64(rawSize)/float64(len(data)), "ratio")
+}
+
+func assertBatchEqual(t *testing.T, expected, actual *TicketBatch) {
+	assert := assert.New(t)
+
+	assert.Equal(expected.Recipient, actual.Recipient)
+	assert.Equal(expected.Sender, actual.Sender)
+	assert.Equal(expected.RecipientRandHash, actual.RecipientRandHash)
+	assert.True(bigIntEqual(expected.FaceValue, actual.FaceValue))
+	assert.True(bigIntEqual(expected.WinProb, actual.WinProb))
+	assert.True(bigIntEqual(expected.Seed, actual.Seed))
+	assert.True(bigIntEqual(expected.ExpirationBlock, actual.ExpirationBlock))
+	assert.True(bigRatEqual(expected.PricePerPixel, actual.PricePerPixel))
+	assert.Equal(expected.ExpirationParams, actual.ExpirationParams)
+	assert.Equal(expected.TicketExpirationParams, actual.TicketExpirationParams)
+
+	if len(expected.SenderParams) == 0 {
+		assert.Empty(actual.SenderParams)
+		return
+	}
+	assert.Equal(expected.SenderParams, actual.SenderParams)
+}
@@ -0,0 +1,34 @@
+package pm
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrBatchSignatureMismatch is returned for a ticket in a batch whose signature was not made by the batch's sender
+var ErrBatchSignatureMismatch = errors.New("ticket signature not made by batch sender")
+
+// VerifyBatchSignatures checks that the signature of every ticket in a batch was made by batch.Sender over the
+// ticket hash computed by Ticket.Hash. The signatures must be in the 65 byte [R || S || V] format with V = 27
+// or V = 28 that is produced by the default SignatureFormat and accepted by the TicketBroker contract.
+// ErrBatchSignatureMismatch is returned for the ticket with the lowest index in the batch whose signature
+// cannot be recovered or was made by another address and the error includes the nonce of the ticket
+func VerifyBatchSignatures(batch *TicketBatch) error {
+	if batch == nil || batch.TicketParams == nil || batch.TicketExpirationParams == nil {
+		return errors.New("batch is missing ticket params or expiration params")
+	}
+
+	for i, ticket := range batch.Tickets() {
+		nonce := ticket.SenderNonce
+
+		recovered, err := recoverTicketSigner(ticket, batch.SenderParams[i].Sig)
+		if err != nil {
+			return errors.Wrapf(ErrBatchSignatureMismatch, "unable to recover signer of ticket senderNonce=%v err=%v", nonce, err)
+		}
+
+		if recovered != batch.Sender {
+			return errors.Wrapf(ErrBatchSignatureMismatch, "ticket senderNonce=%v signed by %x not %x", nonce, recovered, batch.Sender)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package pm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keySignedBatch(t *testing.T, size int) (*TicketBatch, *stubKeySigner) {
+	sender := defaultSender(t)
+	info := sender.senderManager.(*stubSenderManager).info[sender.signer.Account().Address]
+	signer := newStubKeySigner()
+	sender.signer = signer
+	sender.senderManager.(*stubSenderManager).info[signer.Account().Address] = info
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	batch, err := sender.CreateTicketBatch(sessionID, size)
+	require.Nil(t, err)
+
+	return batch, signer
+}
+
+func TestVerifyBatchSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	batch, _ := keySignedBatch(t, 3)
+	assert.Nil(VerifyBatchSignatures(batch))
+
+	// A batch signed by a key other than the batch sender's key is rejected at its first ticket
+	batch.Sender = RandAddress()
+	err := VerifyBatchSignatures(batch)
+	assert.Equal(ErrBatchSignatureMismatch, errors.Cause(err))
+	assert.Contains(err.Error(), "ticket senderNonce=1 signed by")
+
+	assert.EqualError(VerifyBatchSignatures(&TicketBatch{}), "batch is missing ticket params or expiration params")
+}
+
+func TestVerifyBatchSignatures_TamperedSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	batch, _ := keySignedBatch(t, 3)
+	sig := batch.SenderParams[1].Sig
+	sig[0] ^= 0xff
+
+	err := VerifyBatchSignatures(batch)
+	assert.Equal(ErrBatchSignatureMismatch, errors.Cause(err))
+	assert.Contains(err.Error(), "senderNonce=2")
+
+	// A signature that cannot be recovered is rejected
+	batch.SenderParams[1].Sig = sig[:64]
+	err = VerifyBatchSignatures(batch)
+	assert.Equal(ErrBatchSignatureMismatch, errors.Cause(err))
+	assert.Contains(err.Error(), "unable to recover signer of ticket senderNonce=2 err=invalid signature length")
+}
+
+func TestVerifyBatchSignatures_TamperedNonce(t *testing.T) {
+	assert := assert.New(t)
+
+	batch, signer := keySignedBatch(t, 3)
+	batch.SenderParams[2].SenderNonce = 1000
+
+	err := VerifyBatchSignatures(batch)
+	assert.Equal(ErrBatchSignatureMismatch, errors.Cause(err))
+	assert.Contains(err.Error(), "ticket senderNonce=1000 signed by")
+	assert.NotContains(err.Error(), fmt.Sprintf("signed by %x", signer.Account().Address))
+}
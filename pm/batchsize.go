@@ -0,0 +1,87 @@
+package pm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// maxAdaptiveBatchSize is the largest batch size returned by AdaptiveBatchSize
+const maxAdaptiveBatchSize = math.MaxInt32
+
+// AdaptiveBatchSize returns the smallest batch size for a session such that the expected number of winning
+// tickets in the batch is at least targetWinExpectation. The expected number of winning tickets in a batch
+// of n tickets is n * winProb / maxWinProb so sessions with a low win probability get larger batches than
+// sessions with a high win probability. An error is returned if the target cannot be reached because the
+// session's win probability is 0 or because the batch size would exceed math.MaxInt32
+func (s *sender) AdaptiveBatchSize(sessionID string, targetWinExpectation *big.Rat) (int, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	if targetWinExpectation == nil || targetWinExpectation.Sign() <= 0 {
+		return 0, fmt.Errorf("target win expectation must be greater than 0, but %v provided", targetWinExpectation)
+	}
+
+	winProb := session.ticketParams.WinProb
+	if winProb == nil || winProb.Sign() <= 0 {
+		return 0, fmt.Errorf("unable to reach target win expectation %v for session with zero win probability", targetWinExpectation.FloatString(5))
+	}
+
+	// size = ceil(target * maxWinProb / winProb)
+	num := new(big.Int).Mul(targetWinExpectation.Num(), maxWinProb)
+	denom := new(big.Int).Mul(targetWinExpectation.Denom(), winProb)
+	size, rem := new(big.Int).QuoRem(num, denom, new(big.Int))
+	if rem.Sign() > 0 {
+		size.Add(size, big.NewInt(1))
+	}
+
+	if size.Cmp(big.NewInt(maxAdaptiveBatchSize)) > 0 {
+		return 0, fmt.Errorf("batch size %v for target win expectation %v > max batch size %v", size, targetWinExpectation.FloatString(5), maxAdaptiveBatchSize)
+	}
+
+	return int(size.Int64()), nil
+}
+
+// RequiredDeposit returns the minimum deposit needed to back a batch of size tickets for a session, which is
+// the session's face value * size * the session's deposit multiplier. The reserve is not taken into account
+func (s *sender) RequiredDeposit(sessionID string, size int) (*big.Int, error) {
+	if size < 1 {
+		return nil, ErrEmptyBatch
+	}
+
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	faceValue := session.ticketParams.FaceValue
+	if faceValue == nil {
+		faceValue = big.NewInt(0)
+	}
+
+	deposit := new(big.Int).Mul(faceValue, big.NewInt(int64(size)))
+	return deposit.Mul(deposit, big.NewInt(int64(s.sessionDepositMultiplier(session)))), nil
+}
+
+// EVForFaceValue returns the EV of a ticket with faceValue and the session's win probability, i.e.
+// faceValue * winProb / maxWinProb, so that the EV implied by a face value can be computed when negotiating
+// ticket params without re-implementing the EV formula
+func (s *sender) EVForFaceValue(sessionID string, faceValue *big.Int) (*big.Rat, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if faceValue == nil || faceValue.Sign() < 0 {
+		return nil, fmt.Errorf("face value must be at least 0, but %v provided", faceValue)
+	}
+
+	winProb := session.ticketParams.WinProb
+	if winProb == nil {
+		winProb = big.NewInt(0)
+	}
+
+	return ticketEV(faceValue, winProb), nil
+}
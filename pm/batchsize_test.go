@@ -0,0 +1,176 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveBatchSize(t *testing.T) {
+	// winProb returns maxWinProb * num / denom rounded up
+	winProb := func(num, denom int64) *big.Int {
+		p := new(big.Int).Mul(maxWinProb, big.NewInt(num))
+		p.Add(p, big.NewInt(denom-1))
+		return p.Div(p, big.NewInt(denom))
+	}
+
+	tests := []struct {
+		name    string
+		winProb *big.Int
+		target  *big.Rat
+		size    int
+	}{
+		{"certain win", maxWinProb, big.NewRat(1, 1), 1},
+		{"certain win with fractional target", maxWinProb, big.NewRat(5, 2), 3},
+		{"high win probability", winProb(1, 2), big.NewRat(1, 1), 2},
+		{"high win probability below one ticket", winProb(1, 2), big.NewRat(1, 10), 1},
+		{"medium win probability", winProb(1, 100), big.NewRat(3, 1), 300},
+		{"low win probability", winProb(1, 10000), big.NewRat(1, 1), 10000},
+		{"very low win probability", winProb(1, 1000000), big.NewRat(2, 1), 2000000},
+		{"rounds up", winProb(2, 5), big.NewRat(1, 1), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			sender := defaultSender(t)
+			params := defaultTicketParams(t, RandAddress())
+			params.WinProb = tt.winProb
+			sessionID := sender.StartSession(params)
+
+			size, err := sender.AdaptiveBatchSize(sessionID, tt.target)
+			require.Nil(err)
+			assert.Equal(t, tt.size, size)
+
+			// The size is the smallest that reaches the target
+			expected := func(n int) *big.Rat {
+				return new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(n)), tt.winProb), maxWinProb)
+			}
+			assert.True(t, expected(size).Cmp(tt.target) >= 0)
+			assert.True(t, expected(size-1).Cmp(tt.target) < 0)
+		})
+	}
+}
+
+func TestAdaptiveBatchSize_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	params := defaultTicketParams(t, RandAddress())
+	params.WinProb = big.NewInt(1)
+	sessionID := sender.StartSession(params)
+
+	_, err := sender.AdaptiveBatchSize("foo", big.NewRat(1, 1))
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	_, err = sender.AdaptiveBatchSize(sessionID, big.NewRat(0, 1))
+	assert.EqualError(err, "target win expectation must be greater than 0, but 0/1 provided")
+	_, err = sender.AdaptiveBatchSize(sessionID, big.NewRat(-1, 1))
+	assert.EqualError(err, "target win expectation must be greater than 0, but -1/1 provided")
+	_, err = sender.AdaptiveBatchSize(sessionID, nil)
+	assert.EqualError(err, "target win expectation must be greater than 0, but <nil> provided")
+
+	// The batch size for a tiny win probability is too large
+	_, err = sender.AdaptiveBatchSize(sessionID, big.NewRat(1, 1))
+	assert.Contains(err.Error(), "> max batch size 2147483647")
+
+	// The target cannot be reached with a win probability of 0
+	params = defaultTicketParams(t, RandAddress())
+	sessionID = sender.StartSession(params)
+	_, err = sender.AdaptiveBatchSize(sessionID, big.NewRat(1, 1))
+	assert.EqualError(err, "unable to reach target win expectation 1.00000 for session with zero win probability")
+}
+
+func TestRequiredDeposit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1000)
+	sessionID := sender.StartSession(ticketParams)
+
+	otherParams := defaultTicketParams(t, RandAddress())
+	otherParams.FaceValue = big.NewInt(1000)
+	otherID, err := sender.StartSessionWithPolicy(otherParams, SessionPolicy{DepositMultiplier: 5})
+	require.Nil(err)
+
+	tests := []struct {
+		sessionID string
+		size      int
+		deposit   int64
+	}{
+		{sessionID, 1, 2000},
+		{sessionID, 3, 6000},
+		{sessionID, 50, 100000},
+		{otherID, 1, 5000},
+		{otherID, 20, 100000},
+	}
+
+	for _, tt := range tests {
+		deposit, err := sender.RequiredDeposit(tt.sessionID, tt.size)
+		require.Nil(err)
+		assert.Equal(big.NewInt(tt.deposit), deposit)
+	}
+
+	// The sender's deposit multiplier is used for sessions without a policy
+	sender.depositMultiplier = 3
+	deposit, err := sender.RequiredDeposit(sessionID, 2)
+	require.Nil(err)
+	assert.Equal(big.NewInt(6000), deposit)
+
+	_, err = sender.RequiredDeposit(sessionID, 0)
+	assert.Equal(ErrEmptyBatch, err)
+
+	_, err = sender.RequiredDeposit("foo", 1)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+}
+
+func TestEVForFaceValue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+
+	_, err := sender.EVForFaceValue("foo", big.NewInt(1))
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	params := defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(1000)
+	// 1 in 4 win probability
+	params.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(4))
+	sessionID := sender.StartSession(params)
+
+	tests := []struct {
+		faceValue *big.Int
+		ev        string
+	}{
+		{big.NewInt(0), "0.000"},
+		{big.NewInt(1), "0.250"},
+		{big.NewInt(1000), "250.000"},
+		{big.NewInt(1002), "250.500"},
+		{new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil), "250000000000000000.000"},
+	}
+
+	for _, tt := range tests {
+		ev, err := sender.EVForFaceValue(sessionID, tt.faceValue)
+		require.Nil(err)
+		assert.Equal(tt.ev, ev.FloatString(3), tt.faceValue.String())
+		assert.Equal(ticketEV(tt.faceValue, params.WinProb), ev)
+	}
+
+	// The EV at the session's face value is the EV of the session's tickets
+	ev, err := sender.EVForFaceValue(sessionID, params.FaceValue)
+	require.Nil(err)
+	assert.Equal(NewTicket(&params, &TicketExpirationParams{}, RandAddress(), 1).EV(), ev)
+
+	_, err = sender.EVForFaceValue(sessionID, nil)
+	assert.EqualError(err, "face value must be at least 0, but <nil> provided")
+
+	_, err = sender.EVForFaceValue(sessionID, big.NewInt(-1))
+	assert.EqualError(err, "face value must be at least 0, but -1 provided")
+}
@@ -0,0 +1,180 @@
+package pm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// streamedBatchVersion is the version of the format produced by TicketBatch.WriteTo
+const streamedBatchVersion = 1
+
+const (
+	// maxStreamedParamsSize is the max size of the encoded params of a streamed batch
+	maxStreamedParamsSize = 1 << 16
+	// maxStreamedSigSize is the max size of a ticket signature in a streamed batch
+	maxStreamedSigSize = 1 << 10
+)
+
+// WriteTo writes the binary encoding of a batch to w and returns the number of bytes written.
+// The shared params of the batch are written first, followed by the number of tickets and the nonce
+// and signature of every ticket, so the encoding of a batch is never held in memory as a whole.
+// Amounts in the params must not be negative. The batch can be read with ReadTicketBatch
+func (b *TicketBatch) WriteTo(w io.Writer) (int64, error) {
+	if b.TicketParams == nil || b.TicketExpirationParams == nil {
+		return 0, errors.New("unable to write batch with missing params")
+	}
+
+	var params bytes.Buffer
+	if err := writeBatchParams(&params, b); err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(streamedBatchVersion)
+	writeUvarint(&buf, uint64(params.Len()))
+	buf.Write(params.Bytes())
+	writeUvarint(&buf, uint64(len(b.SenderParams)))
+
+	n, err := w.Write(buf.Bytes())
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	for _, senderParams := range b.SenderParams {
+		buf.Reset()
+		writeUvarint(&buf, uint64(senderParams.SenderNonce))
+		writeUvarint(&buf, uint64(len(senderParams.Sig)))
+		buf.Write(senderParams.Sig)
+
+		n, err := w.Write(buf.Bytes())
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// ReadFrom replaces the batch with a batch read from r that was written by WriteTo and returns the
+// number of bytes read. No data following the batch is read from r
+func (b *TicketBatch) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingByteReader{r: r}
+	batch, err := readStreamedBatch(cr)
+	if err != nil {
+		return cr.n, errors.Wrap(err, "error reading batch")
+	}
+
+	*b = *batch
+
+	return cr.n, nil
+}
+
+// ReadTicketBatch reads a batch written by TicketBatch.WriteTo from r
+func ReadTicketBatch(r io.Reader) (*TicketBatch, error) {
+	batch := &TicketBatch{}
+	if _, err := batch.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+func readStreamedBatch(r *countingByteReader) (*TicketBatch, error) {
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != streamedBatchVersion {
+		return nil, fmt.Errorf("unsupported version %v", version)
+	}
+
+	paramsLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if paramsLen > maxStreamedParamsSize {
+		return nil, fmt.Errorf("params size %v > max params size %v", paramsLen, maxStreamedParamsSize)
+	}
+
+	params := make([]byte, paramsLen)
+	if _, err := io.ReadFull(r, params); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	pr := bytes.NewReader(params)
+	batch, err := readBatchParams(pr)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid params")
+	}
+	if pr.Len() > 0 {
+		return nil, errors.New("unexpected trailing params data")
+	}
+
+	numTickets, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	// The number of tickets is not trusted to preallocate the tickets because it is not bounded by the size of the data
+	for i := uint64(0); i < numTickets; i++ {
+		nonce, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if nonce > uint64(^uint32(0)) {
+			return nil, fmt.Errorf("invalid nonce %v", nonce)
+		}
+
+		sigLen, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if sigLen > maxStreamedSigSize {
+			return nil, fmt.Errorf("signature size %v > max signature size %v", sigLen, maxStreamedSigSize)
+		}
+
+		sig := make([]byte, sigLen)
+		if _, err := io.ReadFull(r, sig); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: uint32(nonce), Sig: sig})
+	}
+
+	return batch, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF for reads that follow the start of a batch
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// countingByteReader counts the bytes read from an io.Reader and implements io.ByteReader
+// without reading ahead of the bytes that are consumed
+type countingByteReader struct {
+	r   io.Reader
+	n   int64
+	buf [1]byte
+}
+
+func (c *countingByteReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(c, c.buf[:]); err != nil {
+		return 0, err
+	}
+	return c.buf[0], nil
+}
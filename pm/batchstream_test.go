@@ -0,0 +1,132 @@
+package pm
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamedTestBatch(t *testing.T, size int) *TicketBatch {
+	sender := defaultSender(t)
+	sender.signer.(*stubSigner).signResponse = RandBytes(65)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1000)
+	ticketParams.WinProb = big.NewInt(7)
+	ticketParams.PricePerPixel = big.NewRat(1, 3)
+	sessionID := sender.StartSession(ticketParams)
+
+	batch, err := sender.CreateTicketBatch(sessionID, size)
+	require.Nil(t, err)
+
+	return batch
+}
+
+func TestTicketBatch_WriteTo_Pipe_RoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	batch := streamedTestBatch(t, 1000)
+
+	pr, pw := io.Pipe()
+	written := make(chan int64, 1)
+	go func() {
+		n, err := batch.WriteTo(pw)
+		pw.CloseWithError(err)
+		written <- n
+	}()
+
+	read := &TicketBatch{}
+	n, err := read.ReadFrom(pr)
+	require.Nil(err)
+	assert.Equal(<-written, n)
+	assertBatchEqual(t, batch, read)
+
+	// No data is left in the stream after the batch
+	_, err = pr.Read(make([]byte, 1))
+	assert.Equal(io.EOF, err)
+}
+
+func TestTicketBatch_WriteTo_ConsecutiveBatches(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	batches := []*TicketBatch{streamedTestBatch(t, 3), streamedTestBatch(t, 1)}
+	// A batch without tickets and with all optional params missing
+	batches = append(batches, &TicketBatch{TicketParams: &TicketParams{}, TicketExpirationParams: &TicketExpirationParams{}})
+
+	var buf bytes.Buffer
+	var total int64
+	for _, batch := range batches {
+		n, err := batch.WriteTo(&buf)
+		require.Nil(err)
+		total += n
+	}
+	assert.Equal(int64(buf.Len()), total)
+
+	for _, batch := range batches {
+		read, err := ReadTicketBatch(&buf)
+		require.Nil(err)
+		assertBatchEqual(t, batch, read)
+	}
+	assert.Zero(buf.Len())
+}
+
+func TestTicketBatch_WriteTo_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := (&TicketBatch{TicketParams: &TicketParams{}}).WriteTo(&bytes.Buffer{})
+	assert.EqualError(err, "unable to write batch with missing params")
+
+	batch := &TicketBatch{
+		TicketParams:           &TicketParams{FaceValue: big.NewInt(-1)},
+		TicketExpirationParams: &TicketExpirationParams{},
+	}
+	_, err = batch.WriteTo(&bytes.Buffer{})
+	assert.EqualError(err, "unable to compress negative value -1")
+
+	errWrite := errors.New("write error")
+	pr, pw := io.Pipe()
+	pr.CloseWithError(errWrite)
+	_, err = streamedTestBatch(t, 2).WriteTo(pw)
+	assert.Equal(errWrite, err)
+}
+
+func TestReadTicketBatch_InvalidData(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	_, err := streamedTestBatch(t, 2).WriteTo(&buf)
+	require.Nil(err)
+	data := buf.Bytes()
+
+	_, err = ReadTicketBatch(bytes.NewReader(nil))
+	assert.Equal(io.EOF, errors.Cause(err))
+
+	for i := 1; i < len(data); i++ {
+		_, err := ReadTicketBatch(bytes.NewReader(data[:i]))
+		assert.Equal(io.ErrUnexpectedEOF, errors.Cause(err), "truncated at %v", i)
+	}
+
+	_, err = ReadTicketBatch(bytes.NewReader(append([]byte{2}, data[1:]...)))
+	assert.EqualError(err, "error reading batch: unsupported version 2")
+
+	// A params size larger than the max params size
+	_, err = ReadTicketBatch(bytes.NewReader([]byte{streamedBatchVersion, 0x80, 0x80, 0x08}))
+	assert.EqualError(err, "error reading batch: params size 131072 > max params size 65536")
+
+	// A signature size larger than the max signature size
+	empty := &TicketBatch{TicketParams: &TicketParams{}, TicketExpirationParams: &TicketExpirationParams{}}
+	buf.Reset()
+	_, err = empty.WriteTo(&buf)
+	require.Nil(err)
+	data = buf.Bytes()
+	data = append(data[:len(data)-1], 1, 1, 0x81, 0x08)
+	_, err = ReadTicketBatch(bytes.NewReader(data))
+	assert.EqualError(err, "error reading batch: signature size 1025 > max signature size 1024")
+}
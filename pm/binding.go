@@ -0,0 +1,38 @@
+package pm
+
+import (
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/pkg/errors"
+)
+
+// ErrTicketNotBound is returned for a ticket whose signature was not made by the ticket's sender
+// over the ticket's current fields
+var ErrTicketNotBound = errors.New("ticket signature does not match ticket")
+
+// BindingCheck checks that a signature was made by ticket.Sender over the hash of the ticket computed by
+// Ticket.Hash so that a signature cannot be applied to a ticket with another nonce or any other changed field
+// of the hash. The hash covers Recipient, Sender, FaceValue, WinProb, SenderNonce, RecipientRandHash,
+// CreationRound and CreationRoundBlockHash, which are all of the fields checked by the TicketBroker contract.
+// ParamsExpirationBlock and PricePerPixel are only used off-chain when negotiating ticket params and are not
+// covered by the hash because it has to match the hash computed by the contract.
+// For tickets with an AuxDataHash the signature must be over HashWithAux, which also binds the AuxDataHash.
+// The signature must be in the 65 byte [R || S || V] format with V = 27 or V = 28
+func BindingCheck(ticket *Ticket, sig []byte) error {
+	recovered, err := recoverTicketSigner(ticket, sig)
+	if err != nil {
+		return errors.Wrapf(ErrTicketNotBound, "unable to recover signer of ticket senderNonce=%v err=%v", ticket.SenderNonce, err)
+	}
+
+	if recovered != ticket.Sender {
+		return errors.Wrapf(ErrTicketNotBound, "ticket senderNonce=%v signed by %x not %x", ticket.SenderNonce, recovered, ticket.Sender)
+	}
+
+	return nil
+}
+
+// recoverTicketSigner returns the address that made a signature over the signed hash of a ticket, which is
+// HashWithAux for tickets with an AuxDataHash and Ticket.Hash otherwise
+func recoverTicketSigner(ticket *Ticket, sig []byte) (ethcommon.Address, error) {
+	return crypto.RecoverSig(ticket.signedHash().Bytes(), sig)
+}
@@ -0,0 +1,74 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func boundTicket(t *testing.T) (*Ticket, []byte) {
+	signer := newStubKeySigner()
+	ticket := &Ticket{
+		Recipient:              RandAddress(),
+		Sender:                 signer.Account().Address,
+		FaceValue:              big.NewInt(1000),
+		WinProb:                big.NewInt(500),
+		SenderNonce:            7,
+		RecipientRandHash:      RandHash(),
+		CreationRound:          5,
+		CreationRoundBlockHash: RandHash(),
+		ParamsExpirationBlock:  big.NewInt(100),
+		PricePerPixel:          big.NewRat(1, 1),
+	}
+
+	sig, err := signer.Sign(ticket.Hash().Bytes())
+	require.Nil(t, err)
+
+	return ticket, sig
+}
+
+func TestBindingCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	ticket, sig := boundTicket(t)
+	assert.Nil(BindingCheck(ticket, sig))
+
+	err := BindingCheck(ticket, sig[:64])
+	assert.Equal(ErrTicketNotBound, errors.Cause(err))
+	assert.EqualError(err, "unable to recover signer of ticket senderNonce=7 err=invalid signature length: "+ErrTicketNotBound.Error())
+}
+
+func TestBindingCheck_MutatedFieldsInvalidateSignature(t *testing.T) {
+	mutations := map[string]func(ticket *Ticket){
+		"Recipient":              func(ticket *Ticket) { ticket.Recipient = RandAddress() },
+		"Sender":                 func(ticket *Ticket) { ticket.Sender = RandAddress() },
+		"FaceValue":              func(ticket *Ticket) { ticket.FaceValue = big.NewInt(1001) },
+		"WinProb":                func(ticket *Ticket) { ticket.WinProb = big.NewInt(501) },
+		"SenderNonce":            func(ticket *Ticket) { ticket.SenderNonce = 8 },
+		"RecipientRandHash":      func(ticket *Ticket) { ticket.RecipientRandHash = RandHash() },
+		"CreationRound":          func(ticket *Ticket) { ticket.CreationRound = 6 },
+		"CreationRoundBlockHash": func(ticket *Ticket) { ticket.CreationRoundBlockHash = RandHash() },
+	}
+
+	for field, mutate := range mutations {
+		t.Run(field, func(t *testing.T) {
+			ticket, sig := boundTicket(t)
+			mutate(ticket)
+
+			assert.Equal(t, ErrTicketNotBound, errors.Cause(BindingCheck(ticket, sig)))
+		})
+	}
+}
+
+func TestBindingCheck_OffChainFieldsNotBound(t *testing.T) {
+	assert := assert.New(t)
+
+	// ParamsExpirationBlock and PricePerPixel are not part of the hash computed by the TicketBroker contract
+	ticket, sig := boundTicket(t)
+	ticket.ParamsExpirationBlock = big.NewInt(200)
+	ticket.PricePerPixel = big.NewRat(2, 1)
+	assert.Nil(BindingCheck(ticket, sig))
+}
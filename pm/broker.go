@@ -16,10 +16,30 @@ type SenderInfo struct {
 	// WithdrawRound is the round that the sender can withdraw its deposit and reserve
 	WithdrawRound *big.Int
 
+	// PendingWithdrawal is the amount of the deposit that the sender is withdrawing.
+	// It is nil if the SenderManager does not report pending withdrawals
+	PendingWithdrawal *big.Int
+
 	// ReserveInfo is a struct containing details about a sender's reserve
 	Reserve *ReserveInfo
+
+	// Status is the status of the sender's account. It is SenderStatusUnknown
+	// if the SenderManager does not report account statuses
+	Status SenderStatus
 }
 
+// SenderStatus describes whether a sender's account can be used to back tickets
+type SenderStatus int
+
+const (
+	// SenderStatusUnknown indicates that the sender's account status is not available
+	SenderStatusUnknown SenderStatus = iota
+	// SenderStatusActive indicates that the sender's account is in good standing
+	SenderStatusActive
+	// SenderStatusFrozen indicates that the sender's account is frozen or penalized
+	SenderStatusFrozen
+)
+
 // ReserveInfo holds information about a sender's reserve
 type ReserveInfo struct {
 	// FundsRemaining is the amount of funds the sender has left in its reserve
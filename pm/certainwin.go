@@ -0,0 +1,56 @@
+package pm
+
+import (
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// ErrCertainWin is returned for ticket params with a win probability that implies every ticket wins
+// if the sender is configured to reject them
+var ErrCertainWin = errors.New("ticket win probability implies every ticket wins")
+
+// CertainWinAction is the action taken by the sender for ticket params with a WinProb of maxWinProb.
+// Every ticket created with these params wins so each ticket is a direct payment of its face value
+// that has to be redeemed on-chain
+type CertainWinAction int
+
+const (
+	// CertainWinAllow accepts ticket params that imply certain wins
+	CertainWinAllow CertainWinAction = iota
+	// CertainWinWarn accepts ticket params that imply certain wins and logs a warning when a session is started
+	CertainWinWarn
+	// CertainWinReject rejects ticket params that imply certain wins when starting a session and when
+	// validating ticket params
+	CertainWinReject
+)
+
+// isCertainWin returns true if every ticket created with the ticket params wins
+func isCertainWin(ticketParams *TicketParams) bool {
+	return ticketParams.WinProb != nil && ticketParams.WinProb.Cmp(maxWinProb) >= 0
+}
+
+// checkCertainWin applies the configured CertainWinAction to the ticket params of a session that is starting
+func (s *sender) checkCertainWin(sessionID string, ticketParams *TicketParams) error {
+	if !isCertainWin(ticketParams) {
+		return nil
+	}
+
+	switch s.cfg.CertainWinAction {
+	case CertainWinWarn:
+		glog.Warningf("Ticket params imply every ticket wins sessionID=%v recipient=%x faceValue=%v", sessionID, ticketParams.Recipient, ticketParams.FaceValue)
+	case CertainWinReject:
+		return ErrCertainWin
+	}
+
+	return nil
+}
+
+// validateCertainWin returns ErrCertainWin for ticket params that imply certain wins if the
+// sender is configured to reject them
+func (s *sender) validateCertainWin(ticketParams *TicketParams) error {
+	if s.cfg.CertainWinAction == CertainWinReject && isCertainWin(ticketParams) {
+		return ErrCertainWin
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func certainWinTicketParams(t *testing.T) TicketParams {
+	params := defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(10)
+	params.WinProb = new(big.Int).Set(maxWinProb)
+	return params
+}
+
+func TestCertainWin_AllowedByDefault(t *testing.T) {
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	params := certainWinTicketParams(t)
+
+	require.Nil(sender.ValidateTicketParams(&params))
+	sessionID, err := sender.StartSessionWithPolicy(params, SessionPolicy{})
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+}
+
+func TestCertainWin_Warn(t *testing.T) {
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.CertainWinAction = CertainWinWarn
+	params := certainWinTicketParams(t)
+
+	require.Nil(sender.ValidateTicketParams(&params))
+	sessionID, err := sender.StartSessionWithPolicy(params, SessionPolicy{})
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+}
+
+func TestCertainWin_Reject(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	params := certainWinTicketParams(t)
+	sessionID, err := sender.StartSessionWithPolicy(params, SessionPolicy{})
+	require.Nil(err)
+
+	sender.cfg.CertainWinAction = CertainWinReject
+
+	assert.Equal(ErrCertainWin, sender.ValidateTicketParams(&params))
+
+	_, err = sender.StartSessionWithPolicy(certainWinTicketParams(t), SessionPolicy{})
+	assert.Equal(ErrCertainWin, err)
+	assert.Len(sender.ListSessions(), 1)
+
+	// Sessions started before rejecting certain wins fail validation
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.IsType(ValidationError{}, err)
+	assert.Equal(ErrCertainWin, err.(ValidationError).error)
+
+	// A win probability just below maxWinProb is accepted
+	params = certainWinTicketParams(t)
+	params.WinProb.Sub(params.WinProb, big.NewInt(1))
+	require.Nil(sender.ValidateTicketParams(&params))
+	_, err = sender.StartSessionWithPolicy(params, SessionPolicy{})
+	assert.Nil(err)
+}
@@ -0,0 +1,105 @@
+package pm
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// debugDump is the state of the sender included by DebugDump
+type debugDump struct {
+	Sender   ethcommon.Address
+	DumpedAt time.Time
+	Frozen   bool
+	Stats    GlobalStats
+	Sessions []debugSession
+}
+
+// debugSession is the state of a session included by DebugDump
+type debugSession struct {
+	SessionID      string
+	SenderNonce    uint32
+	TicketsCreated uint64
+	StartedAt      time.Time
+	Trusted        bool
+	Stale          bool
+	Signer         ethcommon.Address
+	TicketParams   debugTicketParams
+	Policy         debugSessionPolicy
+}
+
+// debugTicketParams are the ticket params of a session with the seed redacted
+type debugTicketParams struct {
+	Recipient         ethcommon.Address
+	FaceValue         *big.Int
+	WinProb           *big.Int
+	RecipientRandHash ethcommon.Hash
+	Seed              string
+	ExpirationBlock   *big.Int
+	PricePerPixel     *big.Rat
+	ExpirationParams  *TicketExpirationParams
+}
+
+// debugSessionPolicy are the per-session overrides of a session
+type debugSessionPolicy struct {
+	Metadata              map[string]string
+	DepositMultiplier     int
+	MaxTicketsPerRound    int
+	ExpirationBlock       *big.Int
+	ExpirationRoundOffset int64
+	Deadline              time.Time
+}
+
+// DebugDump returns the state of the sender and all of its sessions as indented JSON for attaching to bug reports.
+// The seeds of ticket params are redacted and the dump cannot be used to restore sessions. Sessions are read
+// one after another as in SnapshotNonces so the dump is not an instantaneous view of all sessions
+func (s *sender) DebugDump() ([]byte, error) {
+	dump := debugDump{
+		Sender:   s.signer.Account().Address,
+		DumpedAt: timeNow(),
+		Frozen:   atomic.LoadUint32(&s.frozen) == 1,
+		Stats:    s.GlobalStats(),
+		Sessions: []debugSession{},
+	}
+
+	s.sessions.Range(func(key, value interface{}) bool {
+		session := value.(*session)
+		params := session.ticketParams
+		dump.Sessions = append(dump.Sessions, debugSession{
+			SessionID:      key.(string),
+			SenderNonce:    atomic.LoadUint32(&session.senderNonce),
+			TicketsCreated: atomic.LoadUint64(&session.ticketsCreated),
+			StartedAt:      session.startedAt,
+			Trusted:        atomic.LoadUint32(&session.trusted) == 1,
+			Stale:          atomic.LoadUint32(&session.stale) == 1,
+			Signer:         s.sessionSigner(session).Account().Address,
+			TicketParams: debugTicketParams{
+				Recipient:         params.Recipient,
+				FaceValue:         params.FaceValue,
+				WinProb:           params.WinProb,
+				RecipientRandHash: params.RecipientRandHash,
+				Seed:              redactedSeed,
+				ExpirationBlock:   params.ExpirationBlock,
+				PricePerPixel:     params.PricePerPixel,
+				ExpirationParams:  params.ExpirationParams,
+			},
+			Policy: debugSessionPolicy{
+				Metadata:              copyMetadata(session.policy.Metadata),
+				DepositMultiplier:     session.policy.DepositMultiplier,
+				MaxTicketsPerRound:    session.policy.MaxTicketsPerRound,
+				ExpirationBlock:       session.policy.ExpirationBlock,
+				ExpirationRoundOffset: session.policy.ExpirationRoundOffset,
+				Deadline:              session.policy.Deadline,
+			},
+		})
+		return true
+	})
+
+	sort.Slice(dump.Sessions, func(i, j int) bool { return dump.Sessions[i].SessionID < dump.Sessions[j].SessionID })
+
+	return json.MarshalIndent(dump, "", "  ")
+}
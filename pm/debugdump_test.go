@@ -0,0 +1,79 @@
+package pm
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugDump(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+
+	dump, err := sender.DebugDump()
+	require.Nil(err)
+	var empty map[string]interface{}
+	require.Nil(json.Unmarshal(dump, &empty))
+	assert.Empty(empty["Sessions"])
+
+	seed, ok := new(big.Int).SetString("8d4e0a3f9b2c17e6d5a4f3c2b1a09876fedcba9876543210aabbccddeeff1122", 16)
+	require.True(ok)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.Seed = seed
+	ticketParams.FaceValue = big.NewInt(1234)
+	sessionID0, err := sender.StartSessionWithPolicy(ticketParams, SessionPolicy{Metadata: map[string]string{"stream": "foo"}})
+	require.Nil(err)
+	sessionID1 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err = sender.CreateTicketBatch(sessionID0, 3)
+	require.Nil(err)
+
+	dump, err = sender.DebugDump()
+	require.Nil(err)
+
+	var decoded struct {
+		Stats struct {
+			ActiveSessions int
+			TicketsCreated uint64
+		}
+		Sessions []struct {
+			SessionID      string
+			SenderNonce    uint32
+			TicketsCreated uint64
+			TicketParams   struct {
+				FaceValue *big.Int
+				Seed      string
+			}
+			Policy struct {
+				Metadata map[string]string
+			}
+		}
+	}
+	require.Nil(json.Unmarshal(dump, &decoded))
+	assert.Equal(2, decoded.Stats.ActiveSessions)
+	assert.Equal(uint64(3), decoded.Stats.TicketsCreated)
+	require.Len(decoded.Sessions, 2)
+
+	for _, session := range decoded.Sessions {
+		assert.Equal(redactedSeed, session.TicketParams.Seed)
+		if session.SessionID == sessionID0 {
+			assert.Equal(uint32(3), session.SenderNonce)
+			assert.Equal(uint64(3), session.TicketsCreated)
+			assert.Equal(big.NewInt(1234), session.TicketParams.FaceValue)
+			assert.Equal(map[string]string{"stream": "foo"}, session.Policy.Metadata)
+		} else {
+			assert.Equal(sessionID1, session.SessionID)
+			assert.Zero(session.SenderNonce)
+		}
+	}
+
+	// The seed is not included in any encoding
+	assert.False(bytes.Contains(dump, []byte(seed.String())))
+	assert.False(bytes.Contains(bytes.ToLower(dump), []byte(seed.Text(16))))
+	assert.False(bytes.Contains(dump, seed.Bytes()))
+}
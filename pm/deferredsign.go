@@ -0,0 +1,158 @@
+package pm
+
+import (
+	"fmt"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// builtTickets tracks the tickets built for a session with BuildTicket so that each of them is signed once.
+// Tickets are removed once they are signed so only the tickets that are waiting to be signed are kept
+type builtTickets struct {
+	mu sync.Mutex
+	// tickets maps the nonce of an unsigned built ticket to its hash
+	tickets map[uint32]ethcommon.Hash
+}
+
+// add records a ticket built with a nonce
+func (b *builtTickets) add(nonce uint32, hash ethcommon.Hash) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tickets == nil {
+		b.tickets = make(map[uint32]ethcommon.Hash)
+	}
+	b.tickets[nonce] = hash
+}
+
+// claim removes the unsigned built ticket with a nonce so that it is signed once. An error is returned if no
+// unsigned ticket was built with the nonce, e.g. because it was already signed, or if the ticket was built
+// with a different hash
+func (b *builtTickets) claim(nonce uint32, hash ethcommon.Hash) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	built, ok := b.tickets[nonce]
+	if !ok {
+		return fmt.Errorf("no unsigned ticket was built with nonce %v", nonce)
+	}
+	if built != hash {
+		return fmt.Errorf("ticket with nonce %v does not match the built ticket", nonce)
+	}
+	delete(b.tickets, nonce)
+
+	return nil
+}
+
+// unclaim adds a claimed ticket back to the unsigned built tickets so that it can be signed after a signing error
+func (b *builtTickets) unclaim(nonce uint32, hash ethcommon.Hash) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tickets[nonce] = hash
+}
+
+// len returns the number of unsigned built tickets
+func (b *builtTickets) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.tickets)
+}
+
+// BuildTicket validates the ticket params of a session, allocates the next nonce of the session and
+// returns an unsigned ticket with the nonce. The ticket can be signed later with SignBuilt.
+// The nonce is consumed when the ticket is built, so the caller is responsible for signing and sending
+// every built ticket: a ticket that is built but never signed leaves a gap in the session's nonces
+func (s *sender) BuildTicket(sessionID string) (*Ticket, uint32, error) {
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, reservation, err := s.reserveTickets(sessionID, 1, reserveOpts{skipSigningSlot: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reservation.release()
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ticket *Ticket
+	err = s.issueNonces(sessionID, session, 1, func(senderNonce uint32) error {
+		ticket = NewTicket(&session.ticketParams, expirationParams, s.sessionSigner(session).Account().Address, senderNonce)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reservation.commit()
+	session.builtTickets.add(ticket.SenderNonce, s.ticketHash(ticket))
+
+	return ticket, ticket.SenderNonce, nil
+}
+
+// SignBuilt signs a ticket returned by BuildTicket. Each built ticket is signed once and only while its session exists
+// with the recipientRandHash and expiration params that the ticket was built with, so a ticket that was modified
+// after it was built or that was built for a previous round of the session is not signed.
+// ErrSenderFrozen is returned if the sender is frozen, in which case the ticket can be signed after the sender is unfrozen.
+// The signed ticket is recorded in the session's issuance log and a TicketCreated event is emitted
+func (s *sender) SignBuilt(ticket *Ticket) ([]byte, error) {
+	if ticket == nil {
+		return nil, errors.New("missing ticket")
+	}
+
+	if err := s.checkFrozen(); err != nil {
+		return nil, err
+	}
+
+	sessionID := s.sessionID(&TicketParams{Recipient: ticket.Recipient, RecipientRandHash: ticket.RecipientRandHash})
+
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := s.sessionSigner(session)
+	if ticket.Sender != signer.Account().Address {
+		return nil, fmt.Errorf("ticket sender %v is not the signer of session: %v", ticket.Sender.Hex(), sessionID)
+	}
+
+	if ticket.RecipientRandHash != session.ticketParams.RecipientRandHash {
+		return nil, fmt.Errorf("ticket recipientRandHash %x does not match session: %v", ticket.RecipientRandHash, sessionID)
+	}
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.CreationRound != expirationParams.CreationRound || ticket.CreationRoundBlockHash != expirationParams.CreationRoundBlockHash {
+		return nil, fmt.Errorf("ticket expiration params do not match session: %v", sessionID)
+	}
+
+	hash := s.ticketHash(ticket)
+	if err := session.builtTickets.claim(ticket.SenderNonce, hash); err != nil {
+		return nil, errors.Wrapf(err, "error signing built ticket for session: %v", sessionID)
+	}
+
+	release := s.acquireSigningSlot(1)
+	defer release()
+
+	sig, err := s.sign(signer, hash.Bytes())
+	if err != nil {
+		session.builtTickets.unclaim(ticket.SenderNonce, hash)
+		return nil, SignerError{errors.Wrapf(err, "error signing built ticket for session: %v", sessionID)}
+	}
+
+	s.recordIssuedTicket(sessionID, session, ticket, hash)
+	s.recordLastTicket(session, ticket, sig)
+
+	return sig, nil
+}
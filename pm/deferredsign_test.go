@@ -0,0 +1,170 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTicketAndSignBuilt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	info := sender.senderManager.(*stubSenderManager).info[sender.signer.Account().Address]
+	signer := newStubKeySigner()
+	sender.signer = signer
+	sender.senderManager.(*stubSenderManager).info[signer.Account().Address] = info
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+
+	ticket, nonce, err := sender.BuildTicket(sessionID)
+	require.Nil(err)
+	assert.Equal(uint32(1), nonce)
+	assert.Equal(nonce, ticket.SenderNonce)
+	assert.Equal(signer.Account().Address, ticket.Sender)
+	assert.Equal(ticketParams.Recipient, ticket.Recipient)
+	assert.Equal(int64(5), ticket.CreationRound)
+
+	// The nonce is consumed when the ticket is built
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(2), batch.SenderParams[0].SenderNonce)
+
+	hash := ticket.Hash()
+	sig, err := sender.SignBuilt(ticket)
+	require.Nil(err)
+	assert.True(crypto.VerifySig(signer.Account().Address, hash.Bytes(), sig))
+	assert.Equal(hash, ticket.Hash())
+
+	// The signature is the same as for a ticket created and signed in one call
+	_, createdSig, err := sender.signTicket(sessionID, mustLoadSession(t, sender, sessionID), ticket.expirationParams(), ticket.SenderNonce)
+	require.Nil(err)
+	assert.Equal(createdSig, sig)
+
+	lastTicket, lastSig, ok := sender.LastTicket(sessionID)
+	require.True(ok)
+	assert.Equal(ticket.SenderNonce, lastTicket.SenderNonce)
+	assert.Equal(sig, lastSig)
+
+	// Tickets for another sender are not signed
+	other := *ticket
+	other.Sender = RandAddress()
+	_, err = sender.SignBuilt(&other)
+	assert.EqualError(err, "ticket sender "+other.Sender.Hex()+" is not the signer of session: "+sessionID)
+
+	_, _, err = sender.BuildTicket("foo")
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	sender.senderManager.(*stubSenderManager).err = errors.New("GetSenderInfo error")
+	_, _, err = sender.BuildTicket(sessionID)
+	_, ok = err.(SenderInfoError)
+	assert.True(ok)
+}
+
+func TestSignBuilt_SignsEachBuiltTicketOnce(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	ticket, _, err := sender.BuildTicket(sessionID)
+	require.Nil(err)
+
+	// A ticket that was modified after it was built is not signed
+	modified := *ticket
+	modified.FaceValue = big.NewInt(1000)
+	_, err = sender.SignBuilt(&modified)
+	assert.EqualError(err, "error signing built ticket for session: "+sessionID+": ticket with nonce 1 does not match the built ticket")
+
+	// A ticket that was not built is not signed
+	notBuilt := *ticket
+	notBuilt.SenderNonce = 2
+	_, err = sender.SignBuilt(&notBuilt)
+	assert.EqualError(err, "error signing built ticket for session: "+sessionID+": no unsigned ticket was built with nonce 2")
+
+	// A ticket is signed again after a signing error
+	sender.signer.(*stubSigner).signShouldFail = true
+	_, err = sender.SignBuilt(ticket)
+	_, ok := err.(SignerError)
+	assert.True(ok)
+	sender.signer.(*stubSigner).signShouldFail = false
+
+	_, err = sender.SignBuilt(ticket)
+	require.Nil(err)
+	assert.Len(mustLoadSession(t, sender, sessionID).issuanceLog.list(), 1)
+
+	// A ticket is signed once
+	_, err = sender.SignBuilt(ticket)
+	assert.EqualError(err, "error signing built ticket for session: "+sessionID+": no unsigned ticket was built with nonce 1")
+	assert.Len(mustLoadSession(t, sender, sessionID).issuanceLog.list(), 1)
+}
+
+func TestSignBuilt_RejectsTicketsThatDoNotMatchSession(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// A ticket is not signed for a previous round of the session
+	ticket, _, err := sender.BuildTicket(sessionID)
+	require.Nil(err)
+
+	tm := sender.timeManager.(*stubTimeManager)
+	tm.round = big.NewInt(6)
+	tm.blkHash = [32]byte{6}
+	_, err = sender.SignBuilt(ticket)
+	assert.EqualError(err, "ticket expiration params do not match session: "+sessionID)
+
+	// A ticket is not signed once its session has ended
+	ticket, _, err = sender.BuildTicket(sessionID)
+	require.Nil(err)
+
+	sender.EndSession(sessionID)
+	_, err = sender.SignBuilt(ticket)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+}
+
+func mustLoadSession(t *testing.T, sender *sender, sessionID string) *session {
+	session, err := sender.loadSession(sessionID)
+	require.Nil(t, err)
+	return session
+}
+
+func TestSignBuilt_RemovesSignedTickets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	builtTickets := &mustLoadSession(t, sender, sessionID).builtTickets
+
+	var tickets []*Ticket
+	for i := 0; i < 5; i++ {
+		ticket, _, err := sender.BuildTicket(sessionID)
+		require.Nil(err)
+		tickets = append(tickets, ticket)
+	}
+	assert.Equal(5, builtTickets.len())
+
+	// A ticket that fails to be signed is kept until it is signed
+	sender.signer.(*stubSigner).signShouldFail = true
+	_, err := sender.SignBuilt(tickets[0])
+	assert.NotNil(err)
+	assert.Equal(5, builtTickets.len())
+	sender.signer.(*stubSigner).signShouldFail = false
+
+	for i, ticket := range tickets {
+		_, err := sender.SignBuilt(ticket)
+		require.Nil(err)
+		assert.Equal(len(tickets)-i-1, builtTickets.len())
+	}
+}
@@ -0,0 +1,50 @@
+package pm
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDepositReservationDenied is returned when the DepositCoordinator denies a deposit reservation
+var ErrDepositReservationDenied = errors.New("deposit reservation denied")
+
+// DepositCoordinator is an interface which describes an object capable of coordinating the use of a
+// sender's deposit by multiple processes that create tickets for the same sender
+type DepositCoordinator interface {
+	// TryReserve reserves amount of the shared deposit and returns false if the
+	// shared deposit cannot cover the amount. The sender calls TryReserve at most once
+	// for each batch of tickets and never releases a reservation, even if creating the batch fails
+	TryReserve(amount *big.Int) (bool, error)
+}
+
+// localDepositCoordinator is the DepositCoordinator used if none is configured.
+// It accepts all reservations because a single process is already limited by the sender's validation
+type localDepositCoordinator struct{}
+
+func (c localDepositCoordinator) TryReserve(amount *big.Int) (bool, error) {
+	return true, nil
+}
+
+// reserveDeposit reserves the total EV of numTickets tickets with the ticket params
+// from the DepositCoordinator. The total EV is rounded up to the nearest integer
+func (s *sender) reserveDeposit(ticketParams *TicketParams, numTickets int) error {
+	// The total EV is faceValue * winProb * numTickets / maxWinProb. It is divided without normalizing it
+	// as a big.Rat which is much more expensive than the division
+	amount := new(big.Int).Mul(ticketParams.FaceValue, ticketParams.WinProb)
+	amount.Mul(amount, big.NewInt(int64(numTickets)))
+	if _, rem := amount.QuoRem(amount, maxWinProb, new(big.Int)); rem.Sign() > 0 {
+		amount.Add(amount, big.NewInt(1))
+	}
+
+	ok, err := s.depositCoordinator.TryReserve(amount)
+	if err != nil {
+		return errors.Wrap(err, "error reserving deposit")
+	}
+
+	if !ok {
+		return errors.Wrapf(ErrDepositReservationDenied, "unable to reserve %v", amount)
+	}
+
+	return nil
+}
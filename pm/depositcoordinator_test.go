@@ -0,0 +1,91 @@
+package pm
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetDepositCoordinator reserves amounts from a budget shared by multiple senders
+type budgetDepositCoordinator struct {
+	budget *big.Int
+	err    error
+	mu     sync.Mutex
+}
+
+func (c *budgetDepositCoordinator) TryReserve(amount *big.Int) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return false, c.err
+	}
+
+	if amount.Cmp(c.budget) > 0 {
+		return false, nil
+	}
+
+	c.budget.Sub(c.budget, amount)
+	return true, nil
+}
+
+func TestCreateTicketBatch_DepositCoordinator_DeniesOnceBudgetExhausted(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	coordinator := &budgetDepositCoordinator{budget: big.NewInt(50)}
+
+	// EV = faceValue / 2 = 5
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(10)
+	ticketParams.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(2))
+
+	var senders []*sender
+	var sessionIDs []string
+	for i := 0; i < 2; i++ {
+		s := defaultSender(t)
+		s.depositCoordinator = coordinator
+		senders = append(senders, s)
+		sessionIDs = append(sessionIDs, s.StartSession(ticketParams))
+	}
+
+	// Total EV of slightly less than 5 per ticket is rounded up
+	_, err := senders[0].CreateTicketBatch(sessionIDs[0], 6)
+	require.Nil(err)
+	assert.Equal(big.NewInt(20), coordinator.budget)
+
+	_, err = senders[1].CreateTicketBatch(sessionIDs[1], 4)
+	require.Nil(err)
+	assert.Zero(coordinator.budget.Sign())
+
+	// Budget shared by both senders is exhausted
+	for i, s := range senders {
+		_, err = s.CreateTicketBatch(sessionIDs[i], 1)
+		assert.Equal(ErrDepositReservationDenied, errors.Cause(err))
+		assert.EqualError(err, "unable to reserve 5: deposit reservation denied")
+	}
+
+	// Failed validations do not reserve the deposit
+	coordinator.budget = big.NewInt(100)
+	senders[0].senderManager.(*stubSenderManager).err = errors.New("GetSenderInfo error")
+	_, err = senders[0].CreateTicketBatch(sessionIDs[0], 1)
+	assert.NotNil(err)
+	assert.Equal(big.NewInt(100), coordinator.budget)
+
+	coordinator.err = errors.New("TryReserve error")
+	_, err = senders[1].CreateTicketBatch(sessionIDs[1], 1)
+	assert.EqualError(err, "error reserving deposit: TryReserve error")
+}
+
+func TestCreateTicketBatch_DefaultDepositCoordinator(t *testing.T) {
+	sender := defaultSender(t)
+	assert.Equal(t, localDepositCoordinator{}, sender.depositCoordinator)
+
+	ok, err := sender.depositCoordinator.TryReserve(big.NewInt(1000000))
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
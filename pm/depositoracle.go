@@ -0,0 +1,42 @@
+package pm
+
+import (
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
+)
+
+// DepositOracle is an interface which describes an object capable of providing a sender's
+// on-chain info faster than the SenderManager, e.g. from a local indexer
+type DepositOracle interface {
+	// SenderInfo returns the info for a sender and the time at which the info was last updated.
+	// A nil info without an error indicates that the oracle has no info for the sender
+	SenderInfo(addr ethcommon.Address) (*SenderInfo, time.Time, error)
+}
+
+// getSenderInfo fetches the info of the account of the signer passed to NewSender
+func (s *sender) getSenderInfo() (*SenderInfo, error) {
+	return s.getSenderInfoFor(s.signer.Account().Address)
+}
+
+// sessionSenderInfo fetches the info of the account of a session's signer
+func (s *sender) sessionSenderInfo(session *session) (*SenderInfo, error) {
+	return s.getSenderInfoFor(s.sessionSigner(session).Account().Address)
+}
+
+// getSenderInfoFor fetches a sender's info from the configured DepositOracle and falls back to
+// the SenderManager if no oracle is configured, the oracle returns an error, has no info for the
+// sender or returns info that was updated longer than DepositOracleMaxAge ago
+func (s *sender) getSenderInfoFor(addr ethcommon.Address) (*SenderInfo, error) {
+	if s.cfg.DepositOracle != nil {
+		info, updatedAt, err := s.cfg.DepositOracle.SenderInfo(addr)
+		if err != nil {
+			glog.Warningf("Error fetching sender info from deposit oracle, falling back to sender manager err=%v", err)
+		} else if info != nil && (s.cfg.DepositOracleMaxAge <= 0 || timeNow().Sub(updatedAt) <= s.cfg.DepositOracleMaxAge) {
+			return info, nil
+		}
+	}
+
+	return s.senderManager.GetSenderInfo(addr)
+}
@@ -0,0 +1,95 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubDepositOracle struct {
+	info      map[ethcommon.Address]*SenderInfo
+	updatedAt time.Time
+	err       error
+	calls     int
+}
+
+func (o *stubDepositOracle) SenderInfo(addr ethcommon.Address) (*SenderInfo, time.Time, error) {
+	o.calls++
+	if o.err != nil {
+		return nil, time.Time{}, o.err
+	}
+
+	return o.info[addr], o.updatedAt, nil
+}
+
+func TestDepositOracle(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	now := time.Unix(1000, 0)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	addr := sender.signer.Account().Address
+
+	// The sender manager lags behind and has not seen the sender's deposit yet
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.info[addr].Deposit = big.NewInt(0)
+
+	oracle := &stubDepositOracle{
+		info: map[ethcommon.Address]*SenderInfo{
+			addr: {
+				Deposit:       big.NewInt(100000),
+				Reserve:       &ReserveInfo{FundsRemaining: big.NewInt(10)},
+				WithdrawRound: big.NewInt(0),
+			},
+		},
+		updatedAt: now.Add(-5 * time.Second),
+	}
+	sender.cfg.DepositOracle = oracle
+	sender.cfg.DepositOracleMaxAge = 10 * time.Second
+
+	params := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(params)
+
+	// The oracle's fresher info is used
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Nil(sender.ValidateTicketParams(&params))
+	assert.Equal(2, oracle.calls)
+
+	// Stale info from the oracle falls back to the sender manager
+	now = now.Add(10 * time.Second)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "no sender deposit")
+
+	// Oracle errors fall back to the sender manager
+	oracle.updatedAt = now
+	oracle.err = errors.New("oracle error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "no sender deposit")
+
+	// Oracle misses fall back to the sender manager
+	oracle.err = nil
+	sm.info[addr].Deposit = big.NewInt(100000)
+	delete(oracle.info, addr)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	// Info of any age is used without a freshness requirement
+	oracle.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(0),
+		Reserve:       &ReserveInfo{FundsRemaining: big.NewInt(10)},
+		WithdrawRound: big.NewInt(0),
+	}
+	oracle.updatedAt = time.Time{}
+	sender.cfg.DepositOracleMaxAge = 0
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "no sender deposit")
+}
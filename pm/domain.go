@@ -0,0 +1,32 @@
+package pm
+
+// TicketHashVersion is the version of the ticket hashing scheme implemented by Ticket.Hash.
+// It changes whenever the fields or the encoding of the ticket hash change
+const TicketHashVersion = "livepeer-pm-ticket-v1"
+
+// CustomTicketHashVersion is the version reported for tickets hashed with a HashFunc if no HashVersion is configured
+const CustomTicketHashVersion = "custom"
+
+// TicketDomain describes the scheme under which a sender hashes and signs tickets so that recipients
+// and tooling can check that they verify the sender's tickets with the same scheme
+type TicketDomain struct {
+	// Version is the version of the ticket hashing scheme
+	Version string
+
+	// Separator is the domain separator included in the ticket hash if any. Ticket.Hash does not include a
+	// domain separator because the hash has to match the hash computed by the TicketBroker contract
+	Separator []byte
+}
+
+// Domain returns the scheme under which the sender hashes and signs tickets. If a HashFunc is configured
+// the version is the configured HashVersion, otherwise it is TicketHashVersion
+func (s *sender) Domain() TicketDomain {
+	if s.cfg.HashFunc != nil {
+		if s.cfg.HashVersion == "" {
+			return TicketDomain{Version: CustomTicketHashVersion}
+		}
+		return TicketDomain{Version: s.cfg.HashVersion}
+	}
+
+	return TicketDomain{Version: TicketHashVersion}
+}
@@ -0,0 +1,35 @@
+package pm
+
+import (
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomain(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	vectors := loadTicketVectors(t)
+
+	// The reported version is the version of the scheme that produced the golden vectors
+	domain := sender.Domain()
+	assert.Equal(TicketHashVersion, domain.Version)
+	assert.Equal(vectors.Version, domain.Version)
+	assert.Nil(domain.Separator)
+
+	for _, v := range vectors.Vectors {
+		params := v.params()
+		expirationParams := v.expirationParams()
+		ticket := NewTicket(&params, &expirationParams, v.Sender, v.SenderNonce)
+		assert.Equal(v.Hash, sender.TicketHash(ticket), v.Name)
+	}
+
+	// Senders with a HashFunc report the version of the HashFunc
+	sender.cfg.HashFunc = func(ticket *Ticket) ethcommon.Hash { return ethcommon.Hash{} }
+	assert.Equal(CustomTicketHashVersion, sender.Domain().Version)
+
+	sender.cfg.HashVersion = "foo-v2"
+	assert.Equal("foo-v2", sender.Domain().Version)
+}
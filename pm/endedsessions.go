@@ -0,0 +1,114 @@
+package pm
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// ErrSessionReused is returned when a session is started with the session ID of a recently ended session
+// and a nonce that is lower than the last nonce of the ended session if the sender is configured to reject it
+var ErrSessionReused = errors.New("session ID of a recently ended session was reused")
+
+// defaultEndedSessionWindow is the default number of recently ended sessions that are remembered
+const defaultEndedSessionWindow = 1000
+
+// ReusedSessionAction is the action taken by the sender when a session is started with the session ID, i.e. the
+// recipientRandHash, of a recently ended session. The nonces of the new session start from the nonce loaded from
+// the SessionStore, or from 0 if no SessionStore is configured, so tickets of the new session can reuse nonces of
+// tickets of the ended session that the recipient has already seen
+type ReusedSessionAction int
+
+const (
+	// ReusedSessionAllow starts sessions that reuse the session ID of a recently ended session
+	ReusedSessionAllow ReusedSessionAction = iota
+	// ReusedSessionWarn starts sessions that reuse the session ID of a recently ended session and logs a warning
+	ReusedSessionWarn
+	// ReusedSessionReject does not start sessions that reuse the session ID of a recently ended session
+	ReusedSessionReject
+)
+
+// endedSessions is a bounded set of the most recently ended sessions with the last nonce of each session
+type endedSessions struct {
+	// ring holds the session IDs in the set in the order they were added
+	ring []string
+	next int
+
+	// nonces is the last nonce of each session in the set
+	nonces map[string]uint32
+
+	mu sync.Mutex
+}
+
+// add records the last nonce of an ended session and removes the oldest session if the set has more than
+// window sessions
+func (e *endedSessions) add(sessionID string, nonce uint32, window int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.nonces == nil {
+		e.nonces = make(map[string]uint32)
+	}
+
+	if _, ok := e.nonces[sessionID]; ok {
+		e.nonces[sessionID] = nonce
+		return
+	}
+
+	if len(e.ring) < window {
+		e.ring = append(e.ring, sessionID)
+	} else {
+		delete(e.nonces, e.ring[e.next])
+		e.ring[e.next] = sessionID
+		e.next = (e.next + 1) % len(e.ring)
+	}
+	e.nonces[sessionID] = nonce
+}
+
+// lastNonce returns the last nonce of a recently ended session
+func (e *endedSessions) lastNonce(sessionID string) (uint32, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	nonce, ok := e.nonces[sessionID]
+	return nonce, ok
+}
+
+// recordEndedSession remembers the last nonce of an ended session if a ReusedSessionAction other than
+// ReusedSessionAllow is configured
+func (s *sender) recordEndedSession(sessionID string, session *session) {
+	if s.cfg.ReusedSessionAction == ReusedSessionAllow {
+		return
+	}
+
+	window := s.cfg.EndedSessionWindow
+	if window <= 0 {
+		window = defaultEndedSessionWindow
+	}
+
+	s.endedSessions.add(sessionID, atomic.LoadUint32(&session.senderNonce), window)
+}
+
+// checkReusedSession applies the configured ReusedSessionAction to a session that is starting with senderNonce
+// if the session ID belongs to a recently ended session whose last nonce is higher than senderNonce
+func (s *sender) checkReusedSession(sessionID string, senderNonce uint32) error {
+	if s.cfg.ReusedSessionAction == ReusedSessionAllow {
+		return nil
+	}
+
+	lastNonce, ok := s.endedSessions.lastNonce(sessionID)
+	if !ok || senderNonce >= lastNonce {
+		return nil
+	}
+
+	switch s.cfg.ReusedSessionAction {
+	case ReusedSessionWarn:
+		glog.Warningf("Session ID of a recently ended session was reused sessionID=%v nonce=%v lastNonce=%v", sessionID, senderNonce, lastNonce)
+	case ReusedSessionReject:
+		return errors.Wrapf(ErrSessionReused, "session %v starts at nonce %v but ended at nonce %v", sessionID, senderNonce, lastNonce)
+	}
+
+	return nil
+}
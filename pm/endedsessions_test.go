@@ -0,0 +1,107 @@
+package pm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReusedSession(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	params := defaultTicketParams(t, RandAddress())
+
+	// Restarting an ended session is allowed by default
+	sessionID := sender.StartSession(params)
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	sender.EndSession(sessionID)
+	_, err = sender.StartSessionWithPolicy(params, SessionPolicy{})
+	require.Nil(err)
+
+	sender.cfg.ReusedSessionAction = ReusedSessionReject
+	params = defaultTicketParams(t, RandAddress())
+	sessionID = sender.StartSession(params)
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+
+	sender.EndSession(sessionID)
+	_, err = sender.StartSessionWithPolicy(params, SessionPolicy{})
+	assert.Equal(ErrSessionReused, errors.Cause(err))
+	assert.EqualError(err, fmt.Sprintf("session %v starts at nonce 0 but ended at nonce 3: %v", sessionID, ErrSessionReused))
+	_, err = sender.loadSession(sessionID)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	// Sessions that did not create any tickets can be restarted
+	unused := defaultTicketParams(t, RandAddress())
+	sender.EndSession(sender.StartSession(unused))
+	_, err = sender.StartSessionWithPolicy(unused, SessionPolicy{})
+	assert.Nil(err)
+
+	// Sessions are flagged when they are ended with EndSessionSync too
+	params = defaultTicketParams(t, RandAddress())
+	sessionID = sender.StartSession(params)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	require.Nil(sender.EndSessionSync(sessionID))
+	_, err = sender.StartSessionWithPolicy(params, SessionPolicy{})
+	assert.Equal(ErrSessionReused, errors.Cause(err))
+
+	// Warning starts the session
+	sender.cfg.ReusedSessionAction = ReusedSessionWarn
+	_, err = sender.StartSessionWithPolicy(params, SessionPolicy{})
+	assert.Nil(err)
+}
+
+func TestReusedSession_SessionStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ReusedSessionAction = ReusedSessionReject
+	sender.cfg.SessionStore = newStubSessionStore()
+	params := defaultTicketParams(t, RandAddress())
+
+	// A session that continues from the persisted nonce of the ended session does not reuse nonces
+	sessionID := sender.StartSession(params)
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	sender.EndSession(sessionID)
+
+	_, err = sender.StartSessionWithPolicy(params, SessionPolicy{})
+	require.Nil(err)
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(4), batch.SenderParams[0].SenderNonce)
+}
+
+func TestEndedSessions_Window(t *testing.T) {
+	assert := assert.New(t)
+
+	var ended endedSessions
+	for i := 0; i < 5; i++ {
+		ended.add(fmt.Sprint(i), uint32(i), 3)
+	}
+
+	// Only the most recently ended sessions are remembered
+	for i := 0; i < 2; i++ {
+		_, ok := ended.lastNonce(fmt.Sprint(i))
+		assert.False(ok)
+	}
+	for i := 2; i < 5; i++ {
+		nonce, ok := ended.lastNonce(fmt.Sprint(i))
+		assert.True(ok)
+		assert.Equal(uint32(i), nonce)
+	}
+
+	// Ending a remembered session again updates its last nonce
+	ended.add("3", 10, 3)
+	nonce, _ := ended.lastNonce("3")
+	assert.Equal(uint32(10), nonce)
+	assert.Len(ended.nonces, 3)
+}
@@ -0,0 +1,28 @@
+package pm
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Freeze stops ticket creation for all sessions until Unfreeze is called. While the sender is frozen,
+// calls that create or sign tickets return ErrSenderFrozen without consuming any nonces.
+// Sessions can still be started and ended. Freeze is safe to call concurrently and more than once
+func (s *sender) Freeze() {
+	atomic.StoreUint32(&s.frozen, 1)
+}
+
+// Unfreeze resumes ticket creation after Freeze. It is safe to call concurrently and more than once
+func (s *sender) Unfreeze() {
+	atomic.StoreUint32(&s.frozen, 0)
+}
+
+// checkFrozen returns ErrSenderFrozen if ticket creation was stopped with Freeze
+func (s *sender) checkFrozen() error {
+	if atomic.LoadUint32(&s.frozen) == 1 {
+		return errors.Wrap(ErrSenderFrozen, "ticket creation is frozen")
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package pm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID0 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	sessionID1 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID0, 1)
+	require.Nil(err)
+
+	// Freezing more than once has the same effect as freezing once
+	sender.Freeze()
+	sender.Freeze()
+
+	for _, sessionID := range []string{sessionID0, sessionID1} {
+		_, err := sender.CreateTicketBatch(sessionID, 2)
+		assert.Equal(ErrSenderFrozen, errors.Cause(err))
+		assert.EqualError(err, "ticket creation is frozen: "+ErrSenderFrozen.Error())
+
+		_, err = sender.CreateBatchSplitByRound(sessionID, 2)
+		assert.Equal(ErrSenderFrozen, errors.Cause(err))
+
+		_, _, err = sender.CreateTicketAtRound(sessionID, 5, [32]byte{5})
+		assert.Equal(ErrSenderFrozen, errors.Cause(err))
+
+		_, _, err = sender.BuildTicket(sessionID)
+		assert.Equal(ErrSenderFrozen, errors.Cause(err))
+	}
+
+	_, err = sender.CreateBatchesAtomic([]BatchRequest{{SessionID: sessionID0, Size: 1}, {SessionID: sessionID1, Size: 1}})
+	assert.Equal(ErrSenderFrozen, errors.Cause(err))
+
+	// Sessions can still be started while the sender is frozen
+	sessionID2 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err = sender.CreateTicketBatch(sessionID2, 1)
+	assert.Equal(ErrSenderFrozen, errors.Cause(err))
+
+	// No nonces were consumed while the sender was frozen
+	sender.Unfreeze()
+	sender.Unfreeze()
+
+	for sessionID, nonce := range map[string]uint32{sessionID0: 2, sessionID1: 1, sessionID2: 1} {
+		batch, err := sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(err)
+		assert.Equal(nonce, batch.SenderParams[0].SenderNonce)
+	}
+}
+
+func TestFreeze_SignBuilt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	ticket, _, err := sender.BuildTicket(sessionID)
+	require.Nil(err)
+
+	sender.Freeze()
+	_, err = sender.SignBuilt(ticket)
+	assert.Equal(ErrSenderFrozen, errors.Cause(err))
+
+	// The built ticket can be signed once the sender is unfrozen
+	sender.Unfreeze()
+	_, err = sender.SignBuilt(ticket)
+	assert.Nil(err)
+}
+
+func TestFreeze_Concurrent(t *testing.T) {
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				sender.Freeze()
+			} else {
+				sender.Unfreeze()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := sender.CreateTicketBatch(sessionID, 1); err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			} else {
+				assert.Equal(t, ErrSenderFrozen, errors.Cause(err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Nonces are only consumed by the calls that created tickets
+	sender.Unfreeze()
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(created+1), batch.SenderParams[0].SenderNonce)
+}
@@ -0,0 +1,56 @@
+package pm
+
+import (
+	"math/big"
+	"sync/atomic"
+)
+
+// GlobalStats are ticket creation totals across all of a sender's sessions
+type GlobalStats struct {
+	// ActiveSessions is the number of sessions
+	ActiveSessions int
+
+	// TicketsCreated is the number of tickets created for the sessions
+	TicketsCreated uint64
+
+	// CommittedFaceValue is the total face value of the tickets created for the sessions
+	CommittedFaceValue *big.Int
+
+	// TicketsPerSecond is the sum of the rates at which tickets were created for each session
+	// since the session was started
+	TicketsPerSecond float64
+
+	// TrackedMemory is the estimated number of bytes retained by the tracking structures of the sessions
+	// that are charged against the TrackingMemoryBudget. Zero if no budget is configured
+	TrackedMemory int64
+}
+
+// GlobalStats returns ticket creation totals across all sessions that have not been ended.
+// Tickets of ended sessions are not included
+func (s *sender) GlobalStats() GlobalStats {
+	stats := GlobalStats{CommittedFaceValue: big.NewInt(0)}
+	now := timeNow()
+
+	s.sessions.Range(func(key, value interface{}) bool {
+		session := value.(*session)
+		tickets := atomic.LoadUint64(&session.ticketsCreated)
+
+		stats.ActiveSessions++
+		stats.TicketsCreated += tickets
+
+		if faceValue := session.ticketParams.FaceValue; faceValue != nil {
+			committed := new(big.Int).SetUint64(tickets)
+			stats.CommittedFaceValue.Add(stats.CommittedFaceValue, committed.Mul(committed, faceValue))
+		}
+
+		if elapsed := now.Sub(session.startedAt).Seconds(); elapsed > 0 {
+			stats.TicketsPerSecond += float64(tickets) / elapsed
+		}
+
+		return true
+	})
+
+	stats.TrackedMemory = s.trackingBudget.usage()
+
+	return stats
+}
@@ -0,0 +1,61 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobalStats(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	now := time.Unix(1000, 0)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+
+	stats := sender.GlobalStats()
+	assert.Equal(0, stats.ActiveSessions)
+	assert.Equal(uint64(0), stats.TicketsCreated)
+	assert.Equal(big.NewInt(0), stats.CommittedFaceValue)
+	assert.Equal(float64(0), stats.TicketsPerSecond)
+
+	params := defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(10)
+	first := sender.StartSession(params)
+
+	now = now.Add(5 * time.Second)
+	params = defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(7)
+	second := sender.StartSession(params)
+
+	ended := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(first, 3)
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(second, 2)
+	require.Nil(err)
+	_, err = sender.CreateBatchSplitByRound(second, 2)
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(ended, 4)
+	require.Nil(err)
+	sender.EndSession(ended)
+
+	// Failed batches are not counted
+	sender.signer.(*stubSigner).signShouldFail = true
+	_, err = sender.CreateTicketBatch(first, 5)
+	require.NotNil(err)
+
+	now = now.Add(5 * time.Second)
+	stats = sender.GlobalStats()
+	assert.Equal(2, stats.ActiveSessions)
+	assert.Equal(uint64(7), stats.TicketsCreated)
+	assert.Equal(big.NewInt(3*10+4*7), stats.CommittedFaceValue)
+	// 3 tickets in 10s + 4 tickets in 5s
+	assert.InDelta(1.1, stats.TicketsPerSecond, 1e-9)
+}
@@ -0,0 +1,114 @@
+package pm
+
+import (
+	"math/big"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Weights of the validation success rate and the signing latency in the session health score
+const (
+	healthValidationWeight = 0.6
+	healthLatencyWeight    = 0.4
+)
+
+// healthLatencyTarget is the P90 signing latency at which the latency component of the health score is 0.5
+const healthLatencyTarget = 100 * time.Millisecond
+
+// validationHistoryWindow is the number of most recent validations of a session that its success rate is computed over
+const validationHistoryWindow = 64
+
+// validationHistory records the outcomes of the most recent validations of a session
+type validationHistory struct {
+	mu sync.Mutex
+	// failures has bit i set if the i-th most recent validation failed
+	failures uint64
+	count    int
+}
+
+func (h *validationHistory) record(ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failures <<= 1
+	if !ok {
+		h.failures |= 1
+	}
+
+	if h.count < validationHistoryWindow {
+		h.count++
+	}
+}
+
+// successRate returns the fraction of the recorded validations that succeeded or 1 if none were recorded
+func (h *validationHistory) successRate() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 {
+		return 1
+	}
+
+	return 1 - float64(bits.OnesCount64(h.failures))/float64(h.count)
+}
+
+// HealthScore returns a score between 0 and 1 for a session to rank sessions on dashboards.
+// The score is depositHeadroom * roundValidity * (0.6 * validationSuccessRate + 0.4 * signingLatency) where
+//   - depositHeadroom is 1 - faceValue * depositMultiplier / deposit for the deposit that tickets of the session
+//     are validated against or 0 if the sender fails validation, e.g. because its deposit or reserve is exhausted
+//   - roundValidity is 1 if tickets can be created with the session's ticket params and the current expiration
+//     params and 0 otherwise
+//   - validationSuccessRate is the fraction of the last 64 ticket params validations of the session that
+//     succeeded or 1 if the session was not validated yet
+//   - signingLatency is 100ms / (100ms + the sender's P90 signing latency)
+//
+// The deposit headroom and the round validity scale the score because no tickets can be created for the
+// session without them. ErrUnknownSession is returned if the session does not exist
+func (s *sender) HealthScore(sessionID string) (float64, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	headroom, err := s.depositHeadroom(session)
+	if err != nil {
+		return 0, err
+	}
+
+	roundValidity := 1.0
+	if _, err := s.issuableExpirationParams(session); err != nil {
+		roundValidity = 0
+	} else if err := s.validateParamsExpiration(&session.ticketParams); err != nil {
+		roundValidity = 0
+	}
+
+	latency := float64(healthLatencyTarget) / float64(healthLatencyTarget+s.signingLatency.stats().P90)
+
+	return headroom * roundValidity * (healthValidationWeight*session.validations.successRate() + healthLatencyWeight*latency), nil
+}
+
+// depositHeadroom returns the fraction of the deposit that is not needed to back a ticket of a session
+func (s *sender) depositHeadroom(session *session) (float64, error) {
+	info, err := s.sessionSenderInfo(session)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.validateSender(info); err != nil {
+		return 0, nil
+	}
+
+	deposit := s.usableDeposit(info)
+	if deposit.Sign() <= 0 {
+		return 0, nil
+	}
+
+	required := new(big.Int).Mul(session.ticketParams.FaceValue, big.NewInt(int64(s.sessionDepositMultiplier(session))))
+	used, _ := new(big.Rat).SetFrac(required, deposit).Float64()
+	if used >= 1 {
+		return 0, nil
+	}
+
+	return 1 - used, nil
+}
@@ -0,0 +1,101 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	var h validationHistory
+	assert.Equal(1.0, h.successRate())
+
+	h.record(true)
+	h.record(false)
+	h.record(true)
+	h.record(true)
+	assert.Equal(0.75, h.successRate())
+
+	// Only the most recent validations are included
+	for i := 0; i < validationHistoryWindow; i++ {
+		h.record(true)
+	}
+	assert.Equal(1.0, h.successRate())
+}
+
+func TestHealthScore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(100)
+	ticketParams.WinProb = big.NewInt(1)
+	sessionID := sender.StartSession(ticketParams)
+
+	_, err := sender.HealthScore("foo")
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	_, err = sender.CreateTicketBatch(sessionID, 5)
+	require.Nil(err)
+
+	// 100 * 2 of the deposit of 100000 is needed to back a ticket
+	score, err := sender.HealthScore(sessionID)
+	require.Nil(err)
+	assert.InDelta(1, score, 0.01)
+	assert.True(score <= 1)
+
+	// A session whose deposit is exhausted cannot create tickets
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.info[sender.signer.Account().Address].Deposit = big.NewInt(0)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.NotNil(err)
+
+	score, err = sender.HealthScore(sessionID)
+	require.Nil(err)
+	assert.InDelta(0, score, 0.01)
+
+	// A deposit that barely backs a ticket gives a low score. One of the two validations failed
+	sm.info[sender.signer.Account().Address].Deposit = big.NewInt(210)
+	score, err = sender.HealthScore(sessionID)
+	require.Nil(err)
+	assert.InDelta((1-200.0/210)*(0.6*0.5+0.4), score, 0.001)
+}
+
+func TestHealthScore_ValidationFailures(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(100)
+	sessionID := sender.StartSession(ticketParams)
+
+	// Validation fails against a low max EV
+	sender.maxEV = big.NewRat(0, 1)
+	session, err := sender.loadSession(sessionID)
+	require.Nil(err)
+	session.ticketParams.WinProb = maxWinProb
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.NotNil(err)
+
+	sender.maxEV = big.NewRat(1000, 1)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	score, err := sender.HealthScore(sessionID)
+	require.Nil(err)
+	// Half of the validations succeeded
+	assert.InDelta(0.998*(0.6*0.5+0.4), score, 0.01)
+
+	// Tickets cannot be created once the ticket params expired
+	sender.timeManager.(*stubTimeManager).lastSeenBlock = big.NewInt(1000)
+	score, err = sender.HealthScore(sessionID)
+	require.Nil(err)
+	assert.Zero(score)
+}
@@ -0,0 +1,169 @@
+package pm
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// The benchmarks cover the hot path of ticket creation and validation. Run them with -benchmem.
+// Allocations per op before and after the scratch values of the EV checks, the allocation free
+// ticket encoding and the preallocated batch slices were introduced:
+//
+//	BenchmarkValidateTicketParams    35 -> 3
+//	BenchmarkCreateTicket           100 -> 31
+//	BenchmarkCreateTicketBatch     1395 -> 527
+//	BenchmarkTicketHash              10 -> 3
+
+// flattenLegacy is the encoding of Ticket.flatten using the big.Int and ethcommon helpers
+func flattenLegacy(t *Ticket) []byte {
+	var auxData []byte
+	if t.CreationRound != 0 || (t.CreationRoundBlockHash != ethcommon.Hash{}) {
+		auxData = append(
+			ethcommon.LeftPadBytes(big.NewInt(t.CreationRound).Bytes(), uint256Size),
+			t.CreationRoundBlockHash.Bytes()...,
+		)
+	}
+
+	buf := make([]byte, addressSize+addressSize+uint256Size+uint256Size+uint256Size+bytes32Size+len(auxData))
+	i := copy(buf[0:], t.Recipient.Bytes())
+	i += copy(buf[i:], t.Sender.Bytes())
+	i += copy(buf[i:], ethcommon.LeftPadBytes(t.FaceValue.Bytes(), uint256Size))
+	i += copy(buf[i:], ethcommon.LeftPadBytes(t.WinProb.Bytes(), uint256Size))
+	i += copy(buf[i:], ethcommon.LeftPadBytes(new(big.Int).SetUint64(uint64(t.SenderNonce)).Bytes(), uint256Size))
+	i += copy(buf[i:], t.RecipientRandHash.Bytes())
+	copy(buf[i:], auxData)
+
+	return buf
+}
+
+// randBits returns a random value with up to n bits
+func randBits(r *rand.Rand, n int) *big.Int {
+	return new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), uint(r.Intn(n+1))))
+}
+
+func TestFlatten_MatchesLegacyEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	r := rand.New(rand.NewSource(1))
+	rounds := []int64{0, 1, -1, 1 << 40, -(1 << 40), 1<<63 - 1, -1 << 63}
+	for i := 0; i < 1000; i++ {
+		ticket := &Ticket{
+			Recipient:         RandAddress(),
+			Sender:            RandAddress(),
+			FaceValue:         randBits(r, 256),
+			WinProb:           randBits(r, 256),
+			SenderNonce:       r.Uint32(),
+			RecipientRandHash: RandHash(),
+			CreationRound:     rounds[i%len(rounds)],
+		}
+		if i%3 != 0 {
+			ticket.CreationRoundBlockHash = RandHash()
+		}
+		// Values that do not fit in a uint256 keep their encoding
+		switch i % 50 {
+		case 0:
+			ticket.FaceValue = new(big.Int).Lsh(randBits(r, 64), 257)
+		case 1:
+			ticket.WinProb = new(big.Int).Lsh(big.NewInt(1), 256)
+		case 2:
+			// Wide enough to overrun every field that follows it
+			ticket.FaceValue = new(big.Int).Lsh(big.NewInt(1), 2000)
+			ticket.WinProb = new(big.Int).Lsh(big.NewInt(1), 300)
+		}
+
+		assert.Equal(flattenLegacy(ticket), ticket.flatten())
+		assert.Equal(crypto.Keccak256Hash(flattenLegacy(ticket)), ticket.Hash())
+
+		auxTicket := &Ticket{FaceValue: big.NewInt(0), WinProb: big.NewInt(0), CreationRound: ticket.CreationRound, CreationRoundBlockHash: ticket.CreationRoundBlockHash}
+		assert.Equal(flattenLegacy(auxTicket)[addressSize*2+uint256Size*3+bytes32Size:], ticket.AuxData())
+	}
+}
+
+func TestCmpTicketEV_MatchesTicketEV(t *testing.T) {
+	assert := assert.New(t)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		faceValue := randBits(r, 128)
+		winProb := randBits(r, 256)
+		numTickets := r.Intn(100) + 1
+
+		totalEV := ticketEV(faceValue, winProb)
+		totalEV.Mul(totalEV, big.NewRat(int64(numTickets), 1))
+
+		bounds := []*big.Rat{
+			totalEV,
+			new(big.Rat).SetFrac(randBits(r, 128), big.NewInt(r.Int63n(1000)+1)),
+			new(big.Rat).SetInt(randBits(r, 128)),
+			new(big.Rat),
+		}
+		for _, bound := range bounds {
+			assert.Equal(totalEV.Cmp(bound), cmpTicketEV(faceValue, winProb, numTickets, bound))
+		}
+	}
+}
+
+// benchmarkTicketParams returns ticket params with an EV of 1 wei so that the EV checks do the full math
+func benchmarkTicketParams() TicketParams {
+	ticketParams := defaultTicketParams(nil, RandAddress())
+	ticketParams.FaceValue = big.NewInt(100)
+	ticketParams.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(100))
+	return ticketParams
+}
+
+func BenchmarkValidateTicketParams(b *testing.B) {
+	sender := defaultSender(nil)
+	ticketParams := benchmarkTicketParams()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := sender.ValidateTicketParams(&ticketParams); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateTicket(b *testing.B) {
+	sender := defaultSender(nil)
+	sessionID := sender.StartSession(benchmarkTicketParams())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sender.CreateTicketBatch(sessionID, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateTicketBatch(b *testing.B) {
+	const size = 100
+
+	sender := defaultSender(nil)
+	sessionID := sender.StartSession(benchmarkTicketParams())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sender.CreateTicketBatch(sessionID, size); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTicketHash(b *testing.B) {
+	ticketParams := benchmarkTicketParams()
+	ticket := NewTicket(&ticketParams, &TicketExpirationParams{CreationRound: 5, CreationRoundBlockHash: RandHash()}, RandAddress(), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ticket.Hash()
+	}
+}
@@ -0,0 +1,136 @@
+package pm
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultIdempotencyWindow is the default time for which the batches of idempotency keys are remembered
+const defaultIdempotencyWindow = time.Minute
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is used again for a session with a different batch size
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with different batch size")
+
+// idempotentBatch is a batch created for an idempotency key
+type idempotentBatch struct {
+	size int
+
+	// done is closed once batch and err are set
+	done  chan struct{}
+	batch *TicketBatch
+	err   error
+
+	// expiresAt is the time after which the batch is forgotten. It is set once the batch is created
+	expiresAt time.Time
+
+	elem *list.Element
+}
+
+// idempotentBatches are the batches recently created for idempotency keys keyed by session ID and key
+type idempotentBatches struct {
+	mu      sync.Mutex
+	batches map[string]*idempotentBatch
+	// order holds the map keys of the batches in the order that they were requested
+	order *list.List
+}
+
+// start returns the batch for a session and key. If the batch is new, created is true and the caller must
+// create the batch and call finish. Batches that expired are forgotten
+func (b *idempotentBatches) start(sessionID string, key string, size int) (batch *idempotentBatch, created bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.batches == nil {
+		b.batches = make(map[string]*idempotentBatch)
+		b.order = list.New()
+	}
+
+	b.prune(timeNow())
+
+	mapKey := sessionID + "/" + key
+	if batch, ok := b.batches[mapKey]; ok {
+		if batch.size != size {
+			return nil, false, errors.Wrapf(ErrIdempotencyKeyReused, "key %v used for batch of size %v requested size %v", key, batch.size, size)
+		}
+		return batch, false, nil
+	}
+
+	batch = &idempotentBatch{size: size, done: make(chan struct{})}
+	batch.elem = b.order.PushBack(mapKey)
+	b.batches[mapKey] = batch
+
+	return batch, true, nil
+}
+
+// finish sets the result of a batch and wakes up the callers waiting for it. Failed batches are forgotten
+// right away so that the request can be retried with the same key
+func (b *idempotentBatches) finish(batch *idempotentBatch, ticketBatch *TicketBatch, err error, window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch.batch, batch.err = ticketBatch, err
+	batch.expiresAt = timeNow().Add(window)
+	close(batch.done)
+
+	if err != nil {
+		delete(b.batches, batch.elem.Value.(string))
+		b.order.Remove(batch.elem)
+	}
+}
+
+// prune forgets the batches that expired before now. Batches are pruned in the order that they were
+// requested and pruning stops at the first batch that has not expired or is still being created.
+// The caller must hold mu
+func (b *idempotentBatches) prune(now time.Time) {
+	for elem := b.order.Front(); elem != nil; elem = b.order.Front() {
+		mapKey := elem.Value.(string)
+		batch := b.batches[mapKey]
+
+		select {
+		case <-batch.done:
+		default:
+			return
+		}
+
+		if now.Before(batch.expiresAt) {
+			return
+		}
+
+		delete(b.batches, mapKey)
+		b.order.Remove(elem)
+	}
+}
+
+// CreateTicketBatchWithKey creates a ticket batch of the specified size like CreateTicketBatch unless a batch was
+// already requested for the session with the same idempotency key within the IdempotencyWindow. In that case the
+// call waits for the batch of the earlier request and returns the same batch instead of allocating new nonces so
+// that retries of a request do not waste tickets. Failed requests are not remembered and can be retried with
+// the same key. ErrIdempotencyKeyReused is returned if the key was used for a batch of a different size
+func (s *sender) CreateTicketBatchWithKey(sessionID string, size int, key string) (*TicketBatch, error) {
+	if key == "" {
+		return s.CreateTicketBatch(sessionID, size)
+	}
+
+	batch, created, err := s.idempotentBatches.start(sessionID, key, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		<-batch.done
+		return batch.batch, batch.err
+	}
+
+	window := s.cfg.IdempotencyWindow
+	if window <= 0 {
+		window = defaultIdempotencyWindow
+	}
+
+	ticketBatch, err := s.CreateTicketBatch(sessionID, size)
+	s.idempotentBatches.finish(batch, ticketBatch, err, window)
+
+	return ticketBatch, err
+}
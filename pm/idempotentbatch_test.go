@@ -0,0 +1,121 @@
+package pm
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTicketBatchWithKey_ConcurrentCallsReturnSameBatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	signing := make(chan struct{})
+	proceed := make(chan struct{})
+	sender.signer = &hookSigner{
+		stubSigner: *sender.signer.(*stubSigner),
+		onSign: func(calls int) {
+			if calls == 1 {
+				close(signing)
+				<-proceed
+			}
+		},
+	}
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	type result struct {
+		batch *TicketBatch
+		err   error
+	}
+	results := make(chan result, 2)
+	create := func() {
+		batch, err := sender.CreateTicketBatchWithKey(sessionID, 3, "retry")
+		results <- result{batch, err}
+	}
+
+	// The retry arrives while the first request is still signing its batch
+	go create()
+	<-signing
+	go create()
+	time.Sleep(20 * time.Millisecond)
+	close(proceed)
+
+	first, second := <-results, <-results
+	require.Nil(first.err)
+	require.Nil(second.err)
+	assert.True(first.batch == second.batch)
+	require.Len(first.batch.SenderParams, 3)
+	assert.Equal(uint32(1), first.batch.SenderParams[0].SenderNonce)
+
+	session, err := sender.loadSession(sessionID)
+	require.Nil(err)
+	assert.Equal(uint32(3), atomic.LoadUint32(&session.senderNonce))
+
+	// A later retry within the window returns the same batch
+	batch, err := sender.CreateTicketBatchWithKey(sessionID, 3, "retry")
+	require.Nil(err)
+	assert.True(first.batch == batch)
+
+	// Other keys and requests without a key allocate new nonces
+	batch, err = sender.CreateTicketBatchWithKey(sessionID, 3, "other")
+	require.Nil(err)
+	assert.Equal(uint32(4), batch.SenderParams[0].SenderNonce)
+	batch, err = sender.CreateTicketBatchWithKey(sessionID, 1, "")
+	require.Nil(err)
+	assert.Equal(uint32(7), batch.SenderParams[0].SenderNonce)
+
+	_, err = sender.CreateTicketBatchWithKey(sessionID, 2, "retry")
+	assert.Equal(ErrIdempotencyKeyReused, errors.Cause(err))
+}
+
+func TestCreateTicketBatchWithKey_Window(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1600000000, 0)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	sender.cfg.IdempotencyWindow = time.Second
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	first, err := sender.CreateTicketBatchWithKey(sessionID, 1, "key")
+	require.Nil(err)
+
+	now = now.Add(500 * time.Millisecond)
+	batch, err := sender.CreateTicketBatchWithKey(sessionID, 1, "key")
+	require.Nil(err)
+	assert.True(first == batch)
+
+	// The key is forgotten once the window passed
+	now = now.Add(time.Second)
+	batch, err = sender.CreateTicketBatchWithKey(sessionID, 1, "key")
+	require.Nil(err)
+	assert.Equal(uint32(2), batch.SenderParams[0].SenderNonce)
+	assert.Len(sender.idempotentBatches.batches, 1)
+}
+
+func TestCreateTicketBatchWithKey_FailedRequestIsRetried(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	signer := &flakySigner{failEvery: 1}
+	signer.account = sender.signer.Account()
+	sender.signer = signer
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatchWithKey(sessionID, 2, "key")
+	require.NotNil(err)
+
+	signer.failEvery = 100
+	batch, err := sender.CreateTicketBatchWithKey(sessionID, 2, "key")
+	require.Nil(err)
+	assert.Len(batch.SenderParams, 2)
+}
@@ -0,0 +1,176 @@
+package pm
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// IssuanceRecord describes a ticket that was successfully created by a sender
+type IssuanceRecord struct {
+	SenderNonce uint32
+
+	FaceValue *big.Int
+
+	Timestamp time.Time
+
+	// Hash is the hash of the ticket that was signed
+	Hash ethcommon.Hash
+}
+
+// issuanceLog is a bounded ring buffer of issuance records.
+// Once the log reaches its capacity the oldest records are evicted. If the log is charged against
+// a trackingBudget the oldest records can also be evicted to keep the sender within its budget
+type issuanceLog struct {
+	mu sync.Mutex
+	// records holds the records in the log starting at start. It grows up to size
+	records []IssuanceRecord
+	// seqs holds the budget sequence numbers of the records if the log is charged against a budget
+	seqs  []uint64
+	start int
+	count int
+	size  int
+
+	budget *trackingBudget
+}
+
+func newIssuanceLog(size int, budget *trackingBudget) *issuanceLog {
+	l := &issuanceLog{size: size}
+	if size > 0 && budget != nil {
+		l.budget = budget
+		budget.register(l)
+	}
+
+	return l
+}
+
+func (l *issuanceLog) append(record IssuanceRecord) {
+	l.mu.Lock()
+
+	if l.size <= 0 {
+		l.mu.Unlock()
+		return
+	}
+
+	if l.count == l.size {
+		l.dropOldest()
+	}
+
+	if l.count == len(l.records) {
+		l.grow()
+	}
+
+	i := (l.start + l.count) % len(l.records)
+	l.records[i] = record
+	if l.budget != nil {
+		l.seqs[i] = l.budget.nextSeq()
+		l.budget.charge(issuanceRecordBytes)
+	}
+	l.count++
+
+	l.mu.Unlock()
+
+	l.budget.enforce()
+}
+
+// dropOldest evicts the oldest record of the log. The caller must hold mu
+func (l *issuanceLog) dropOldest() {
+	l.records[l.start] = IssuanceRecord{}
+	l.start = (l.start + 1) % len(l.records)
+	l.count--
+
+	if l.budget != nil {
+		l.budget.charge(-issuanceRecordBytes)
+	}
+}
+
+// grow extends the buffer in order so that the next record can be written after the last record.
+// The caller must hold mu
+func (l *issuanceLog) grow() {
+	size := 2 * len(l.records)
+	if size == 0 {
+		size = 1
+	}
+	if size > l.size {
+		size = l.size
+	}
+
+	records := make([]IssuanceRecord, size)
+	var seqs []uint64
+	if l.budget != nil {
+		seqs = make([]uint64, size)
+	}
+
+	for i := 0; i < l.count; i++ {
+		records[i] = l.records[(l.start+i)%len(l.records)]
+		if seqs != nil {
+			seqs[i] = l.seqs[(l.start+i)%len(l.seqs)]
+		}
+	}
+
+	l.records, l.seqs, l.start = records, seqs, 0
+}
+
+// ordered returns the records ordered from oldest to newest. The caller must hold mu
+func (l *issuanceLog) ordered() []IssuanceRecord {
+	records := make([]IssuanceRecord, 0, l.count)
+	for i := 0; i < l.count; i++ {
+		records = append(records, l.records[(l.start+i)%len(l.records)])
+	}
+
+	return records
+}
+
+// list returns the records in the log ordered from oldest to newest
+func (l *issuanceLog) list() []IssuanceRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == 0 {
+		return nil
+	}
+
+	return l.ordered()
+}
+
+func (l *issuanceLog) oldestSeq() (uint64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == 0 {
+		return 0, false
+	}
+
+	return l.seqs[l.start], true
+}
+
+func (l *issuanceLog) evict(before uint64, bytes int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var freed int64
+	for l.count > 0 && (freed == 0 || (freed < bytes && l.seqs[l.start] < before)) {
+		l.records[l.start] = IssuanceRecord{}
+		l.start = (l.start + 1) % len(l.records)
+		l.count--
+		freed += issuanceRecordBytes
+	}
+
+	return freed
+}
+
+// release removes the records of the log from its budget when the session of the log is removed
+func (l *issuanceLog) release() {
+	if l == nil || l.budget == nil {
+		return
+	}
+
+	l.mu.Lock()
+	l.budget.charge(-int64(l.count) * issuanceRecordBytes)
+	l.records, l.seqs, l.start, l.count, l.size = nil, nil, 0, 0, 0
+	l.mu.Unlock()
+
+	l.budget.unregister(l)
+}
@@ -0,0 +1,83 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuanceLog_ZeroSize(t *testing.T) {
+	log := newIssuanceLog(0, nil)
+	log.append(IssuanceRecord{SenderNonce: 1})
+	assert.Empty(t, log.list())
+}
+
+func TestIssuanceLog_EvictsOldestRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	log := newIssuanceLog(3, nil)
+	assert.Empty(log.list())
+
+	for i := uint32(1); i <= 2; i++ {
+		log.append(IssuanceRecord{SenderNonce: i, FaceValue: big.NewInt(int64(i))})
+	}
+	assert.Equal([]uint32{1, 2}, recordNonces(log.list()))
+
+	log.append(IssuanceRecord{SenderNonce: 3})
+	assert.Equal([]uint32{1, 2, 3}, recordNonces(log.list()))
+
+	log.append(IssuanceRecord{SenderNonce: 4})
+	assert.Equal([]uint32{2, 3, 4}, recordNonces(log.list()))
+
+	for i := uint32(5); i <= 9; i++ {
+		log.append(IssuanceRecord{SenderNonce: i})
+	}
+	assert.Equal([]uint32{7, 8, 9}, recordNonces(log.list()))
+}
+
+func recordNonces(records []IssuanceRecord) []uint32 {
+	var nonces []uint32
+	for _, record := range records {
+		nonces = append(nonces, record.SenderNonce)
+	}
+	return nonces
+}
+
+func TestIssuanceLog_FailedBatchesAreNotRecorded(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	signer := &flakySigner{failEvery: 3}
+	signer.account = sender.signer.Account()
+	sender.signer = signer
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	drainEvents(sender)
+
+	// The third signature fails after the first two tickets were signed
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.NotNil(err)
+	assert.Empty(sender.events)
+
+	signer.failEvery = 100
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+
+	session, err := sender.loadSession(sessionID)
+	require.Nil(err)
+	var nonces []uint32
+	for _, record := range session.issuanceLog.list() {
+		nonces = append(nonces, record.SenderNonce)
+	}
+	assert.Equal([]uint32{1, 2, 3}, nonces)
+
+	for _, nonce := range nonces {
+		e := <-sender.events
+		assert.Equal(TicketCreated, e.Type)
+		assert.Equal(nonce, e.SenderNonce)
+	}
+	assert.Empty(sender.events)
+}
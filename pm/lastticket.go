@@ -0,0 +1,80 @@
+package pm
+
+import "math/big"
+
+// lastTicket is the most recently created ticket for a session and its signature
+type lastTicket struct {
+	ticket *Ticket
+	sig    []byte
+}
+
+// LastTicket returns a copy of the ticket most recently created for a session and its signature.
+// false is returned if the session is unknown or no tickets were created for it. Peek tickets and
+// tickets of batches that failed to be created are not recorded
+func (s *sender) LastTicket(sessionID string) (*Ticket, []byte, bool) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	session.lastTicketMu.Lock()
+	defer session.lastTicketMu.Unlock()
+
+	if session.lastTicket == nil {
+		return nil, nil, false
+	}
+
+	return copyTicket(session.lastTicket.ticket), copyBytes(session.lastTicket.sig), true
+}
+
+// recordLastBatchTicket records the last ticket of a batch that was created for a session
+func (s *sender) recordLastBatchTicket(session *session, batch *TicketBatch) {
+	if len(batch.SenderParams) == 0 {
+		return
+	}
+
+	senderParams := batch.SenderParams[len(batch.SenderParams)-1]
+	ticket := NewTicket(batch.TicketParams, batch.TicketExpirationParams, batch.Sender, senderParams.SenderNonce)
+	s.recordLastTicket(session, ticket, senderParams.Sig)
+}
+
+// recordLastTicket records a ticket that was created for a session
+func (s *sender) recordLastTicket(session *session, ticket *Ticket, sig []byte) {
+	last := &lastTicket{
+		ticket: copyTicket(ticket),
+		sig:    copyBytes(sig),
+	}
+
+	session.lastTicketMu.Lock()
+	session.lastTicket = last
+	session.lastTicketMu.Unlock()
+}
+
+// copyTicket returns a copy of a ticket that does not share any values with the original
+func copyTicket(ticket *Ticket) *Ticket {
+	cp := *ticket
+	cp.FaceValue = copyBigInt(ticket.FaceValue)
+	cp.WinProb = copyBigInt(ticket.WinProb)
+	cp.ParamsExpirationBlock = copyBigInt(ticket.ParamsExpirationBlock)
+	if ticket.PricePerPixel != nil {
+		cp.PricePerPixel = new(big.Rat).Set(ticket.PricePerPixel)
+	}
+
+	return &cp
+}
+
+func copyBigInt(x *big.Int) *big.Int {
+	if x == nil {
+		return nil
+	}
+
+	return new(big.Int).Set(x)
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,80 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastTicket(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	am := sender.signer.(*stubSigner)
+	params := defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(10)
+	sessionID := sender.StartSession(params)
+
+	_, _, ok := sender.LastTicket("foo")
+	assert.False(ok)
+	_, _, ok = sender.LastTicket(sessionID)
+	assert.False(ok)
+
+	am.signResponse = []byte("first")
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	ticket, sig, ok := sender.LastTicket(sessionID)
+	require.True(ok)
+	assert.Equal(uint32(3), ticket.SenderNonce)
+	assert.Equal([]byte("first"), sig)
+
+	am.signResponse = []byte("second")
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	ticket, sig, ok = sender.LastTicket(sessionID)
+	require.True(ok)
+	assert.Equal(uint32(5), ticket.SenderNonce)
+	assert.Equal([]byte("second"), sig)
+	assert.Equal(batch.Tickets()[1].Hash(), ticket.Hash())
+	assert.Equal(params.ExpirationBlock, ticket.ParamsExpirationBlock)
+
+	am.signResponse = []byte("third")
+	batches, err := sender.CreateBatchSplitByRound(sessionID, 2)
+	require.Nil(err)
+	ticket, sig, ok = sender.LastTicket(sessionID)
+	require.True(ok)
+	assert.Equal(uint32(7), ticket.SenderNonce)
+	assert.Equal([]byte("third"), sig)
+	assert.Equal(batches[0].Tickets()[1].Hash(), ticket.Hash())
+
+	am.signResponse = []byte("fourth")
+	atRound, _, err := sender.CreateTicketAtRound(sessionID, 2, [32]byte{2})
+	require.Nil(err)
+	ticket, sig, ok = sender.LastTicket(sessionID)
+	require.True(ok)
+	assert.Equal(atRound, ticket)
+	assert.Equal([]byte("fourth"), sig)
+
+	// Failed batches and peek tickets are not recorded
+	am.signShouldFail = true
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.NotNil(err)
+	am.signShouldFail = false
+	_, _, err = sender.PeekTicket(sessionID)
+	require.Nil(err)
+	ticket, _, ok = sender.LastTicket(sessionID)
+	require.True(ok)
+	assert.Equal(uint32(8), ticket.SenderNonce)
+	assert.Equal(int64(2), ticket.CreationRound)
+
+	// The returned ticket and signature are copies
+	ticket.FaceValue.SetInt64(1000)
+	sig[0] = 'x'
+	ticket, sig, _ = sender.LastTicket(sessionID)
+	assert.Equal(big.NewInt(10), ticket.FaceValue)
+	assert.Equal([]byte("fourth"), sig)
+	assert.Equal(big.NewInt(10), params.FaceValue)
+}
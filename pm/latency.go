@@ -0,0 +1,89 @@
+package pm
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// latencyMinBucket is the upper bound of the first latency histogram bucket
+	latencyMinBucket = time.Microsecond
+	// latencyBucketGrowth is the ratio between the upper bounds of consecutive buckets
+	latencyBucketGrowth = 1.1
+	// latencyNumBuckets is the number of buckets which covers latencies up to ~2 minutes
+	latencyNumBuckets = 196
+)
+
+// SigningStats describes the latency of the signer's Sign calls
+type SigningStats struct {
+	Count uint64
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// latencyHistogram is a fixed size histogram of durations with exponentially growing buckets.
+// Observations are recorded with atomic operations only so recording never blocks.
+// Percentiles are approximated by the upper bound of the bucket they fall in
+// which is accurate to within latencyBucketGrowth
+type latencyHistogram struct {
+	buckets [latencyNumBuckets]uint64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	atomic.AddUint64(&h.buckets[latencyBucket(d)], 1)
+}
+
+func (h *latencyHistogram) stats() SigningStats {
+	var counts [latencyNumBuckets]uint64
+	var total uint64
+	for i := range h.buckets {
+		counts[i] = atomic.LoadUint64(&h.buckets[i])
+		total += counts[i]
+	}
+
+	return SigningStats{
+		Count: total,
+		P50:   percentile(&counts, total, 0.5),
+		P90:   percentile(&counts, total, 0.9),
+		P99:   percentile(&counts, total, 0.99),
+	}
+}
+
+func percentile(counts *[latencyNumBuckets]uint64, total uint64, p float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p * float64(total)))
+	var cumulative uint64
+	for i, count := range counts {
+		cumulative += count
+		if cumulative >= rank {
+			return latencyBucketBound(i)
+		}
+	}
+
+	return latencyBucketBound(latencyNumBuckets - 1)
+}
+
+// latencyBucket returns the index of the first bucket whose upper bound is >= d
+func latencyBucket(d time.Duration) int {
+	if d <= latencyMinBucket {
+		return 0
+	}
+
+	i := int(math.Ceil(math.Log(float64(d)/float64(latencyMinBucket)) / math.Log(latencyBucketGrowth)))
+	if i >= latencyNumBuckets {
+		return latencyNumBuckets - 1
+	}
+
+	return i
+}
+
+// latencyBucketBound returns the upper bound of a bucket
+func latencyBucketBound(i int) time.Duration {
+	return time.Duration(float64(latencyMinBucket) * math.Pow(latencyBucketGrowth, float64(i)))
+}
@@ -0,0 +1,46 @@
+package pm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram_Empty(t *testing.T) {
+	var h latencyHistogram
+	assert.Equal(t, SigningStats{}, h.stats())
+}
+
+func TestLatencyHistogram_Percentiles(t *testing.T) {
+	assert := assert.New(t)
+
+	var h latencyHistogram
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	stats := h.stats()
+	assert.Equal(uint64(100), stats.Count)
+	assertLatency(t, 50*time.Millisecond, stats.P50)
+	assertLatency(t, 90*time.Millisecond, stats.P90)
+	assertLatency(t, 99*time.Millisecond, stats.P99)
+}
+
+func TestLatencyHistogram_OutOfRange(t *testing.T) {
+	assert := assert.New(t)
+
+	var h latencyHistogram
+	h.observe(0)
+	assert.Equal(latencyMinBucket, h.stats().P50)
+
+	h = latencyHistogram{}
+	h.observe(24 * time.Hour)
+	assert.Equal(latencyBucketBound(latencyNumBuckets-1), h.stats().P50)
+}
+
+// assertLatency checks that an approximated latency is within one bucket of the expected latency
+func assertLatency(t *testing.T, expected, actual time.Duration) {
+	assert.True(t, actual >= expected, "expected %v >= %v", actual, expected)
+	assert.True(t, float64(actual) <= float64(expected)*latencyBucketGrowth, "expected %v <= %v", actual, time.Duration(float64(expected)*latencyBucketGrowth))
+}
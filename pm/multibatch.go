@@ -0,0 +1,101 @@
+package pm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// BatchRequest describes a ticket batch to create for a session
+type BatchRequest struct {
+	SessionID string
+	Size      int
+}
+
+// MultiRecipientBatch groups ticket batches for multiple recipients so that they can be
+// delivered together when a sender pays several recipients at the same time.
+// A MultiRecipientBatch contains at most one batch per recipient
+type MultiRecipientBatch struct {
+	Batches []*TicketBatch
+}
+
+// Recipients returns the recipients of the batches in the order that the batches were added
+func (b *MultiRecipientBatch) Recipients() []ethcommon.Address {
+	var recipients []ethcommon.Address
+	for _, batch := range b.Batches {
+		recipients = append(recipients, batch.Recipient)
+	}
+
+	return recipients
+}
+
+// Batch returns the ticket batch for a recipient
+func (b *MultiRecipientBatch) Batch(recipient ethcommon.Address) (*TicketBatch, bool) {
+	for _, batch := range b.Batches {
+		if batch.Recipient == recipient {
+			return batch, true
+		}
+	}
+
+	return nil, false
+}
+
+// Encode returns the serialized representation of the batch
+func (b *MultiRecipientBatch) Encode() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// DecodeMultiRecipientBatch parses a batch serialized with MultiRecipientBatch.Encode
+func DecodeMultiRecipientBatch(data []byte) (*MultiRecipientBatch, error) {
+	var b MultiRecipientBatch
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, errors.Wrap(err, "error decoding multi recipient batch")
+	}
+
+	seen := make(map[ethcommon.Address]bool)
+	for i, batch := range b.Batches {
+		if batch == nil || batch.TicketParams == nil || batch.TicketExpirationParams == nil {
+			return nil, fmt.Errorf("error decoding multi recipient batch: missing params for batch %v", i)
+		}
+
+		if seen[batch.Recipient] {
+			return nil, fmt.Errorf("error decoding multi recipient batch: multiple batches for recipient %x", batch.Recipient)
+		}
+		seen[batch.Recipient] = true
+	}
+
+	return &b, nil
+}
+
+// CreateMultiRecipientBatch creates a ticket batch for each request and groups them in a MultiRecipientBatch.
+// The sessions of the requests must be for different recipients. If creating a batch fails, the nonces
+// used by the batches created for earlier requests are not reused
+func (s *sender) CreateMultiRecipientBatch(requests []BatchRequest) (*MultiRecipientBatch, error) {
+	seen := make(map[ethcommon.Address]bool)
+	for _, req := range requests {
+		session, err := s.loadIssuableSession(req.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		recipient := session.ticketParams.Recipient
+		if seen[recipient] {
+			return nil, fmt.Errorf("multiple sessions for recipient %x", recipient)
+		}
+		seen[recipient] = true
+	}
+
+	multiBatch := &MultiRecipientBatch{}
+	for _, req := range requests {
+		batch, err := s.CreateTicketBatch(req.SessionID, req.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		multiBatch.Batches = append(multiBatch.Batches, batch)
+	}
+
+	return multiBatch, nil
+}
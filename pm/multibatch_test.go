@@ -0,0 +1,99 @@
+package pm
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMultiRecipientBatch_EncodeDecode(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.signer.(*stubSigner).signResponse = []byte("foo")
+
+	recipient0 := RandAddress()
+	recipient1 := RandAddress()
+	ticketParams1 := defaultTicketParams(t, recipient1)
+	ticketParams1.FaceValue = big.NewInt(100)
+	ticketParams1.WinProb = big.NewInt(5)
+	ticketParams1.ExpirationParams = &TicketExpirationParams{CreationRound: 4, CreationRoundBlockHash: ethcommon.Hash{4}}
+	sessionID0 := sender.StartSession(defaultTicketParams(t, recipient0))
+	sessionID1 := sender.StartSession(ticketParams1)
+
+	multiBatch, err := sender.CreateMultiRecipientBatch([]BatchRequest{
+		{SessionID: sessionID0, Size: 2},
+		{SessionID: sessionID1, Size: 3},
+	})
+	require.Nil(err)
+	assert.Equal([]ethcommon.Address{recipient0, recipient1}, multiBatch.Recipients())
+
+	data, err := multiBatch.Encode()
+	require.Nil(err)
+	decoded, err := DecodeMultiRecipientBatch(data)
+	require.Nil(err)
+	require.Len(decoded.Batches, 2)
+
+	for i, recipient := range []ethcommon.Address{recipient0, recipient1} {
+		batch, ok := decoded.Batch(recipient)
+		require.True(ok)
+		assert.Equal(multiBatch.Batches[i].Tickets(), batch.Tickets())
+		assert.Equal(multiBatch.Batches[i].SenderParams, batch.SenderParams)
+		assert.Equal(multiBatch.Batches[i].Sender, batch.Sender)
+		assert.Zero(multiBatch.Batches[i].PricePerPixel.Cmp(batch.PricePerPixel))
+		assert.Zero(multiBatch.Batches[i].ExpirationBlock.Cmp(batch.ExpirationBlock))
+	}
+
+	batch, _ := decoded.Batch(recipient1)
+	assert.Len(batch.SenderParams, 3)
+	assert.Equal(int64(4), batch.CreationRound)
+	assert.Equal(big.NewInt(100), batch.FaceValue)
+
+	_, ok := decoded.Batch(RandAddress())
+	assert.False(ok)
+}
+
+func TestCreateMultiRecipientBatch_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	recipient := RandAddress()
+	sessionID0 := sender.StartSession(defaultTicketParams(t, recipient))
+	sessionID1 := sender.StartSession(defaultTicketParams(t, recipient))
+
+	_, err := sender.CreateMultiRecipientBatch([]BatchRequest{{SessionID: "foo", Size: 1}})
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	_, err = sender.CreateMultiRecipientBatch([]BatchRequest{{SessionID: sessionID0, Size: 1}, {SessionID: sessionID1, Size: 1}})
+	assert.EqualError(err, fmt.Sprintf("multiple sessions for recipient %x", recipient))
+
+	// No nonces are used if the requests are invalid
+	for _, info := range sender.ListSessions() {
+		assert.Equal(uint32(0), info.SenderNonce)
+	}
+}
+
+func TestDecodeMultiRecipientBatch_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := DecodeMultiRecipientBatch([]byte("foo"))
+	assert.Contains(err.Error(), "error decoding multi recipient batch")
+
+	_, err = DecodeMultiRecipientBatch([]byte(`{"Batches":[{"Sender":"0x0000000000000000000000000000000000000001"}]}`))
+	assert.EqualError(err, "error decoding multi recipient batch: missing params for batch 0")
+
+	batch := &TicketBatch{
+		TicketParams:           &TicketParams{Recipient: ethcommon.Address{1}},
+		TicketExpirationParams: &TicketExpirationParams{},
+	}
+	data, err := (&MultiRecipientBatch{Batches: []*TicketBatch{batch, batch}}).Encode()
+	assert.Nil(err)
+	_, err = DecodeMultiRecipientBatch(data)
+	assert.EqualError(err, "error decoding multi recipient batch: multiple batches for recipient 0100000000000000000000000000000000000000")
+}
@@ -0,0 +1,84 @@
+package pm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMightHaveIssued(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.NonceFilterSize = 1 << 16
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	assert.False(sender.MightHaveIssued("foo", 1))
+
+	const numTickets = 5000
+	for issued := 0; issued < numTickets; issued += 100 {
+		_, err := sender.CreateTicketBatch(sessionID, 100)
+		require.Nil(err)
+	}
+
+	// There are no false negatives for issued nonces
+	for nonce := uint32(1); nonce <= numTickets; nonce++ {
+		require.True(sender.MightHaveIssued(sessionID, nonce), "nonce %v", nonce)
+	}
+
+	// The false positive rate for 5000 nonces in 65536 bits with 4 hash functions is about 0.5%
+	falsePositives := 0
+	const numChecks = 100000
+	for nonce := uint32(numTickets + 1); nonce <= numTickets+numChecks; nonce++ {
+		if sender.MightHaveIssued(sessionID, nonce) {
+			falsePositives++
+		}
+	}
+	assert.True(falsePositives < numChecks/100, "%v false positives", falsePositives)
+
+	// Nonces issued after the nonce was advanced are added to the filter
+	require.Nil(sender.AdvanceNonce(sessionID, numTickets+numChecks))
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.True(sender.MightHaveIssued(sessionID, batch.SenderParams[0].SenderNonce))
+}
+
+func TestMightHaveIssued_NoFilter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+
+	// Every allocated nonce might have been issued
+	assert.False(sender.MightHaveIssued(sessionID, 0))
+	for nonce := uint32(1); nonce <= 3; nonce++ {
+		assert.True(sender.MightHaveIssued(sessionID, nonce))
+	}
+	assert.False(sender.MightHaveIssued(sessionID, 4))
+}
+
+func TestNonceFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newNonceFilter(0, 4))
+
+	f := newNonceFilter(100, 0)
+	assert.Len(f.bits, 2)
+	assert.Equal(defaultNonceFilterHashes, f.hashes)
+
+	// Nonces across the whole range are found after they are added
+	nonces := []uint32{0, 1, 2, 1 << 31, ^uint32(0) - 1}
+	for _, nonce := range nonces {
+		f.add(nonce, 2)
+	}
+	for _, nonce := range nonces {
+		assert.True(f.mightContain(nonce))
+		assert.True(f.mightContain(nonce + 1))
+	}
+}
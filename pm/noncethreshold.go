@@ -0,0 +1,66 @@
+package pm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+)
+
+// nonceThreshold is a configured NonceThresholds percentage and the nonce at which it is crossed
+type nonceThreshold struct {
+	pct   int
+	nonce uint32
+}
+
+// newNonceThresholds returns the thresholds for the NonceThresholds percentages ordered by nonce. The percentages
+// are relative to MaxNoncePerSession or to the range of uint32 if no MaxNoncePerSession is configured
+func newNonceThresholds(cfg SenderConfig) ([]nonceThreshold, error) {
+	maxNonce := uint64(cfg.MaxNoncePerSession)
+	if maxNonce == 0 {
+		maxNonce = math.MaxUint32
+	}
+
+	var thresholds []nonceThreshold
+	for _, pct := range cfg.NonceThresholds {
+		if pct < 1 || pct > 100 {
+			return nil, fmt.Errorf("nonce threshold %v%% must be between 1%% and 100%%", pct)
+		}
+
+		// The threshold is crossed by the first nonce that is at least pct percent of the max nonce
+		nonce := (maxNonce*uint64(pct) + 99) / 100
+		thresholds = append(thresholds, nonceThreshold{pct: pct, nonce: uint32(nonce)})
+	}
+
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i].pct < thresholds[j].pct })
+
+	// Percentages that are configured more than once are only reported once
+	unique := thresholds[:0]
+	for i, threshold := range thresholds {
+		if i == 0 || threshold.pct != thresholds[i-1].pct {
+			unique = append(unique, threshold)
+		}
+	}
+
+	return unique, nil
+}
+
+// checkNonceThresholds calls OnNonceThreshold for every threshold that a nonce issued for a session crosses and
+// that was not crossed by an earlier nonce of the session
+func (s *sender) checkNonceThresholds(sessionID string, session *session, nonce uint32) {
+	if s.cfg.OnNonceThreshold == nil {
+		return
+	}
+
+	for {
+		next := atomic.LoadUint32(&session.nextNonceThreshold)
+		if int(next) >= len(s.nonceThresholds) || nonce < s.nonceThresholds[next].nonce {
+			return
+		}
+
+		// Only the caller that advances past the threshold fires it so each threshold fires once per session
+		if atomic.CompareAndSwapUint32(&session.nextNonceThreshold, next, next+1) {
+			s.cfg.OnNonceThreshold(sessionID, nonce, s.nonceThresholds[next].pct)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pm
+
+import (
+	"math"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nonceThresholdCall struct {
+	sessionID string
+	nonce     uint32
+	pct       int
+}
+
+func TestNewNonceThresholds(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	thresholds, err := newNonceThresholds(SenderConfig{MaxNoncePerSession: 10, NonceThresholds: []int{90, 50, 90, 100, 1}})
+	require.Nil(err)
+	assert.Equal([]nonceThreshold{{1, 1}, {50, 5}, {90, 9}, {100, 10}}, thresholds)
+
+	// Thresholds round up to the first nonce that reaches the percentage
+	thresholds, err = newNonceThresholds(SenderConfig{MaxNoncePerSession: 3, NonceThresholds: []int{50}})
+	require.Nil(err)
+	assert.Equal([]nonceThreshold{{50, 2}}, thresholds)
+
+	// Without a max nonce per session the thresholds are relative to the range of uint32
+	thresholds, err = newNonceThresholds(SenderConfig{NonceThresholds: []int{100}})
+	require.Nil(err)
+	assert.Equal([]nonceThreshold{{100, math.MaxUint32}}, thresholds)
+
+	_, err = newNonceThresholds(SenderConfig{NonceThresholds: []int{0}})
+	assert.EqualError(err, "nonce threshold 0% must be between 1% and 100%")
+	_, err = newNonceThresholds(SenderConfig{NonceThresholds: []int{101}})
+	assert.EqualError(err, "nonce threshold 101% must be between 1% and 100%")
+
+	_, err = NewSender(&stubSigner{}, &stubTimeManager{}, newStubSenderManager(), big.NewRat(100, 1), 2, SenderConfig{NonceThresholds: []int{-1}})
+	assert.EqualError(err, "nonce threshold -1% must be between 1% and 100%")
+}
+
+func TestOnNonceThreshold(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var (
+		mu    sync.Mutex
+		calls []nonceThresholdCall
+	)
+	sender := defaultSender(t)
+	sender.cfg.MaxNoncePerSession = 10
+	sender.cfg.OnNonceThreshold = func(sessionID string, nonce uint32, thresholdPct int) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, nonceThresholdCall{sessionID, nonce, thresholdPct})
+	}
+	thresholds, err := newNonceThresholds(SenderConfig{MaxNoncePerSession: 10, NonceThresholds: []int{50, 90}})
+	require.Nil(err)
+	sender.nonceThresholds = thresholds
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	otherSessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err = sender.CreateTicketBatch(sessionID, 4)
+	require.Nil(err)
+	assert.Empty(calls)
+
+	// The 50% threshold is crossed by nonce 5 and fires once
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal([]nonceThresholdCall{{sessionID, 5, 50}}, calls)
+
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	assert.Len(calls, 1)
+
+	// Each session crosses thresholds on its own and a batch can cross several thresholds at once
+	_, err = sender.CreateTicketBatch(otherSessionID, 9)
+	require.Nil(err)
+	assert.Equal([]nonceThresholdCall{{sessionID, 5, 50}, {otherSessionID, 5, 50}, {otherSessionID, 9, 90}}, calls)
+
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	assert.Equal(nonceThresholdCall{sessionID, 9, 90}, calls[3])
+	assert.Len(calls, 4)
+}
@@ -0,0 +1,35 @@
+package pm
+
+import "time"
+
+// SenderObserver is notified synchronously of a sender's activity. Unlike the event channel,
+// an observer is notified of every event even if the event channel buffer is full so its methods
+// should return quickly and must be safe for concurrent use
+type SenderObserver interface {
+	// ObserveEvent is called for each event emitted by the sender
+	ObserveEvent(event SenderEvent)
+
+	// ObserveBatch is called for each ticket batch created for a session
+	ObserveBatch(sessionID string, size int)
+
+	// ObserveSign is called with the latency of each of the signer's Sign calls
+	ObserveSign(latency time.Duration)
+}
+
+func (s *sender) observeEvent(event SenderEvent) {
+	if s.cfg.Observer != nil {
+		s.cfg.Observer.ObserveEvent(event)
+	}
+}
+
+func (s *sender) observeBatch(sessionID string, batch *TicketBatch) {
+	if s.cfg.Observer != nil {
+		s.cfg.Observer.ObserveBatch(sessionID, len(batch.SenderParams))
+	}
+}
+
+func (s *sender) observeSign(latency time.Duration) {
+	if s.cfg.Observer != nil {
+		s.cfg.Observer.ObserveSign(latency)
+	}
+}
@@ -0,0 +1,64 @@
+package pm
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	events  []SenderEvent
+	batches []int
+	signs   int
+	mu      sync.Mutex
+}
+
+func (o *recordingObserver) ObserveEvent(event SenderEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) ObserveBatch(sessionID string, size int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.batches = append(o.batches, size)
+}
+
+func (o *recordingObserver) ObserveSign(latency time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.signs++
+}
+
+func TestSenderObserver(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	observer := &recordingObserver{}
+	sender.cfg.Observer = observer
+	// Observers are notified even if events are dropped
+	sender.events = make(chan SenderEvent)
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	batches, err := sender.CreateBatchSplitByRound(sessionID, 2)
+	require.Nil(err)
+	require.Len(batches, 1)
+	_, _, err = sender.PeekTicket(sessionID)
+	require.Nil(err)
+
+	require.Len(observer.events, 6)
+	assert.Equal(SessionStarted, observer.events[0].Type)
+	for _, event := range observer.events[1:] {
+		assert.Equal(TicketCreated, event.Type)
+	}
+	assert.Equal([]int{3, 2}, observer.batches)
+	assert.Equal(6, observer.signs)
+	assert.Equal(uint64(6), sender.DroppedEvents())
+}
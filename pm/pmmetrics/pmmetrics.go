@@ -0,0 +1,107 @@
+// Package pmmetrics exports the activity of a pm sender as Prometheus metrics. It is kept separate from
+// package pm so that pm does not depend on the Prometheus client
+package pmmetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "livepeer"
+	subsystem = "pm"
+)
+
+// Observer is a pm.SenderObserver that records a sender's activity in Prometheus metrics
+type Observer struct {
+	ticketsCreated     prometheus.Counter
+	batchesCreated     prometheus.Counter
+	validationFailures prometheus.Counter
+	signingDuration    prometheus.Histogram
+	activeSessions     prometheus.Gauge
+
+	sessions map[string]bool
+	mu       sync.Mutex
+}
+
+// NewObserver creates an Observer and registers its metrics with reg
+func NewObserver(reg prometheus.Registerer) (*Observer, error) {
+	o := &Observer{
+		ticketsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "tickets_created_total",
+			Help:      "Number of tickets created and signed",
+		}),
+		batchesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "batches_created_total",
+			Help:      "Number of ticket batches created",
+		}),
+		validationFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "validation_failures_total",
+			Help:      "Number of ticket params validation failures",
+		}),
+		signingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "signing_duration_seconds",
+			Help:      "Latency of the signer's Sign calls",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		activeSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "active_sessions",
+			Help:      "Number of active sessions",
+		}),
+		sessions: make(map[string]bool),
+	}
+
+	collectors := []prometheus.Collector{o.ticketsCreated, o.batchesCreated, o.validationFailures, o.signingDuration, o.activeSessions}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// ObserveEvent implements pm.SenderObserver
+func (o *Observer) ObserveEvent(event pm.SenderEvent) {
+	switch event.Type {
+	case pm.TicketCreated:
+		o.ticketsCreated.Inc()
+	case pm.ValidationFailed:
+		o.validationFailures.Inc()
+	case pm.SessionStarted, pm.SessionEnded:
+		// Sessions are tracked by ID because starting a session that already
+		// exists replaces the existing session
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		if event.Type == pm.SessionStarted {
+			o.sessions[event.SessionID] = true
+		} else {
+			delete(o.sessions, event.SessionID)
+		}
+		o.activeSessions.Set(float64(len(o.sessions)))
+	}
+}
+
+// ObserveBatch implements pm.SenderObserver
+func (o *Observer) ObserveBatch(sessionID string, size int) {
+	o.batchesCreated.Inc()
+}
+
+// ObserveSign implements pm.SenderObserver
+func (o *Observer) ObserveSign(latency time.Duration) {
+	o.signingDuration.Observe(latency.Seconds())
+}
@@ -0,0 +1,163 @@
+package pmmetrics
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSigner struct {
+	account accounts.Account
+}
+
+func (s *stubSigner) Sign(msg []byte) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+func (s *stubSigner) Account() accounts.Account {
+	return s.account
+}
+
+type stubTimeManager struct{}
+
+func (m *stubTimeManager) LastInitializedRound() *big.Int                      { return big.NewInt(5) }
+func (m *stubTimeManager) LastInitializedBlockHash() [32]byte                  { return [32]byte{5} }
+func (m *stubTimeManager) GetTranscoderPoolSize() *big.Int                     { return big.NewInt(1) }
+func (m *stubTimeManager) LastSeenBlock() *big.Int                             { return big.NewInt(0) }
+func (m *stubTimeManager) SubscribeRounds(chan<- types.Log) event.Subscription { return nil }
+func (m *stubTimeManager) SubscribeBlocks(chan<- *big.Int) event.Subscription  { return nil }
+
+type stubSenderManager struct {
+	info *pm.SenderInfo
+	err  error
+}
+
+func (m *stubSenderManager) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
+	return m.info, m.err
+}
+
+func (m *stubSenderManager) ClaimedReserve(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (m *stubSenderManager) Clear(addr ethcommon.Address) {}
+
+func ticketParams() pm.TicketParams {
+	return pm.TicketParams{
+		Recipient:         pm.RandAddress(),
+		FaceValue:         big.NewInt(0),
+		WinProb:           big.NewInt(0),
+		Seed:              big.NewInt(0),
+		RecipientRandHash: pm.RandHash(),
+		ExpirationBlock:   big.NewInt(100),
+		PricePerPixel:     big.NewRat(1, 1),
+	}
+}
+
+func TestObserver(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	reg := prometheus.NewRegistry()
+	observer, err := NewObserver(reg)
+	require.Nil(err)
+
+	sm := &stubSenderManager{
+		info: &pm.SenderInfo{
+			Deposit:       big.NewInt(100000),
+			Reserve:       &pm.ReserveInfo{FundsRemaining: big.NewInt(10)},
+			WithdrawRound: big.NewInt(0),
+		},
+	}
+	sender, err := pm.NewSender(&stubSigner{account: accounts.Account{Address: pm.RandAddress()}}, &stubTimeManager{}, sm, big.NewRat(100, 1), 2, pm.SenderConfig{Observer: observer})
+	require.Nil(err)
+
+	params := ticketParams()
+	sessionID := sender.StartSession(params)
+	sender.StartSession(params)
+	otherSessionID := sender.StartSession(ticketParams())
+	assert.Equal(float64(2), testutil.ToFloat64(observer.activeSessions))
+
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(otherSessionID, 2)
+	require.Nil(err)
+
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.NotNil(err)
+
+	sender.EndSession(otherSessionID)
+
+	assert.Equal(float64(5), testutil.ToFloat64(observer.ticketsCreated))
+	assert.Equal(float64(2), testutil.ToFloat64(observer.batchesCreated))
+	assert.Equal(float64(1), testutil.ToFloat64(observer.validationFailures))
+	assert.Equal(float64(1), testutil.ToFloat64(observer.activeSessions))
+
+	families, err := reg.Gather()
+	require.Nil(err)
+	metrics := make(map[string]float64)
+	for _, f := range families {
+		m := f.GetMetric()[0]
+		switch {
+		case m.Counter != nil:
+			metrics[f.GetName()] = m.Counter.GetValue()
+		case m.Gauge != nil:
+			metrics[f.GetName()] = m.Gauge.GetValue()
+		case m.Histogram != nil:
+			metrics[f.GetName()] = float64(m.Histogram.GetSampleCount())
+		}
+	}
+	assert.Equal(map[string]float64{
+		"livepeer_pm_tickets_created_total":     5,
+		"livepeer_pm_batches_created_total":     2,
+		"livepeer_pm_validation_failures_total": 1,
+		"livepeer_pm_signing_duration_seconds":  5,
+		"livepeer_pm_active_sessions":           1,
+	}, metrics)
+}
+
+func TestNewObserver_RegisterError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	_, err := NewObserver(reg)
+	require.Nil(t, err)
+
+	// Registering the same metrics twice fails
+	_, err = NewObserver(reg)
+	assert.NotNil(t, err)
+}
+
+func TestObserveSign(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	reg := prometheus.NewRegistry()
+	observer, err := NewObserver(reg)
+	require.Nil(err)
+
+	observer.ObserveSign(2 * time.Millisecond)
+	observer.ObserveSign(3 * time.Millisecond)
+
+	families, err := reg.Gather()
+	require.Nil(err)
+	for _, f := range families {
+		if f.GetName() == "livepeer_pm_signing_duration_seconds" {
+			h := f.GetMetric()[0].GetHistogram()
+			assert.Equal(uint64(2), h.GetSampleCount())
+			assert.InDelta(0.005, h.GetSampleSum(), 1e-9)
+			return
+		}
+	}
+	t.Fatal("signing duration metric not gathered")
+}
@@ -0,0 +1,144 @@
+package pm
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Policy holds the validation limits of the sender that can be reloaded from a PolicySource
+type Policy struct {
+	// MaxEV is the max total EV of the tickets validated at once. If nil, the maxEV passed to NewSender is used
+	MaxEV *big.Rat
+
+	// DepositMultiplier is the deposit multiplier used to compute the max face value of tickets.
+	// If zero, the depositMultiplier passed to NewSender is used
+	DepositMultiplier int
+
+	// MinEV is the min EV of a single ticket. If nil, ticket EVs are not checked against a min
+	MinEV *big.Rat
+}
+
+// PolicySource is an interface which describes an object capable of providing the current
+// validation limits of the sender, e.g. from a watched config file or a remote config service
+type PolicySource interface {
+	// Policy returns the current policy
+	Policy() (Policy, error)
+}
+
+// checkPolicy returns an error if a policy can not be applied. The minEV of the policy must not be greater than its
+// maxEV or the maxEV passed to NewSender if the policy has no maxEV because every ticket would be rejected
+func checkPolicy(policy Policy, senderMaxEV *big.Rat) error {
+	if policy.MaxEV != nil && policy.MaxEV.Sign() < 0 {
+		return fmt.Errorf("policy maxEV %v is negative", policy.MaxEV.FloatString(5))
+	}
+
+	if policy.DepositMultiplier < 0 {
+		return fmt.Errorf("policy depositMultiplier %v is negative", policy.DepositMultiplier)
+	}
+
+	if policy.MinEV != nil && policy.MinEV.Sign() < 0 {
+		return fmt.Errorf("policy minEV %v is negative", policy.MinEV.FloatString(5))
+	}
+
+	maxEV := policy.MaxEV
+	if maxEV == nil {
+		maxEV = senderMaxEV
+	}
+	if policy.MinEV != nil && maxEV != nil && policy.MinEV.Cmp(maxEV) > 0 {
+		return fmt.Errorf("policy minEV %v > maxEV %v", policy.MinEV.FloatString(5), maxEV.FloatString(5))
+	}
+
+	return nil
+}
+
+// reloadPolicy fetches the policy from the configured PolicySource and replaces the current policy with it.
+// The current policy is kept if the source returns an error or an invalid policy
+func (s *sender) reloadPolicy() error {
+	if s.cfg.PolicySource == nil {
+		return nil
+	}
+
+	policy, err := s.cfg.PolicySource.Policy()
+	if err != nil {
+		return errors.Wrap(err, "error fetching policy")
+	}
+
+	if err := checkPolicy(policy, s.maxEV); err != nil {
+		return err
+	}
+
+	// The rats are copied so that the source can not change the policy of the sender after it was applied
+	if policy.MaxEV != nil {
+		policy.MaxEV = new(big.Rat).Set(policy.MaxEV)
+	}
+	if policy.MinEV != nil {
+		policy.MinEV = new(big.Rat).Set(policy.MinEV)
+	}
+
+	// The whole policy is swapped at once so that validation never sees a mix of the old and the new limits
+	s.policy.Store(policy)
+
+	return nil
+}
+
+// startPolicyLoop initiates a loop that reloads the policy from the
+// configured PolicySource every PolicyPollInterval
+func (s *sender) startPolicyLoop() {
+	ticker := time.NewTicker(s.cfg.PolicyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.reloadPolicy(); err != nil {
+				glog.Errorf("error reloading policy, keeping current policy err=%v", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// currentPolicy returns the policy last loaded from the PolicySource
+func (s *sender) currentPolicy() Policy {
+	policy, _ := s.policy.Load().(Policy)
+	return policy
+}
+
+// policyMaxEV returns the maxEV of the current policy and falls back to the maxEV passed to NewSender
+func (s *sender) policyMaxEV() *big.Rat {
+	if maxEV := s.currentPolicy().MaxEV; maxEV != nil {
+		return maxEV
+	}
+
+	return s.maxEV
+}
+
+// policyDepositMultiplier returns the deposit multiplier of the current policy and falls back to the
+// depositMultiplier passed to NewSender
+func (s *sender) policyDepositMultiplier() int {
+	if depositMultiplier := s.currentPolicy().DepositMultiplier; depositMultiplier > 0 {
+		return depositMultiplier
+	}
+
+	return s.depositMultiplier
+}
+
+// validateMinEV checks the EV of a ticket against the minEV of the current policy
+func (s *sender) validateMinEV(ticketParams *TicketParams) error {
+	minEV := s.currentPolicy().MinEV
+	if minEV == nil {
+		return nil
+	}
+
+	if cmpTicketEV(ticketParams.FaceValue, ticketParams.WinProb, 1, minEV) < 0 {
+		ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+		return fmt.Errorf("ticket EV %v < min ticket EV %v", ev.FloatString(5), minEV.FloatString(5))
+	}
+
+	return nil
+}
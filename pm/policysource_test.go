@@ -0,0 +1,190 @@
+package pm
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubPolicySource struct {
+	mu     sync.Mutex
+	policy Policy
+	err    error
+	calls  int
+}
+
+func (s *stubPolicySource) Policy() (Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	return s.policy, s.err
+}
+
+func (s *stubPolicySource) set(policy Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = policy
+}
+
+func (s *stubPolicySource) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+func TestPolicySource_ReloadedPolicyChangesValidation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	source := &stubPolicySource{}
+	sender.cfg.PolicySource = source
+
+	// EV = 101
+	ticketParams := &TicketParams{
+		FaceValue: big.NewInt(202),
+		WinProb:   new(big.Int).Div(maxWinProb, big.NewInt(2)),
+	}
+	ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+
+	// The maxEV passed to NewSender is used while the source has no maxEV
+	require.Nil(sender.reloadPolicy())
+	assert.EqualError(sender.ValidateTicketParams(ticketParams), maxEVErrStr(ev, 1, sender.maxEV))
+
+	source.set(Policy{MaxEV: big.NewRat(200, 1)})
+	require.Nil(sender.reloadPolicy())
+	assert.Nil(sender.ValidateTicketParams(ticketParams))
+
+	source.set(Policy{MaxEV: big.NewRat(50, 1)})
+	require.Nil(sender.reloadPolicy())
+	assert.EqualError(sender.ValidateTicketParams(ticketParams), maxEVErrStr(ev, 1, big.NewRat(50, 1)))
+
+	// The deposit multiplier of the source is used for the max face value
+	source.set(Policy{MaxEV: big.NewRat(200, 1), DepositMultiplier: 1000})
+	require.Nil(sender.reloadPolicy())
+	assert.EqualError(sender.ValidateTicketParams(ticketParams), maxFaceValueErrStr(ticketParams.FaceValue, big.NewInt(100)))
+
+	// Tickets with an EV below the min of the source are rejected
+	source.set(Policy{MaxEV: big.NewRat(200, 1), MinEV: big.NewRat(150, 1)})
+	require.Nil(sender.reloadPolicy())
+	assert.EqualError(sender.ValidateTicketParams(ticketParams), "ticket EV 101.00000 < min ticket EV 150.00000")
+}
+
+func TestPolicySource_InvalidPolicyIsNotApplied(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	source := &stubPolicySource{policy: Policy{MaxEV: big.NewRat(200, 1)}}
+	sender.cfg.PolicySource = source
+	require.Nil(sender.reloadPolicy())
+
+	source.err = errors.New("source unavailable")
+	assert.EqualError(sender.reloadPolicy(), "error fetching policy: source unavailable")
+	assert.Equal(big.NewRat(200, 1), sender.policyMaxEV())
+
+	source.err = nil
+	source.set(Policy{DepositMultiplier: -1})
+	assert.EqualError(sender.reloadPolicy(), "policy depositMultiplier -1 is negative")
+	assert.Equal(big.NewRat(200, 1), sender.policyMaxEV())
+
+	// Changing the rats of the source does not change the applied policy
+	maxEV := big.NewRat(300, 1)
+	source.set(Policy{MaxEV: maxEV})
+	require.Nil(sender.reloadPolicy())
+	maxEV.SetInt64(1)
+	assert.Equal(big.NewRat(300, 1), sender.policyMaxEV())
+}
+
+func TestPolicySource_Poll(t *testing.T) {
+	assert := assert.New(t)
+
+	source := &stubPolicySource{policy: Policy{MaxEV: big.NewRat(200, 1)}}
+	s, err := NewSender(&stubSigner{}, &stubTimeManager{}, newStubSenderManager(), big.NewRat(100, 1), 2, SenderConfig{
+		PolicySource:       source,
+		PolicyPollInterval: time.Millisecond,
+	})
+	require.Nil(t, err)
+	sender := s.(*sender)
+
+	// The policy is loaded by NewSender
+	assert.Equal(big.NewRat(200, 1), sender.policyMaxEV())
+	assert.Equal(2, sender.policyDepositMultiplier())
+
+	sender.Start()
+	defer sender.Stop()
+
+	source.set(Policy{MaxEV: big.NewRat(50, 1), DepositMultiplier: 3})
+	assert.Eventually(func() bool {
+		return sender.policyMaxEV().Cmp(big.NewRat(50, 1)) == 0 && sender.policyDepositMultiplier() == 3
+	}, time.Second, time.Millisecond)
+	assert.Greater(source.callCount(), 1)
+}
+
+func TestPolicySource_NoPollInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	source := &stubPolicySource{policy: Policy{MaxEV: big.NewRat(200, 1)}}
+	s, err := NewSender(&stubSigner{}, &stubTimeManager{}, newStubSenderManager(), big.NewRat(100, 1), 2, SenderConfig{
+		PolicySource: source,
+	})
+	require.Nil(t, err)
+	sender := s.(*sender)
+	sender.Start()
+	defer sender.Stop()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(1, source.callCount())
+}
+
+func TestNewSender_InvalidBounds(t *testing.T) {
+	assert := assert.New(t)
+
+	newSender := func(maxEV *big.Rat, policy Policy) error {
+		_, err := NewSender(&stubSigner{}, &stubTimeManager{}, newStubSenderManager(), maxEV, 2, SenderConfig{
+			PolicySource: &stubPolicySource{policy: policy},
+		})
+		return err
+	}
+
+	assert.EqualError(newSender(nil, Policy{}), "missing maxEV")
+	assert.EqualError(newSender(big.NewRat(-1, 1), Policy{}), "maxEV -1.00000 is negative")
+	assert.EqualError(newSender(big.NewRat(100, 1), Policy{MinEV: big.NewRat(-1, 1)}), "policy minEV -1.00000 is negative")
+	assert.EqualError(newSender(big.NewRat(100, 1), Policy{MaxEV: big.NewRat(-1, 1)}), "policy maxEV -1.00000 is negative")
+
+	// A minEV greater than the maxEV of the policy or of the sender rejects every ticket
+	assert.EqualError(newSender(big.NewRat(100, 1), Policy{MinEV: big.NewRat(101, 1)}), "policy minEV 101.00000 > maxEV 100.00000")
+	assert.EqualError(newSender(big.NewRat(100, 1), Policy{MinEV: big.NewRat(60, 1), MaxEV: big.NewRat(50, 1)}), "policy minEV 60.00000 > maxEV 50.00000")
+
+	assert.Nil(newSender(big.NewRat(100, 1), Policy{MinEV: big.NewRat(100, 1)}))
+	assert.Nil(newSender(big.NewRat(100, 1), Policy{MinEV: big.NewRat(150, 1), MaxEV: big.NewRat(200, 1)}))
+	assert.Nil(newSender(big.NewRat(0, 1), Policy{}))
+
+	_, err := NewSender(&stubSigner{}, &stubTimeManager{}, newStubSenderManager(), big.NewRat(100, 1), 2, SenderConfig{
+		PolicySource: &stubPolicySource{err: errors.New("source unavailable")},
+	})
+	assert.EqualError(err, "error fetching policy: source unavailable")
+}
+
+func TestReloadPolicy_InvertedBoundsAreNotApplied(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	source := &stubPolicySource{policy: Policy{MinEV: big.NewRat(10, 1)}}
+	sender.cfg.PolicySource = source
+	require.Nil(sender.reloadPolicy())
+
+	source.set(Policy{MinEV: big.NewRat(10, 1), MaxEV: big.NewRat(5, 1)})
+	assert.EqualError(sender.reloadPolicy(), "policy minEV 10.00000 > maxEV 5.00000")
+	assert.Equal(sender.maxEV, sender.policyMaxEV())
+	assert.Equal(big.NewRat(10, 1), sender.currentPolicy().MinEV)
+}
@@ -0,0 +1,81 @@
+package pm
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// CanIssueBatch checks whether a batch of size tickets can currently be created for a session without
+// creating it. It checks that the sender's deposit and reserve back the tickets, that the ticket face value
+// and total EV are acceptable, that the session's ticket params have not expired, that the last initialized
+// round has not regressed, that the session's round quota is not exhausted, that the tickets do not exceed the
+// MaxRecipientEV of the session's recipient and that the round of the session's
+// expiration params is not too far behind the chain head round. The deposit is checked with the
+// same validation as ticket creation, so a pending withdrawal reduces or rejects the deposit according to the
+// sender's WithdrawalAction. The sender info is fetched
+// once and no deposit, quota, recipient EV or nonces are reserved. If a batch cannot be created, the reason describes the
+// first check that failed. An error is returned if the session is unknown or the sender info cannot be fetched
+func (s *sender) CanIssueBatch(sessionID string, size int) (bool, string, error) {
+	if size < 1 {
+		return false, "", ErrEmptyBatch
+	}
+
+	session, err := s.loadIssuableSession(sessionID)
+	if err != nil {
+		return false, "", err
+	}
+
+	if atomic.LoadUint32(&session.trusted) == 0 {
+		info, err := s.sessionSenderInfo(session)
+		if err != nil {
+			return false, "", SenderInfoError{err}
+		}
+
+		if err := s.validateTicketParamsWithInfo(&session.ticketParams, size, s.sessionDepositMultiplier(session), info); err != nil {
+			return false, err.Error(), nil
+		}
+	}
+
+	round := s.timeManager.LastInitializedRound().Int64()
+	if highest := atomic.LoadInt64(&s.highestRound); round < highest && !s.cfg.AllowRoundRegression {
+		return false, errors.Wrapf(ErrRoundRegression, "round %v < highest seen round %v", round, highest).Error(), nil
+	}
+
+	if s.roundQuotaExhausted(session, size, round) {
+		return false, ErrRoundQuotaExhausted.Error(), nil
+	}
+
+	if err := s.checkRecipientEV(session, size); err != nil {
+		return false, err.Error(), nil
+	}
+
+	if _, err := s.issuableExpirationParams(session); err != nil {
+		return false, err.Error(), nil
+	}
+
+	return true, "", nil
+}
+
+// roundQuotaExhausted returns true if creating numTickets tickets for a session in round
+// would exceed the session's ticket quota for the round
+func (s *sender) roundQuotaExhausted(session *session, numTickets int, round int64) bool {
+	maxTickets := s.cfg.MaxTicketsPerRound
+	if session.policy.MaxTicketsPerRound > 0 {
+		maxTickets = session.policy.MaxTicketsPerRound
+	}
+
+	if maxTickets <= 0 {
+		return false
+	}
+
+	session.quotaMu.Lock()
+	defer session.quotaMu.Unlock()
+
+	used := session.quotaTickets
+	if session.quotaRound != round {
+		used = 0
+	}
+
+	return used+numTickets > maxTickets
+}
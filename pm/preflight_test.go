@@ -0,0 +1,223 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSenderManager counts GetSenderInfo calls
+type countingSenderManager struct {
+	*stubSenderManager
+	calls int
+}
+
+func (m *countingSenderManager) GetSenderInfo(addr ethcommon.Address) (*SenderInfo, error) {
+	m.calls++
+	return m.stubSenderManager.GetSenderInfo(addr)
+}
+
+func TestCanIssueBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(s *sender, params *TicketParams)
+		reason string
+	}{
+		{
+			name:  "issuable",
+			setup: func(s *sender, params *TicketParams) {},
+		},
+		{
+			name: "no reserve",
+			setup: func(s *sender, params *TicketParams) {
+				s.senderManager.(*countingSenderManager).info[s.signer.Account().Address].Reserve.FundsRemaining = big.NewInt(0)
+			},
+			reason: "no sender reserve",
+		},
+		{
+			name: "no deposit",
+			setup: func(s *sender, params *TicketParams) {
+				s.senderManager.(*countingSenderManager).info[s.signer.Account().Address].Deposit = big.NewInt(0)
+			},
+			reason: "no sender deposit",
+		},
+		{
+			name: "face value not backed by deposit",
+			setup: func(s *sender, params *TicketParams) {
+				params.FaceValue = big.NewInt(60000)
+			},
+			reason: maxFaceValueErrStr(big.NewInt(60000), big.NewInt(50000)),
+		},
+		{
+			name: "EV too high",
+			setup: func(s *sender, params *TicketParams) {
+				params.FaceValue = big.NewInt(40)
+				params.WinProb = new(big.Int).Set(maxWinProb)
+			},
+			reason: maxEVErrStr(big.NewRat(120, 1), 3, big.NewRat(100, 1)),
+		},
+		{
+			name: "ticket params expired",
+			setup: func(s *sender, params *TicketParams) {
+				s.timeManager.(*stubTimeManager).lastSeenBlock = big.NewInt(100)
+			},
+			reason: ErrTicketParamsExpired.Error(),
+		},
+		{
+			name: "round regressed",
+			setup: func(s *sender, params *TicketParams) {
+				s.highestRound = 10
+			},
+			reason: "round 5 < highest seen round 10: " + ErrRoundRegression.Error(),
+		},
+		{
+			name: "round quota exhausted",
+			setup: func(s *sender, params *TicketParams) {
+				s.cfg.MaxTicketsPerRound = 2
+			},
+			reason: ErrRoundQuotaExhausted.Error(),
+		},
+		{
+			name: "recipient EV limit",
+			setup: func(s *sender, params *TicketParams) {
+				// Tickets with an EV of 1 each
+				params.Recipient = ethcommon.HexToAddress("0x01")
+				params.FaceValue = big.NewInt(100)
+				params.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(100))
+				s.cfg.MaxRecipientEV = big.NewRat(2, 1)
+			},
+			reason: "recipient 0x0000000000000000000000000000000000000001 committed EV 0.00000 + EV 3.00000 for 3 tickets > max recipient EV 2.00000: " + ErrRecipientEVLimit.Error(),
+		},
+		{
+			name: "stale round",
+			setup: func(s *sender, params *TicketParams) {
+				s.cfg.MaxRoundLag = 2
+				s.cfg.ChainHeadRound = func() *big.Int { return big.NewInt(8) }
+			},
+			reason: "creation round 5 is 3 rounds behind chain head round 8: " + ErrStaleRound.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sender := defaultSender(t)
+			sm := &countingSenderManager{stubSenderManager: sender.senderManager.(*stubSenderManager)}
+			sender.senderManager = sm
+			params := defaultTicketParams(t, RandAddress())
+			tt.setup(sender, &params)
+			sessionID := sender.StartSession(params)
+
+			ok, reason, err := sender.CanIssueBatch(sessionID, 3)
+			require.Nil(err)
+			assert.Equal(tt.reason == "", ok)
+			assert.Equal(tt.reason, reason)
+			assert.Equal(1, sm.calls)
+
+			// The result agrees with the outcome of creating the batch
+			_, err = sender.CreateTicketBatch(sessionID, 3)
+			if ok {
+				assert.Nil(err)
+			} else {
+				require.NotNil(err)
+				assert.Equal(reason, err.Error())
+			}
+		})
+	}
+}
+
+func TestCanIssueBatch_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, _, err := sender.CanIssueBatch(sessionID, 0)
+	assert.Equal(ErrEmptyBatch, err)
+
+	_, _, err = sender.CanIssueBatch("foo", 1)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.err = errors.New("GetSenderInfo error")
+	ok, reason, err := sender.CanIssueBatch(sessionID, 1)
+	assert.False(ok)
+	assert.Empty(reason)
+	_, isSenderInfoErr := err.(SenderInfoError)
+	assert.True(isSenderInfoErr)
+
+	// Trusted sessions do not fetch the sender info
+	assert.Nil(sender.MarkTrusted(sessionID))
+	ok, reason, err = sender.CanIssueBatch(sessionID, 1)
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Empty(reason)
+}
+
+func TestCanIssueBatch_DoesNotReserve(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxTicketsPerRound = 2
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := sender.CanIssueBatch(sessionID, 2)
+		require.Nil(err)
+		assert.True(ok)
+	}
+	assert.Equal(uint32(0), sender.ListSessions()[0].SenderNonce)
+
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	ok, reason, err := sender.CanIssueBatch(sessionID, 1)
+	require.Nil(err)
+	assert.False(ok)
+	assert.Equal(ErrRoundQuotaExhausted.Error(), reason)
+}
+
+func TestCanIssueBatch_PendingWithdrawal_MatchesCreateTicketBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    WithdrawalAction
+		faceValue int64
+		ok        bool
+	}{
+		{name: "ignored withdrawal", action: WithdrawalIgnore, faceValue: 40000, ok: true},
+		{name: "subtracted withdrawal backs face value", action: WithdrawalSubtract, faceValue: 20000, ok: true},
+		{name: "subtracted withdrawal does not back face value", action: WithdrawalSubtract, faceValue: 40000},
+		{name: "rejected withdrawal", action: WithdrawalReject, faceValue: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sender := defaultSender(t)
+			sender.cfg.WithdrawalAction = tt.action
+			sm := sender.senderManager.(*stubSenderManager)
+			sm.info[sender.signer.Account().Address].PendingWithdrawal = big.NewInt(50000)
+
+			ticketParams := defaultTicketParams(t, RandAddress())
+			ticketParams.FaceValue = big.NewInt(tt.faceValue)
+			sessionID := sender.StartSession(ticketParams)
+
+			ok, reason, err := sender.CanIssueBatch(sessionID, 1)
+			assert.Nil(err)
+			assert.Equal(tt.ok, ok, reason)
+
+			_, err = sender.CreateTicketBatch(sessionID, 1)
+			assert.Equal(tt.ok, err == nil, "%v", err)
+			if err != nil {
+				assert.Contains(err.Error(), reason)
+			}
+		})
+	}
+}
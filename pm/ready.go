@@ -0,0 +1,54 @@
+package pm
+
+import (
+	"math/big"
+	"sync/atomic"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// ErrRoundNotInitialized is returned when the TimeManager does not know the last initialized round
+var ErrRoundNotInitialized = errors.New("last initialized round is not known")
+
+// Ready checks that tickets can be created for a session and returns the first failed check. Unless the
+// session is trusted it checks that the session's ticket params are acceptable and backed by the sender's
+// deposit and reserve. It then checks that the last initialized round is known and has not regressed and
+// that the signer is responsive by signing a ticket that is never used as a payment. Ready does not
+// allocate nonces or reserve the deposit
+func (s *sender) Ready(sessionID string) error {
+	session, err := s.loadIssuableSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if atomic.LoadUint32(&session.trusted) == 0 {
+		info, err := s.sessionSenderInfo(session)
+		if err != nil {
+			return SenderInfoError{err}
+		}
+
+		if err := s.validateTicketParamsWithInfo(&session.ticketParams, 1, s.sessionDepositMultiplier(session), info); err != nil {
+			return ValidationError{err}
+		}
+	}
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return err
+	}
+
+	if expirationParams.CreationRound == 0 || expirationParams.CreationRoundBlockHash == (ethcommon.Hash{}) {
+		return RoundError{ErrRoundNotInitialized}
+	}
+
+	// Sign a ticket with nonce 0 and a face value of 0 like a peek ticket so the signature is not a valid payment
+	ticket := NewTicket(&session.ticketParams, expirationParams, s.sessionSigner(session).Account().Address, 0)
+	ticket.FaceValue = big.NewInt(0)
+
+	if _, err := s.sign(s.sessionSigner(session), s.ticketHash(ticket).Bytes()); err != nil {
+		return SignerError{errors.Wrapf(err, "error signing dry run ticket for session: %v", sessionID)}
+	}
+
+	return nil
+}
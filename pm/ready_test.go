@@ -0,0 +1,150 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	am := sender.signer.(*stubSigner)
+	am.saveSignRequest = true
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	require.Nil(sender.Ready(sessionID))
+	// The signer is dry run without allocating a nonce
+	assert.Len(am.signRequests, 1)
+	assert.Equal(uint32(0), sender.ListSessions()[0].SenderNonce)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+}
+
+func TestReady_Failures(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *sender)
+		check func(t *testing.T, err error)
+	}{
+		{
+			name: "unknown session",
+			setup: func(s *sender) {
+				s.EndSession(s.ListSessions()[0].SessionID)
+			},
+			check: func(t *testing.T, err error) {
+				assert.Equal(t, ErrUnknownSession, errors.Cause(err))
+			},
+		},
+		{
+			name: "sender info error",
+			setup: func(s *sender) {
+				s.senderManager.(*stubSenderManager).err = errors.New("GetSenderInfo error")
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, SenderInfoError{}, err)
+				assert.EqualError(t, err, "GetSenderInfo error")
+			},
+		},
+		{
+			name: "insufficient deposit",
+			setup: func(s *sender) {
+				s.senderManager.(*stubSenderManager).info[s.signer.Account().Address].Deposit = big.NewInt(0)
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, ValidationError{}, err)
+				assert.EqualError(t, err, "no sender deposit")
+			},
+		},
+		{
+			name: "ticket params expired",
+			setup: func(s *sender) {
+				s.timeManager.(*stubTimeManager).lastSeenBlock = big.NewInt(100)
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, ValidationError{}, err)
+				assert.Equal(t, ErrTicketParamsExpired, errors.Cause(err))
+			},
+		},
+		{
+			name: "round not initialized",
+			setup: func(s *sender) {
+				s.timeManager.(*stubTimeManager).round = big.NewInt(0)
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, RoundError{}, err)
+				assert.Equal(t, ErrRoundNotInitialized, errors.Cause(err))
+			},
+		},
+		{
+			name: "round block hash unknown",
+			setup: func(s *sender) {
+				s.timeManager.(*stubTimeManager).blkHash = [32]byte{}
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, RoundError{}, err)
+				assert.Equal(t, ErrRoundNotInitialized, errors.Cause(err))
+			},
+		},
+		{
+			name: "round regressed",
+			setup: func(s *sender) {
+				s.highestRound = 10
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, RoundError{}, err)
+				assert.Equal(t, ErrRoundRegression, errors.Cause(err))
+			},
+		},
+		{
+			name: "signer error",
+			setup: func(s *sender) {
+				s.signer.(*stubSigner).signShouldFail = true
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, SignerError{}, err)
+				assert.Contains(t, err.Error(), "error signing dry run ticket")
+			},
+		},
+		{
+			name: "signer panic",
+			setup: func(s *sender) {
+				s.signer = &panickingSigner{stubSigner: *s.signer.(*stubSigner)}
+			},
+			check: func(t *testing.T, err error) {
+				assert.IsType(t, SignerError{}, err)
+				assert.Equal(t, ErrSignerPanic, errors.Cause(err))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := defaultSender(t)
+			sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+			tt.setup(sender)
+
+			err := sender.Ready(sessionID)
+			require.NotNil(t, err)
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestReady_TrustedSession(t *testing.T) {
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	require.Nil(sender.MarkTrusted(sessionID))
+	sender.senderManager.(*stubSenderManager).err = errors.New("GetSenderInfo error")
+
+	require.Nil(sender.Ready(sessionID))
+}
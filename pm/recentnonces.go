@@ -0,0 +1,180 @@
+package pm
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDuplicateNonce is returned when creating tickets would reuse a nonce that was recently
+// issued for the session
+var ErrDuplicateNonce = errors.New("nonce was already issued for session")
+
+// recentNonces is a bounded set of the nonces most recently issued for a session.
+// If the set is charged against a trackingBudget the oldest nonces can also be evicted
+// to keep the sender within its budget
+type recentNonces struct {
+	// ring holds the nonces in the set in the order they were added starting at start. It grows up to window
+	ring []uint32
+	// seqs holds the budget sequence numbers of the nonces if the set is charged against a budget
+	seqs   []uint64
+	start  int
+	count  int
+	window int
+
+	// counts is the number of times each nonce occurs in ring
+	counts map[uint32]int
+
+	budget *trackingBudget
+
+	mu sync.Mutex
+}
+
+// newRecentNonces returns a set that keeps the last window issued nonces or nil if window is not positive
+func newRecentNonces(window int, budget *trackingBudget) *recentNonces {
+	if window <= 0 {
+		return nil
+	}
+
+	r := &recentNonces{
+		window: window,
+		counts: make(map[uint32]int),
+	}
+	if budget != nil {
+		r.budget = budget
+		budget.register(r)
+	}
+
+	return r
+}
+
+// check returns ErrDuplicateNonce if any of the numTickets nonces starting at firstNonce is in the set
+func (r *recentNonces) check(firstNonce uint32, numTickets int) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := 0; i < numTickets; i++ {
+		nonce := firstNonce + uint32(i)
+		if r.counts[nonce] > 0 {
+			return errors.Wrapf(ErrDuplicateNonce, "nonce %v", nonce)
+		}
+	}
+
+	return nil
+}
+
+// add adds numTickets nonces starting at firstNonce to the set evicting the oldest nonces if the set is full
+func (r *recentNonces) add(firstNonce uint32, numTickets int) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+
+	for i := 0; i < numTickets && r.window > 0; i++ {
+		if r.count == r.window {
+			r.dropOldest()
+			if r.budget != nil {
+				r.budget.charge(-recentNonceBytes)
+			}
+		}
+
+		if r.count == len(r.ring) {
+			r.grow()
+		}
+
+		nonce := firstNonce + uint32(i)
+		j := (r.start + r.count) % len(r.ring)
+		r.ring[j] = nonce
+		r.counts[nonce]++
+		if r.budget != nil {
+			r.seqs[j] = r.budget.nextSeq()
+			r.budget.charge(recentNonceBytes)
+		}
+		r.count++
+	}
+
+	r.mu.Unlock()
+
+	r.budget.enforce()
+}
+
+// grow extends the ring in order so that the next nonce can be written after the last nonce. The caller must hold mu
+func (r *recentNonces) grow() {
+	size := 2 * len(r.ring)
+	if size == 0 {
+		size = 1
+	}
+	if size > r.window {
+		size = r.window
+	}
+
+	ring := make([]uint32, size)
+	var seqs []uint64
+	if r.budget != nil {
+		seqs = make([]uint64, size)
+	}
+
+	for i := 0; i < r.count; i++ {
+		ring[i] = r.ring[(r.start+i)%len(r.ring)]
+		if seqs != nil {
+			seqs[i] = r.seqs[(r.start+i)%len(r.seqs)]
+		}
+	}
+
+	r.ring, r.seqs, r.start = ring, seqs, 0
+}
+
+// dropOldest removes the oldest nonce from the set. The caller must hold mu
+func (r *recentNonces) dropOldest() {
+	evicted := r.ring[r.start]
+	if r.counts[evicted]--; r.counts[evicted] <= 0 {
+		delete(r.counts, evicted)
+	}
+
+	r.start = (r.start + 1) % len(r.ring)
+	r.count--
+}
+
+func (r *recentNonces) oldestSeq() (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.count == 0 {
+		return 0, false
+	}
+
+	return r.seqs[r.start], true
+}
+
+func (r *recentNonces) evict(before uint64, bytes int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var freed int64
+	for r.count > 0 && (freed == 0 || (freed < bytes && r.seqs[r.start] < before)) {
+		r.dropOldest()
+		freed += recentNonceBytes
+	}
+
+	return freed
+}
+
+// release removes the nonces of the set from its budget when the session of the set is removed
+func (r *recentNonces) release() {
+	if r == nil || r.budget == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.budget.charge(-int64(r.count) * recentNonceBytes)
+	r.ring, r.seqs, r.start, r.count, r.window = nil, nil, 0, 0, 0
+	r.counts = make(map[uint32]int)
+	r.mu.Unlock()
+
+	r.budget.unregister(r)
+}
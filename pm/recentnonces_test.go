@@ -0,0 +1,83 @@
+package pm
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentNonces(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newRecentNonces(0, nil))
+	var disabled *recentNonces
+	disabled.add(1, 1)
+	assert.Nil(disabled.check(1, 1))
+
+	r := newRecentNonces(4, nil)
+	assert.Nil(r.check(1, 10))
+
+	r.add(1, 3)
+	assert.Nil(r.check(4, 2))
+	err := r.check(0, 2)
+	assert.Equal(ErrDuplicateNonce, errors.Cause(err))
+	assert.Contains(err.Error(), "nonce 1")
+	assert.NotNil(r.check(3, 1))
+
+	// The oldest nonces are evicted when the window is full
+	r.add(4, 2)
+	assert.Nil(r.check(1, 1))
+	assert.NotNil(r.check(2, 1))
+	assert.NotNil(r.check(5, 1))
+
+	// Batches larger than the window keep the newest nonces
+	r.add(6, 10)
+	assert.Nil(r.check(1, 11))
+	assert.NotNil(r.check(12, 1))
+	assert.NotNil(r.check(15, 1))
+	assert.Len(r.counts, 4)
+}
+
+func TestCreateTicketBatch_RecentNonceWindow_DuplicateNonce(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		sender := defaultSender(t)
+		sender.cfg.RecentNonceWindow = 10
+		sender.cfg.StrictSequential = strict
+		sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+		_, err := sender.CreateTicketBatch(sessionID, 3)
+		require.Nil(err)
+
+		// Simulate a bug that moves the session's nonce backwards
+		session, err := sender.loadSession(sessionID)
+		require.Nil(err)
+		atomic.StoreUint32(&session.senderNonce, 0)
+
+		if !strict {
+			require.Nil(sender.AdvanceNonce(sessionID, 1))
+		}
+		_, err = sender.CreateTicketBatch(sessionID, 1)
+		assert.Equal(ErrDuplicateNonce, errors.Cause(err))
+		_, _, err = sender.CreateTicketAtRound(sessionID, 5, [32]byte{5})
+		assert.Equal(ErrDuplicateNonce, errors.Cause(err))
+
+		// Nonces are not consumed by the failed creation
+		if strict {
+			assert.Equal(uint32(0), sender.ListSessions()[0].SenderNonce)
+			atomic.StoreUint32(&session.senderNonce, 3)
+		} else {
+			assert.Equal(uint32(1), sender.ListSessions()[0].SenderNonce)
+			require.Nil(sender.AdvanceNonce(sessionID, 3))
+		}
+
+		batch, err := sender.CreateTicketBatch(sessionID, 2)
+		require.Nil(err)
+		assert.Equal(uint32(4), batch.SenderParams[0].SenderNonce)
+	}
+}
@@ -0,0 +1,147 @@
+package pm
+
+import (
+	"math/big"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// ErrRecipientEVLimit is returned when creating tickets would exceed the MaxRecipientEV of their recipient
+var ErrRecipientEVLimit = errors.New("recipient EV limit reached")
+
+// recipientEVs is the cumulative EV of the tickets created for each recipient across all sessions.
+// The EVs are stored scaled by maxWinProb, i.e. as the sum of faceValue * winProb of the tickets,
+// so that they can be added and compared without normalizing fractions
+type recipientEVs struct {
+	mu        sync.Mutex
+	committed map[ethcommon.Address]*big.Int
+}
+
+// reserveRecipientEV adds the EV of numTickets tickets of a session to the committed EV of the session's recipient
+// and returns ErrRecipientEVLimit without adding it if the committed EV would exceed MaxRecipientEV.
+// The EV is removed from the committed EV by release unless keep is called first
+func (s *sender) reserveRecipientEV(session *session, numTickets int) (*recipientEVReservation, error) {
+	params := &session.ticketParams
+	ev := scaledTicketsEV(params, numTickets)
+
+	r := &s.recipientEVs
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.committed == nil {
+		r.committed = make(map[ethcommon.Address]*big.Int)
+	}
+
+	committed, ok := r.committed[params.Recipient]
+	if !ok {
+		committed = new(big.Int)
+		r.committed[params.Recipient] = committed
+	}
+
+	if err := s.checkRecipientEVLimit(params.Recipient, committed, ev, numTickets); err != nil {
+		return nil, err
+	}
+
+	committed.Add(committed, ev)
+
+	return &recipientEVReservation{evs: r, recipient: params.Recipient, ev: ev}, nil
+}
+
+// checkRecipientEV returns ErrRecipientEVLimit if creating numTickets tickets for a session would exceed the
+// MaxRecipientEV of the session's recipient. No EV is reserved
+func (s *sender) checkRecipientEV(session *session, numTickets int) error {
+	if s.cfg.MaxRecipientEV == nil {
+		return nil
+	}
+
+	params := &session.ticketParams
+	ev := scaledTicketsEV(params, numTickets)
+
+	r := &s.recipientEVs
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	committed, ok := r.committed[params.Recipient]
+	if !ok {
+		committed = new(big.Int)
+	}
+
+	return s.checkRecipientEVLimit(params.Recipient, committed, ev, numTickets)
+}
+
+// checkRecipientEVLimit returns ErrRecipientEVLimit if adding the scaled EV of numTickets tickets to the scaled EV
+// committed to a recipient would exceed MaxRecipientEV. The caller must hold the lock of the recipient EVs
+func (s *sender) checkRecipientEVLimit(recipient ethcommon.Address, committed *big.Int, ev *big.Int, numTickets int) error {
+	maxEV := s.cfg.MaxRecipientEV
+	if maxEV == nil {
+		return nil
+	}
+
+	sc := evScratchPool.Get().(*evScratch)
+	exceeded := sc.cmpScaledEV(sc.total.Add(committed, ev), maxEV) > 0
+	evScratchPool.Put(sc)
+
+	if exceeded {
+		return errors.Wrapf(ErrRecipientEVLimit, "recipient %v committed EV %v + EV %v for %v tickets > max recipient EV %v",
+			recipient.Hex(), scaledEV(committed).FloatString(5), scaledEV(ev).FloatString(5), numTickets, maxEV.FloatString(5))
+	}
+
+	return nil
+}
+
+// scaledTicketsEV returns the EV of numTickets tickets with ticket params scaled by maxWinProb
+func scaledTicketsEV(params *TicketParams, numTickets int) *big.Int {
+	ev := new(big.Int).Mul(params.FaceValue, params.WinProb)
+	return ev.Mul(ev, big.NewInt(int64(numTickets)))
+}
+
+// recipientEVReservation is the EV of tickets added to the committed EV of their recipient
+type recipientEVReservation struct {
+	evs       *recipientEVs
+	recipient ethcommon.Address
+	ev        *big.Int
+	kept      bool
+}
+
+// keep keeps the reserved EV in the committed EV of the recipient
+func (r *recipientEVReservation) keep() {
+	if r != nil {
+		r.kept = true
+	}
+}
+
+// release removes the reserved EV from the committed EV of the recipient unless keep was called
+func (r *recipientEVReservation) release() {
+	if r == nil || r.kept {
+		return
+	}
+
+	r.evs.mu.Lock()
+	defer r.evs.mu.Unlock()
+
+	committed := r.evs.committed[r.recipient]
+	committed.Sub(committed, r.ev)
+	r.kept = true
+}
+
+// RecipientCommittedEV returns the total EV of the tickets created for a recipient across all sessions
+// including sessions that have ended
+func (s *sender) RecipientCommittedEV(recipient ethcommon.Address) *big.Rat {
+	r := &s.recipientEVs
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	committed, ok := r.committed[recipient]
+	if !ok {
+		return new(big.Rat)
+	}
+
+	return scaledEV(committed)
+}
+
+// scaledEV returns the EV of a value scaled by maxWinProb
+func scaledEV(ev *big.Int) *big.Rat {
+	return new(big.Rat).SetFrac(new(big.Int).Set(ev), maxWinProb)
+}
@@ -0,0 +1,73 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipientEVLimit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxRecipientEV = big.NewRat(10, 1)
+
+	// Tickets with an EV of 1
+	recipient := RandAddress()
+	ticketParams := defaultTicketParams(t, recipient)
+	ticketParams.FaceValue = big.NewInt(100)
+	ticketParams.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(100))
+	ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+
+	otherParams := ticketParams
+	otherParams.RecipientRandHash = RandHash()
+
+	sessionID0 := sender.StartSession(ticketParams)
+	sessionID1 := sender.StartSession(otherParams)
+	require.NotEqual(sessionID0, sessionID1)
+
+	_, err := sender.CreateTicketBatch(sessionID0, 4)
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(sessionID1, 5)
+	require.Nil(err)
+	assert.Equal(new(big.Rat).Mul(ev, big.NewRat(9, 1)), sender.RecipientCommittedEV(recipient))
+
+	// The combined EV of the sessions would exceed the cap
+	_, err = sender.CreateTicketBatch(sessionID0, 2)
+	assert.Equal(ErrRecipientEVLimit, errors.Cause(err))
+	assert.Equal(uint32(4), sender.SnapshotNonces()[sessionID0])
+
+	// The failed batch is not committed and the remaining EV can still be used
+	_, err = sender.CreateTicketBatch(sessionID1, 1)
+	require.Nil(err)
+	assert.Equal(new(big.Rat).Mul(ev, big.NewRat(10, 1)), sender.RecipientCommittedEV(recipient))
+
+	_, _, err = sender.CreateTicketAtRound(sessionID0, 5, [32]byte{})
+	assert.Equal(ErrRecipientEVLimit, errors.Cause(err))
+
+	// Other recipients have their own cap
+	sessionID2 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err = sender.CreateTicketBatch(sessionID2, 1)
+	require.Nil(err)
+
+	// The committed EV is kept after sessions end
+	sender.EndSession(sessionID0)
+	assert.Equal(new(big.Rat).Mul(ev, big.NewRat(10, 1)), sender.RecipientCommittedEV(recipient))
+	assert.Zero(sender.RecipientCommittedEV(RandAddress()).Sign())
+
+	// Failed batches give back their reserved EV
+	sender.cfg.MaxRecipientEV = nil
+	sender.signer.(*stubSigner).signShouldFail = true
+	_, err = sender.CreateTicketBatch(sessionID1, 3)
+	assert.NotNil(err)
+	assert.Equal(new(big.Rat).Mul(ev, big.NewRat(10, 1)), sender.RecipientCommittedEV(recipient))
+}
+
+func TestNewSender_NegativeMaxRecipientEV(t *testing.T) {
+	_, err := NewSender(&stubSigner{}, &stubTimeManager{}, newStubSenderManager(), big.NewRat(100, 1), 2, SenderConfig{MaxRecipientEV: big.NewRat(-1, 1)})
+	assert.EqualError(t, err, "max recipient EV -1.00000 is negative")
+}
@@ -0,0 +1,126 @@
+package pm
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// ReconcileAction is the action taken by the sender's reconciler for a session
+// that can no longer be backed by the sender's deposit and reserve
+type ReconcileAction int
+
+const (
+	// ReconcileEmitEvent emits a SessionExhausted event for the session
+	ReconcileEmitEvent ReconcileAction = iota
+	// ReconcileEndSession emits a SessionExhausted event and ends the session
+	ReconcileEndSession
+)
+
+// Start initiates the helper goroutines for the sender.
+// The reconciler is only started if a ReconcileInterval is configured,
+// validation decisions are only recorded if an AuditSink is configured and
+// the policy is only reloaded if a PolicySource and PolicyPollInterval are configured
+func (s *sender) Start() {
+	if s.cfg.ReconcileInterval > 0 {
+		go s.startReconcileLoop()
+	}
+
+	if s.audits != nil {
+		go s.startAuditLoop()
+	}
+
+	if s.cfg.PolicySource != nil && s.cfg.PolicyPollInterval > 0 {
+		go s.startPolicyLoop()
+	}
+}
+
+// Stop signals the sender's helper goroutines to exit
+func (s *sender) Stop() {
+	close(s.quit)
+}
+
+// startReconcileLoop initiates a loop that checks if sessions
+// can still be backed by the sender every ReconcileInterval
+func (s *sender) startReconcileLoop() {
+	ticker := time.NewTicker(s.cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.reconcile()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// reconcile applies the configured ReconcileAction to all untrusted sessions
+// that can no longer be backed by the sender's deposit and reserve
+func (s *sender) reconcile() {
+	info, err := s.getSenderInfo()
+	if err != nil {
+		glog.Errorf("error reconciling sessions, unable to fetch sender info err=%v", err)
+		return
+	}
+
+	// The info of the accounts of other signers is fetched once for all of their sessions
+	infos := map[ethcommon.Address]*SenderInfo{s.signer.Account().Address: info}
+
+	s.sessions.Range(func(key, value interface{}) bool {
+		sessionID := key.(string)
+		session := value.(*session)
+
+		addr := s.sessionSigner(session).Account().Address
+		info, ok := infos[addr]
+		if !ok {
+			var err error
+			info, err = s.getSenderInfoFor(addr)
+			if err != nil {
+				glog.Errorf("error reconciling session, unable to fetch sender info sessionID=%v sender=%v err=%v", sessionID, addr.Hex(), err)
+				return true
+			}
+			infos[addr] = info
+		}
+
+		s.clearValidation(session, info.Deposit)
+
+		if atomic.LoadUint32(&session.trusted) == 1 {
+			return true
+		}
+
+		if err := s.checkSessionBacked(session, info); err != nil {
+			s.emit(SenderEvent{Type: SessionExhausted, SessionID: sessionID, Err: err})
+
+			if s.cfg.ReconcileAction == ReconcileEndSession {
+				s.EndSession(sessionID)
+			}
+		}
+
+		return true
+	})
+}
+
+// checkSessionBacked returns an error if the sender's deposit and reserve
+// cannot back a ticket with the session's face value
+func (s *sender) checkSessionBacked(session *session, info *SenderInfo) error {
+	if info.Reserve == nil || info.Reserve.FundsRemaining == nil || info.Reserve.FundsRemaining.Sign() == 0 {
+		return errors.New("no sender reserve")
+	}
+
+	if info.Deposit == nil || info.Deposit.Sign() == 0 {
+		return errors.New("no sender deposit")
+	}
+
+	maxFaceValue := s.maxFaceValue(info, s.sessionDepositMultiplier(session))
+	if session.ticketParams.FaceValue.Cmp(maxFaceValue) > 0 {
+		return fmt.Errorf("ticket faceValue %v > max faceValue %v", session.ticketParams.FaceValue, maxFaceValue)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile_EmitEvent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	info := sm.info[sender.signer.Account().Address]
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1000)
+	sessionID := sender.StartSession(ticketParams)
+	trustedParams := defaultTicketParams(t, RandAddress())
+	trustedParams.FaceValue = big.NewInt(1000)
+	trustedSessionID := sender.StartSession(trustedParams)
+	require.Nil(sender.MarkTrusted(trustedSessionID))
+	drainEvents(sender)
+
+	// Sessions that can be backed are not reported
+	sender.reconcile()
+	assert.Len(sender.Events(), 0)
+
+	// Deposit no longer covers the session's face value
+	info.Deposit = big.NewInt(1000)
+	sender.reconcile()
+	require.Len(sender.Events(), 1)
+	event := <-sender.Events()
+	assert.Equal(SessionExhausted, event.Type)
+	assert.Equal(sessionID, event.SessionID)
+	assert.EqualError(event.Err, "ticket faceValue 1000 > max faceValue 500")
+
+	info.Deposit = big.NewInt(0)
+	sender.reconcile()
+	require.Len(sender.Events(), 1)
+	event = <-sender.Events()
+	assert.EqualError(event.Err, "no sender deposit")
+
+	// Sessions are not ended
+	assert.Len(sender.ListSessions(), 2)
+}
+
+func TestReconcile_EndSession(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ReconcileAction = ReconcileEndSession
+	sm := sender.senderManager.(*stubSenderManager)
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	drainEvents(sender)
+
+	sm.info[sender.signer.Account().Address].Reserve.FundsRemaining = big.NewInt(0)
+	sender.reconcile()
+
+	require.Len(sender.Events(), 2)
+	event := <-sender.Events()
+	assert.Equal(SessionExhausted, event.Type)
+	assert.Equal(sessionID, event.SessionID)
+	assert.EqualError(event.Err, "no sender reserve")
+	assert.Equal(SenderEvent{Type: SessionEnded, SessionID: sessionID}, <-sender.Events())
+	assert.Empty(sender.ListSessions())
+}
+
+func TestReconcile_Loop(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ReconcileInterval = 5 * time.Millisecond
+	sm := sender.senderManager.(*stubSenderManager)
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	drainEvents(sender)
+	sm.info[sender.signer.Account().Address].Deposit = big.NewInt(0)
+
+	sender.Start()
+	defer sender.Stop()
+
+	select {
+	case event := <-sender.Events():
+		assert.Equal(SessionExhausted, event.Type)
+		assert.Equal(sessionID, event.SessionID)
+	case <-time.After(time.Second):
+		t.Fatal("expected SessionExhausted event")
+	}
+}
+
+func TestReconcile_NoInterval_DoesNotStart(t *testing.T) {
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+
+	sender.StartSession(defaultTicketParams(t, RandAddress()))
+	drainEvents(sender)
+	sm.info[sender.signer.Account().Address].Deposit = big.NewInt(0)
+
+	sender.Start()
+	defer sender.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, sender.Events(), 0)
+}
+
+func drainEvents(sender *sender) {
+	for len(sender.events) > 0 {
+		<-sender.events
+	}
+}
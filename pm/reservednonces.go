@@ -0,0 +1,27 @@
+package pm
+
+import (
+	"fmt"
+)
+
+// WithReservedNonces reserves count consecutive nonces for a session and calls fn with the first reserved nonce,
+// e.g. for custom pipelines that build and sign the tickets for the nonces themselves. If fn returns an error, the
+// reservation is rolled back unless nonces were allocated for the session after the reservation, in which case
+// rolling back would let the later nonces be issued again, and the error is returned. The reserved nonces count
+// towards the session's tickets once fn succeeds. If StrictSequential is enabled, the session's nonce is locked
+// while fn runs so fn must not allocate nonces for the same session
+func (s *sender) WithReservedNonces(sessionID string, count int, fn func(start uint32) error) error {
+	if count < 1 {
+		return fmt.Errorf("nonce count must be greater than 0, but %v provided", count)
+	}
+
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, err := s.loadIssuableSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	return s.issueNonces(sessionID, session, count, fn)
+}
@@ -0,0 +1,70 @@
+package pm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReservedNonces(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	var start uint32
+	require.Nil(sender.WithReservedNonces(sessionID, 3, func(s uint32) error {
+		start = s
+		return nil
+	}))
+	assert.Equal(uint32(3), start)
+	assert.Equal(uint32(5), sender.SnapshotNonces()[sessionID])
+
+	// The reservation is rolled back if fn fails and the next ticket uses the reserved start
+	errFn := errors.New("fn error")
+	err = sender.WithReservedNonces(sessionID, 4, func(s uint32) error {
+		start = s
+		return errFn
+	})
+	assert.Equal(errFn, err)
+	assert.Equal(uint32(6), start)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(start, batch.SenderParams[0].SenderNonce)
+
+	// The reservation is kept if a nonce was issued after it
+	err = sender.WithReservedNonces(sessionID, 2, func(s uint32) error {
+		start = s
+		batch, err := sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(err)
+		assert.Equal(uint32(9), batch.SenderParams[0].SenderNonce)
+		return errFn
+	})
+	assert.Equal(errFn, err)
+	assert.Equal(uint32(7), start)
+
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(10), batch.SenderParams[0].SenderNonce)
+
+	// With StrictSequential the nonce is only advanced if fn succeeds
+	sender.cfg.StrictSequential = true
+	err = sender.WithReservedNonces(sessionID, 5, func(s uint32) error {
+		start = s
+		return errFn
+	})
+	assert.Equal(errFn, err)
+	assert.Equal(uint32(11), start)
+	assert.Equal(uint32(10), sender.SnapshotNonces()[sessionID])
+
+	assert.Contains(sender.WithReservedNonces(sessionID, 0, func(uint32) error { return nil }).Error(), "nonce count must be greater than 0")
+	err = sender.WithReservedNonces("foo", 1, func(uint32) error { return nil })
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+}
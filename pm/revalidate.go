@@ -0,0 +1,28 @@
+package pm
+
+import "sync/atomic"
+
+// revalidateSession re-validates a session's ticket params for the remaining tickets of a batch
+// after every PeriodicRevalidate tickets signed for the batch
+func (s *sender) revalidateSession(sessionID string, session *session, signed, size int) error {
+	if s.cfg.PeriodicRevalidate <= 0 || signed == 0 || signed%s.cfg.PeriodicRevalidate != 0 {
+		return nil
+	}
+
+	if atomic.LoadUint32(&session.trusted) == 1 {
+		return nil
+	}
+
+	info, err := s.sessionSenderInfo(session)
+	if err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, SessionID: sessionID, Err: err})
+		return SenderInfoError{err}
+	}
+
+	if err := s.validateTicketParamsWithInfo(&session.ticketParams, size-signed, s.sessionDepositMultiplier(session), info); err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, SessionID: sessionID, Err: err})
+		return ValidationError{err}
+	}
+
+	return nil
+}
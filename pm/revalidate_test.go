@@ -0,0 +1,109 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookSigner calls onSign with the number of Sign calls so far before signing
+type hookSigner struct {
+	stubSigner
+	calls  int
+	onSign func(calls int)
+}
+
+func (s *hookSigner) Sign(msg []byte) ([]byte, error) {
+	s.calls++
+	s.onSign(s.calls)
+	return s.signResponse, nil
+}
+
+func TestCreateTicketBatch_PeriodicRevalidate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	addr := sender.signer.Account().Address
+	// The deposit is drained after the 7th ticket is signed
+	sender.signer = &hookSigner{
+		stubSigner: *sender.signer.(*stubSigner),
+		onSign: func(calls int) {
+			if calls == 7 {
+				sm.info[addr].Deposit = big.NewInt(0)
+			}
+		},
+	}
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// Ticket params are only validated at the start of the batch by default
+	batch, err := sender.CreateTicketBatch(sessionID, 20)
+	require.Nil(err)
+	assert.Len(batch.SenderParams, 20)
+
+	sm.info[addr].Deposit = big.NewInt(100000)
+	sender.signer.(*hookSigner).calls = 0
+	sender.cfg.PeriodicRevalidate = 5
+	nonce := sender.ListSessions()[0].SenderNonce
+
+	batch, err = sender.CreateTicketBatch(sessionID, 20)
+	assert.Nil(batch)
+	require.NotNil(err)
+	_, ok := err.(ValidationError)
+	assert.True(ok)
+	assert.Equal("no sender deposit", errors.Cause(err).Error())
+	assert.Equal(10, sender.signer.(*hookSigner).calls)
+	assert.Equal(nonce, sender.ListSessions()[0].SenderNonce)
+
+	// Sender info errors while revalidating are returned
+	sm.info[addr].Deposit = big.NewInt(100000)
+	sender.signer.(*hookSigner).calls = 0
+	sender.signer.(*hookSigner).onSign = func(calls int) {
+		if calls == 5 {
+			sm.err = errors.New("GetSenderInfo error")
+		}
+	}
+	_, err = sender.CreateTicketBatch(sessionID, 20)
+	_, ok = err.(SenderInfoError)
+	assert.True(ok)
+
+	// Trusted sessions are not revalidated
+	sm.err = nil
+	sm.info[addr].Deposit = big.NewInt(0)
+	require.Nil(sender.MarkTrusted(sessionID))
+	batch, err = sender.CreateTicketBatch(sessionID, 20)
+	require.Nil(err)
+	assert.Len(batch.SenderParams, 20)
+}
+
+func BenchmarkCreateTicketBatch_Revalidation(b *testing.B) {
+	const size = 10000
+
+	modes := []struct {
+		name               string
+		periodicRevalidate int
+	}{
+		{"ValidateOnce", 0},
+		{"Revalidate1000", 1000},
+		{"Revalidate100", 100},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			sender := defaultSender(nil)
+			sender.cfg.PeriodicRevalidate = mode.periodicRevalidate
+			sessionID := sender.StartSession(defaultTicketParams(nil, RandAddress()))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := sender.CreateTicketBatch(sessionID, size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
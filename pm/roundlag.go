@@ -0,0 +1,43 @@
+package pm
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrStaleRound is returned when the creation round of tickets is further behind the chain head round than
+// MaxRoundLag, which indicates that the node is lagging behind the chain
+var ErrStaleRound = errors.New("ticket creation round is too far behind the chain head round")
+
+// issuableExpirationParams returns the expiration params of a session that tickets are issued with.
+// A RoundError with ErrStaleRound is returned if the creation round is too far behind the chain head round
+func (s *sender) issuableExpirationParams(session *session) (*TicketExpirationParams, error) {
+	expirationParams, err := s.sessionExpirationParams(session)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRoundLag(expirationParams.CreationRound); err != nil {
+		return nil, err
+	}
+
+	return expirationParams, nil
+}
+
+// checkRoundLag returns a RoundError with ErrStaleRound if MaxRoundLag and ChainHeadRound are configured and
+// creationRound is more than MaxRoundLag rounds behind the chain head round
+func (s *sender) checkRoundLag(creationRound int64) error {
+	if s.cfg.MaxRoundLag <= 0 || s.cfg.ChainHeadRound == nil {
+		return nil
+	}
+
+	headRound := s.cfg.ChainHeadRound()
+	if headRound == nil || !headRound.IsInt64() {
+		return nil
+	}
+
+	if lag := headRound.Int64() - creationRound; lag > s.cfg.MaxRoundLag {
+		return RoundError{errors.Wrapf(ErrStaleRound, "creation round %v is %v rounds behind chain head round %v", creationRound, lag, headRound)}
+	}
+
+	return nil
+}
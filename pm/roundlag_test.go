@@ -0,0 +1,62 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxRoundLag(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	headRound := big.NewInt(5)
+	sender.cfg.ChainHeadRound = func() *big.Int { return headRound }
+	sender.cfg.MaxRoundLag = 2
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// The last initialized round is round 5
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	headRound = big.NewInt(7)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	// A large gap between the creation round and the chain head round indicates that the node is lagging
+	headRound = big.NewInt(100)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok := err.(RoundError)
+	assert.True(ok)
+	assert.Equal(ErrStaleRound, errors.Cause(err))
+	assert.EqualError(err, "creation round 5 is 95 rounds behind chain head round 100: "+ErrStaleRound.Error())
+
+	_, _, err = sender.BuildTicket(sessionID)
+	assert.Equal(ErrStaleRound, errors.Cause(err))
+
+	_, err = sender.CreateBatchSplitByRound(sessionID, 1)
+	assert.Equal(ErrStaleRound, errors.Cause(err))
+
+	assert.Equal(ErrStaleRound, errors.Cause(sender.Ready(sessionID)))
+
+	// No nonces are consumed by rejected batches
+	headRound = big.NewInt(6)
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+
+	// The creation round is not compared to the chain head round if no max lag is configured
+	headRound = big.NewInt(100)
+	sender.cfg.MaxRoundLag = 0
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	sender.cfg.MaxRoundLag = 2
+	sender.cfg.ChainHeadRound = func() *big.Int { return nil }
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+}
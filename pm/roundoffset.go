@@ -0,0 +1,64 @@
+package pm
+
+import (
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// RoundBlockHasher is implemented by a TimeManager that can return the block hash of rounds
+// before the last initialized round. It is required to use a SessionPolicy.ExpirationRoundOffset
+type RoundBlockHasher interface {
+	// BlockHashForRound returns the hash of the block a round was initialized in
+	BlockHashForRound(round *big.Int) ([32]byte, error)
+}
+
+// validateExpirationRoundOffset checks that the tickets of a session can be stamped with a creation
+// round offset from the last initialized round.
+// Rounds after the last initialized round do not have a block hash yet and tickets with such a creation
+// round cannot be redeemed, so the offset must not be positive
+func (s *sender) validateExpirationRoundOffset(offset int64) error {
+	if offset == 0 {
+		return nil
+	}
+
+	if offset > 0 {
+		return fmt.Errorf("session expiration round offset must not be greater than 0, but %v provided", offset)
+	}
+
+	if _, ok := s.timeManager.(RoundBlockHasher); !ok {
+		return errors.New("session expiration round offset requires a TimeManager that returns the block hash of past rounds")
+	}
+
+	return nil
+}
+
+// offsetExpirationParams returns expiration params for the creation round of params offset by the session's
+// ExpirationRoundOffset. A RoundError is returned if the resulting round is not initialized
+func (s *sender) offsetExpirationParams(session *session, params *TicketExpirationParams) (*TicketExpirationParams, error) {
+	offset := session.policy.ExpirationRoundOffset
+	if offset == 0 {
+		return params, nil
+	}
+
+	round := params.CreationRound + offset
+	if round < 1 {
+		return nil, RoundError{fmt.Errorf("creation round %v with offset %v is not initialized", round, offset)}
+	}
+
+	blkHash, err := s.timeManager.(RoundBlockHasher).BlockHashForRound(big.NewInt(round))
+	if err != nil {
+		return nil, RoundError{errors.Wrapf(err, "error fetching block hash for creation round %v", round)}
+	}
+
+	if blkHash == [32]byte{} {
+		return nil, RoundError{fmt.Errorf("creation round %v with offset %v is not initialized", round, offset)}
+	}
+
+	return &TicketExpirationParams{
+		CreationRound:          round,
+		CreationRoundBlockHash: ethcommon.BytesToHash(blkHash[:]),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package pm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRoundBlockHasher struct {
+	*stubTimeManager
+	blkHashes map[int64][32]byte
+	err       error
+}
+
+func (m *stubRoundBlockHasher) BlockHashForRound(round *big.Int) ([32]byte, error) {
+	return m.blkHashes[round.Int64()], m.err
+}
+
+func TestExpirationRoundOffset(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+
+	// The TimeManager must return block hashes of past rounds
+	_, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{ExpirationRoundOffset: -2})
+	assert.EqualError(err, "session expiration round offset requires a TimeManager that returns the block hash of past rounds")
+
+	tm := &stubRoundBlockHasher{
+		stubTimeManager: sender.timeManager.(*stubTimeManager),
+		blkHashes:       map[int64][32]byte{3: {3}, 4: {4}},
+	}
+	sender.timeManager = tm
+
+	_, err = sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{ExpirationRoundOffset: 1})
+	assert.EqualError(err, "session expiration round offset must not be greater than 0, but 1 provided")
+	assert.Empty(sender.ListSessions())
+
+	sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{ExpirationRoundOffset: -2})
+	require.Nil(err)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(int64(3), batch.CreationRound)
+	assert.Equal(ethcommon.Hash{3}, batch.CreationRoundBlockHash)
+	assert.Equal(int64(3), batch.Tickets()[0].CreationRound)
+
+	// The offset is applied to the last initialized round
+	tm.round = big.NewInt(6)
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(int64(4), batch.CreationRound)
+	assert.Equal(ethcommon.Hash{4}, batch.CreationRoundBlockHash)
+
+	// Sessions without an offset use the last initialized round
+	otherID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+	require.Nil(err)
+	batch, err = sender.CreateTicketBatch(otherID, 1)
+	require.Nil(err)
+	assert.Equal(int64(6), batch.CreationRound)
+
+	// Rounds without a block hash are not initialized
+	tm.round = big.NewInt(7)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok := err.(RoundError)
+	assert.True(ok)
+	assert.EqualError(err, "creation round 5 with offset -2 is not initialized")
+
+	sender.cfg.AllowRoundRegression = true
+	tm.round = big.NewInt(1)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "creation round -1 with offset -2 is not initialized")
+
+	tm.round = big.NewInt(5)
+	tm.err = errors.New("BlockHashForRound error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "error fetching block hash for creation round 3: BlockHashForRound error")
+}
@@ -0,0 +1,69 @@
+package pm
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInsufficientRateData is returned when there are not enough issuance records
+// to compute the ticket creation rate of a session
+var ErrInsufficientRateData = errors.New("insufficient ticket creation rate data")
+
+// DepositRunway estimates how long the sender's deposit can back tickets for a session if tickets
+// continue to be created at the session's current rate and winning tickets are redeemed at the session's
+// ticket EV. The rate is computed from the session's issuance log so the sender must be configured with an
+// IssuanceLogSize of at least 2. The deposit can no longer back tickets once the part of it that the sender
+// validates tickets against, e.g. excluding a pending withdrawal, is lower than the session's face value times
+// its deposit multiplier. The runway is 0 if the sender already fails validation, e.g. because its reserve is
+// empty or its deposit unlocks soon. This is only a projection and does not affect ticket creation
+func (s *sender) DepositRunway(sessionID string) (time.Duration, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	records := session.issuanceLog.list()
+	if len(records) < 2 {
+		return 0, ErrInsufficientRateData
+	}
+
+	elapsed := records[len(records)-1].Timestamp.Sub(records[0].Timestamp)
+	if elapsed <= 0 {
+		return 0, ErrInsufficientRateData
+	}
+
+	ev := ticketEV(session.ticketParams.FaceValue, session.ticketParams.WinProb)
+	if ev.Sign() == 0 {
+		return 0, errors.New("unable to compute deposit runway for session with zero ticket EV")
+	}
+
+	info, err := s.sessionSenderInfo(session)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.validateSender(info); err != nil {
+		return 0, nil
+	}
+
+	minDeposit := new(big.Int).Mul(session.ticketParams.FaceValue, big.NewInt(int64(s.sessionDepositMultiplier(session))))
+	spendable := new(big.Int).Sub(s.usableDeposit(info), minDeposit)
+	if spendable.Sign() <= 0 {
+		return 0, nil
+	}
+
+	// runway = spendable / (rate * ev) where rate = (len(records) - 1) / elapsed
+	cost := new(big.Rat).Mul(ev, new(big.Rat).SetInt64(int64(len(records)-1)))
+	runway := new(big.Rat).Mul(new(big.Rat).SetInt(spendable), new(big.Rat).SetInt64(int64(elapsed)))
+	runway.Quo(runway, cost)
+
+	nanos := new(big.Int).Quo(runway.Num(), runway.Denom())
+	if !nanos.IsInt64() {
+		return time.Duration(math.MaxInt64), nil
+	}
+
+	return time.Duration(nanos.Int64()), nil
+}
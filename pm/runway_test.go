@@ -0,0 +1,110 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositRunway(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 5
+	sm := sender.senderManager.(*stubSenderManager)
+	info := sm.info[sender.signer.Account().Address]
+
+	_, err := sender.DepositRunway("foo")
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	// EV = faceValue = 100
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(100)
+	ticketParams.WinProb = maxWinProb
+	sessionID := sender.StartSession(ticketParams)
+
+	_, err = sender.DepositRunway(sessionID)
+	assert.Equal(ErrInsufficientRateData, err)
+
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	_, err = sender.DepositRunway(sessionID)
+	assert.Equal(ErrInsufficientRateData, err)
+
+	// 1 ticket per second
+	for i := 0; i < 6; i++ {
+		now = now.Add(time.Second)
+		_, err = sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(err)
+	}
+
+	// (100000 - 100 * 2) / 100 per second
+	runway, err := sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Equal(998*time.Second, runway)
+
+	info.Deposit = big.NewInt(250)
+	runway, err = sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Equal(500*time.Millisecond, runway)
+
+	info.Deposit = big.NewInt(200)
+	runway, err = sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Zero(runway)
+
+	// A pending withdrawal is not spendable if the sender subtracts it from the deposit
+	info.Deposit = big.NewInt(100000)
+	info.PendingWithdrawal = big.NewInt(50000)
+	runway, err = sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Equal(998*time.Second, runway)
+
+	sender.cfg.WithdrawalAction = WithdrawalSubtract
+	runway, err = sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Equal(498*time.Second, runway)
+
+	// A sender that fails validation has no runway
+	sender.cfg.WithdrawalAction = WithdrawalReject
+	runway, err = sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Zero(runway)
+
+	info.PendingWithdrawal = nil
+	info.Reserve = &ReserveInfo{FundsRemaining: big.NewInt(0)}
+	runway, err = sender.DepositRunway(sessionID)
+	require.Nil(err)
+	assert.Zero(runway)
+
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.DepositRunway(sessionID)
+	assert.Equal(sm.err, err)
+}
+
+func TestDepositRunway_ZeroEV_ReturnsError(t *testing.T) {
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 5
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Second)
+		_, err := sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(t, err)
+	}
+
+	_, err := sender.DepositRunway(sessionID)
+	assert.EqualError(t, err, "unable to compute deposit runway for session with zero ticket EV")
+}
@@ -1,199 +1,2284 @@
 package pm
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
 	"github.com/pkg/errors"
 )
 
+// timeNow returns the current time
+var timeNow = time.Now
+
+// randJitter returns a random duration in [0, window)
+var randJitter = func(window time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(window)))
+}
+
+// ErrUnknownSession is returned when a session cannot be found
+var ErrUnknownSession = errors.New("unknown session")
+
+// ErrInvalidTicketParams is returned for ticket params with values that cannot be used in a ticket
+var ErrInvalidTicketParams = errors.New("invalid ticket params")
+
+// ErrSessionNonceLimit is returned when creating tickets for a session would exceed the
+// configured max nonce for a session
+var ErrSessionNonceLimit = errors.New("session nonce limit reached")
+
+// ErrSessionStale is returned when creating tickets for a session that was marked stale
+var ErrSessionStale = errors.New("session is stale")
+
+// ErrSessionExpired is returned when creating tickets for a session after the deadline of its SessionPolicy
+var ErrSessionExpired = errors.New("session deadline passed")
+
+// ErrRoundRegression is returned when the last initialized round reported by the
+// TimeManager is lower than a previously seen round
+var ErrRoundRegression = errors.New("last initialized round regressed")
+
+// ErrSenderFrozen is returned when the sender's account is frozen or when ticket creation was stopped with Freeze
+var ErrSenderFrozen = errors.New("sender is frozen")
+
+// ErrRoundQuotaExhausted is returned when a session has reached its max number of tickets for the current round
+var ErrRoundQuotaExhausted = errors.New("session ticket quota for round exhausted")
+
+// ErrSignerPanic is returned when the signer panics while signing a ticket
+var ErrSignerPanic = errors.New("signer panicked")
+
+// ErrNonceGap is returned for operations that would skip nonces of a session if StrictSequential is enabled
+var ErrNonceGap = errors.New("operation would create a nonce gap")
+
+// ErrSubWeiEV is returned for ticket params with an EV of less than one wei if RejectSubWeiEV is enabled
+var ErrSubWeiEV = errors.New("ticket EV is less than 1 wei")
+
+// ErrEmptyBatch is returned when a ticket batch with less than one ticket is requested.
+// ValidateTicketParams can be used to check ticket params without creating tickets
+var ErrEmptyBatch = errors.New("ticket batch size must be greater than 0")
+
 // ErrSenderValidation is returned when the sender cannot send tickets
 type ErrSenderValidation struct {
 	error
 }
 
+// The following error types categorize the errors returned when creating tickets so that callers
+// can decide whether to retry. Each type wraps the underlying error which can be retrieved with
+// errors.Cause or errors.Unwrap. Errors caused by invalid arguments such as ErrUnknownSession and
+// ErrEmptyBatch, ErrRoundQuotaExhausted and session store errors are not categorized
+
+// SignerError is returned when the signer fails to sign a ticket.
+// Retrying is not expected to help without fixing the signer
+type SignerError struct {
+	error
+}
+
+// RoundError is returned when the expiration params for the current round cannot be determined,
+// for example because the last initialized round regressed. Retrying may help once the
+// TimeManager reports the correct round
+type RoundError struct {
+	error
+}
+
+// ValidationError is returned when a session's ticket params or the sender's on-chain state are not
+// acceptable for creating tickets. Retrying does not help until the ticket params or the sender's state change
+type ValidationError struct {
+	error
+}
+
+// SenderInfoError is returned when the sender's on-chain info cannot be fetched to validate ticket params.
+// Retrying may help once the SenderManager is able to fetch the sender info
+type SenderInfoError struct {
+	error
+}
+
+// Cause returns the underlying error
+func (e SignerError) Cause() error { return e.error }
+
+// Unwrap returns the underlying error
+func (e SignerError) Unwrap() error { return e.error }
+
+// Cause returns the underlying error
+func (e RoundError) Cause() error { return e.error }
+
+// Unwrap returns the underlying error
+func (e RoundError) Unwrap() error { return e.error }
+
+// Cause returns the underlying error
+func (e ValidationError) Cause() error { return e.error }
+
+// Unwrap returns the underlying error
+func (e ValidationError) Unwrap() error { return e.error }
+
+// Cause returns the underlying error
+func (e SenderInfoError) Cause() error { return e.error }
+
+// Unwrap returns the underlying error
+func (e SenderInfoError) Unwrap() error { return e.error }
+
 // Sender enables starting multiple probabilistic micropayment sessions with multiple recipients
 // and create tickets that adhere to each session's params and unique nonce requirements.
 type Sender interface {
 	// StartSession creates a session for a given set of ticket params which tracks information
-	// for creating new tickets
+	// for creating new tickets. If the sender is configured with a ParamsTransform that returns
+	// an error, the session is not created and subsequent calls for the session ID will fail
 	StartSession(ticketParams TicketParams) string
 
-	// CreateTicketBatch returns a ticket batch of the specified size
+	// StartSessionWithPolicy creates a session for a given set of ticket params using a policy
+	// that overrides the sender's defaults for the session
+	StartSessionWithPolicy(ticketParams TicketParams, policy SessionPolicy) (string, error)
+
+	// CreateTicketBatch returns a ticket batch of the specified size. ErrEmptyBatch is returned if size is less than 1.
+	// Tickets are signed on the calling goroutine without holding any sender-wide lock
+	// so creating a large batch for one session does not delay ticket creation for other sessions
 	CreateTicketBatch(sessionID string, size int) (*TicketBatch, error)
 
+	// CreateTicketBatchCtx returns a ticket batch of the specified size and returns ctx.Err() if the context
+	// is done before all tickets of the batch are signed. The nonces of a cancelled batch are given back to the session
+	CreateTicketBatchCtx(ctx context.Context, sessionID string, size int) (*TicketBatch, error)
+
+	// MightHaveIssued returns false if a nonce was definitely not issued for a session and true if it might have been
+	MightHaveIssued(sessionID string, nonce uint32) bool
+
+	// CreateTicketBatchAsync creates a ticket batch of the specified size on a new goroutine and returns a
+	// channel that delivers exactly one BatchResult and is then closed
+	CreateTicketBatchAsync(ctx context.Context, sessionID string, size int) <-chan BatchResult
+
+	// CreateTicketBatchWithKey creates a ticket batch of the specified size unless a batch was recently requested
+	// for the session with the same idempotency key, in which case the same batch is returned
+	CreateTicketBatchWithKey(sessionID string, size int, key string) (*TicketBatch, error)
+
+	// SingleWriter returns a SingleWriterSession that creates tickets for a session from a single goroutine
+	// and reserves nonces from the session in blocks of blockSize nonces
+	SingleWriter(sessionID string, blockSize int) (*SingleWriterSession, error)
+
+	// CreateBatchSplitByRound returns ticket batches for a contiguous sequence of size nonces
+	// with one batch for each round that the tickets were created in
+	CreateBatchSplitByRound(sessionID string, size int) ([]*TicketBatch, error)
+
+	// CreateMultiRecipientBatch creates a ticket batch for each request and groups them
+	// in a MultiRecipientBatch. The sessions of the requests must be for different recipients
+	CreateMultiRecipientBatch(requests []BatchRequest) (*MultiRecipientBatch, error)
+
+	// CreateBatchesAtomic creates a ticket batch for every request or returns an error without
+	// advancing the nonce of any of the sessions
+	CreateBatchesAtomic(requests []BatchRequest) ([]*TicketBatch, error)
+
+	// CanIssueBatch checks whether a batch of size tickets can currently be created for a session
+	// and returns a human readable reason if it cannot
+	CanIssueBatch(sessionID string, size int) (bool, string, error)
+
 	// ValidateTicketParams checks if ticket params are acceptable
 	ValidateTicketParams(ticketParams *TicketParams) error
 
+	// ValidateParamsBatch checks if each of a list of ticket params is acceptable and returns
+	// an error or nil for each entry in the list
+	ValidateParamsBatch(paramsList []TicketParams) []error
+
+	// ValidateTicketParamsCtx checks if ticket params are acceptable and returns ctx.Err()
+	// if the context is done before the sender info is fetched
+	ValidateTicketParamsCtx(ctx context.Context, ticketParams *TicketParams) error
+
+	// AdaptiveBatchSize returns the smallest batch size for a session such that the expected
+	// number of winning tickets in the batch is at least targetWinExpectation
+	AdaptiveBatchSize(sessionID string, targetWinExpectation *big.Rat) (int, error)
+
+	// EVForFaceValue returns the EV of a ticket with faceValue and the session's win probability
+	EVForFaceValue(sessionID string, faceValue *big.Int) (*big.Rat, error)
+
+	// RequiredDeposit returns the minimum deposit needed to back a batch of size tickets for a session
+	RequiredDeposit(sessionID string, size int) (*big.Int, error)
+
 	// EV returns the ticket EV for a session
 	EV(sessionID string) (*big.Rat, error)
+
+	// MaxFaceValue returns the highest face value that is accepted for tickets of a session
+	MaxFaceValue(sessionID string) (*big.Int, error)
+
+	// ListSessions returns information about all sessions ordered by session ID
+	ListSessions() []SessionInfo
+
+	// GlobalStats returns ticket creation totals across all sessions
+	GlobalStats() GlobalStats
+
+	// SessionsForRecipient returns information about the sessions for a recipient ordered by session ID
+	SessionsForRecipient(recipient ethcommon.Address) []SessionInfo
+
+	// SnapshotNonces returns the current nonce of every session keyed by session ID
+	SnapshotNonces() map[string]uint32
+
+	// DebugDump returns the state of all sessions as indented JSON with secrets redacted for debugging
+	DebugDump() ([]byte, error)
+
+	// AdvanceNonce sets the nonce of a session to the provided value if it is greater
+	// than the session's current nonce
+	AdvanceNonce(sessionID string, to uint32) error
+
+	// WithReservedNonces reserves count consecutive nonces for a session, calls fn with the first
+	// reserved nonce and rolls back the reservation if fn fails and no later nonce was issued
+	WithReservedNonces(sessionID string, count int, fn func(start uint32) error) error
+
+	// RecipientCommittedEV returns the total EV of the tickets created for a recipient across all sessions
+	RecipientCommittedEV(recipient ethcommon.Address) *big.Rat
+
+	// MarkTrusted disables ticket params validation when creating tickets for a session
+	MarkTrusted(sessionID string) error
+
+	// MarkSessionStale flags a session so that creating tickets for it returns ErrSessionStale
+	MarkSessionStale(sessionID string) error
+
+	// IssuanceLog returns the most recent tickets created for a session ordered from oldest to newest
+	IssuanceLog(sessionID string) ([]IssuanceRecord, error)
+
+	// TicketHash returns the hash of a ticket that is signed by the sender, which is used to
+	// verify the signatures of the sender's tickets
+	TicketHash(ticket *Ticket) ethcommon.Hash
+
+	// Domain returns the version of the ticket hashing scheme and the domain separator that the sender signs tickets under
+	Domain() TicketDomain
+
+	// VerifyTicket checks if a ticket matches the ticket that the sender would create for a session
+	VerifyTicket(sessionID string, ticket *Ticket) error
+
+	// EndSession removes a session
+	EndSession(sessionID string)
+
+	// EndSessionSync removes a session after its nonce is durably persisted by the SessionStore
+	EndSessionSync(sessionID string) error
+
+	// Events returns a channel that receives events describing changes in the state of the sender
+	Events() <-chan SenderEvent
+
+	// DroppedAudits returns the number of validation decisions that were not recorded by the AuditSink
+	// because the audit queue was full
+	DroppedAudits() uint64
+
+	// DroppedEvents returns the number of events that were dropped because the events channel was full
+	DroppedEvents() uint64
+
+	// RefreshBatch re-signs the tickets of a batch using the current round's expiration params
+	RefreshBatch(sessionID string, oldBatch *TicketBatch) (*TicketBatch, error)
+
+	// RefreshBatchExcept re-signs the tickets of a batch except for the tickets with the skipped nonces
+	// using the current round's expiration params
+	RefreshBatchExcept(sessionID string, oldBatch *TicketBatch, skipNonces []uint32) (*TicketBatch, error)
+
+	// Ready checks that tickets can be created for a session including a dry run of the signer
+	// and returns the first failed check
+	Ready(sessionID string) error
+
+	// LastTicket returns the ticket most recently created for a session and its signature
+	LastTicket(sessionID string) (*Ticket, []byte, bool)
+
+	// PeekTicket returns a signed sample ticket for a session that is not a valid payment
+	PeekTicket(sessionID string) (*Ticket, []byte, error)
+
+	// BuildTicket allocates the next nonce of a session and returns an unsigned ticket with the nonce.
+	// The caller must sign every built ticket with SignBuilt to avoid gaps in the session's nonces
+	BuildTicket(sessionID string) (*Ticket, uint32, error)
+
+	// SignBuilt signs a ticket returned by BuildTicket. Each built ticket is signed once
+	SignBuilt(ticket *Ticket) ([]byte, error)
+
+	// CreateTicketAtRound creates a signed ticket for a session with expiration params for the provided
+	// round and block hash. It is intended for tests and replaying historical tickets
+	CreateTicketAtRound(sessionID string, round int64, blockHash [32]byte) (*Ticket, []byte, error)
+
+	// CreateTicketWithAux creates a signed ticket for a session that is bound to the hash of an off-chain artifact
+	CreateTicketWithAux(sessionID string, auxDataHash ethcommon.Hash) (*Ticket, []byte, error)
+
+	// SigningStats returns the latency percentiles of the signer's Sign calls
+	SigningStats() SigningStats
+
+	// DepositRunway estimates how long the sender's deposit can back tickets for a session
+	// at the session's current ticket creation rate
+	DepositRunway(sessionID string) (time.Duration, error)
+
+	// HealthScore returns a score between 0 and 1 for a session that combines the deposit headroom, round validity,
+	// validation success rate and signing latency of the session
+	HealthScore(sessionID string) (float64, error)
+
+	// Freeze stops ticket creation for all sessions until Unfreeze is called
+	Freeze()
+
+	// Unfreeze resumes ticket creation after Freeze
+	Unfreeze()
+
+	// Start initiates the helper goroutines for the sender
+	Start()
+
+	// Stop signals the sender's helper goroutines to exit
+	Stop()
+}
+
+// SessionInfo describes the state of a sender session
+type SessionInfo struct {
+	SessionID string
+
+	SenderNonce uint32
+
+	TicketParams TicketParams
+
+	// Metadata is the caller-defined metadata the session was started with
+	Metadata map[string]string
+}
+
+// SessionPolicy contains optional per-session overrides of the sender's defaults
+type SessionPolicy struct {
+	// Metadata is caller-defined information associated with the session such as an
+	// orchestrator URL or stream ID. It is not used by the sender and is only returned in SessionInfo
+	Metadata map[string]string
+
+	// DepositMultiplier is used instead of the sender's deposit multiplier to compute
+	// the max face value of tickets for the session. If zero, the sender's deposit multiplier is used
+	DepositMultiplier int
+
+	// MaxTicketsPerRound is used instead of the sender's MaxTicketsPerRound for the session if non-zero
+	MaxTicketsPerRound int
+
+	// ExpirationBlock overrides the expiration block of the session's ticket params for
+	// recipients that accept a shorter validity window than advertised in their ticket params.
+	// The override must be after the last seen block and cannot be later than the ticket params' expiration block
+	ExpirationBlock *big.Int
+
+	// ExpirationRoundOffset is added to the last initialized round to get the creation round of tickets
+	// for the session when the ticket params do not include expiration params. The offset cannot be
+	// positive and requires a TimeManager that implements RoundBlockHasher. If zero, the last initialized round is used
+	ExpirationRoundOffset int64
+
+	// Deadline is the wall-clock time after which tickets can no longer be created for the session, e.g. the
+	// time at which the job paid for by the session times out. Creating tickets after the deadline returns
+	// ErrSessionExpired. If zero, the session has no deadline
+	Deadline time.Time
+}
+
+// SenderConfig contains optional config information for a sender
+type SenderConfig struct {
+	// ParamsTransform is applied to the ticket params passed to StartSession before
+	// they are stored with the session. A non-nil error aborts the session start
+	ParamsTransform func(TicketParams) (TicketParams, error)
+
+	// EVPolicy replaces the default check that the total EV of a group of tickets does not exceed
+	// the sender's maxEV. It is called with the ticket params, the number of tickets being validated
+	// and the sender's on-chain info and should return a non-nil error if the tickets are not acceptable
+	EVPolicy func(params *TicketParams, numTickets int, info SenderInfo) error
+
+	// IssuanceLogSize is the max number of records kept in the issuance log of each session.
+	// If zero, no issuance records are kept
+	IssuanceLogSize int
+
+	// AllowRoundRegression disables the check that the last initialized round reported
+	// by the TimeManager never goes backwards
+	AllowRoundRegression bool
+
+	// EventBufferSize is the size of the buffer of the channel returned by Events().
+	// If zero, a default size is used
+	EventBufferSize int
+
+	// RejectFrozenSender enables rejecting ticket creation with ErrSenderFrozen if the
+	// SenderManager reports that the sender's account is frozen. This should only be enabled
+	// if the SenderManager populates SenderInfo.Status
+	RejectFrozenSender bool
+
+	// WithdrawalAction is the action taken when validating ticket params while the sender has a pending
+	// withdrawal of its deposit. Defaults to WithdrawalIgnore
+	WithdrawalAction WithdrawalAction
+
+	// MaxTicketsPerRound is the max number of tickets that can be created for a session
+	// during a single round. If zero, the number of tickets per round is not limited
+	MaxTicketsPerRound int
+
+	// SessionStore is used to persist session nonces when tickets are created and to restore
+	// them when a session is started. If nil, session nonces are only kept in memory
+	SessionStore SessionStore
+
+	// StrictPersistence enables failing ticket creation if the SessionStore cannot persist
+	// a session's nonce. If false, persistence errors are logged and tickets are still created
+	StrictPersistence bool
+
+	// RefreshJitter enables caching the expiration params of the last initialized round. When a new
+	// round is seen, the cache is refreshed after a random delay within [0, RefreshJitter) so that
+	// senders sharing a TimeManager do not all refresh at the round boundary. Until the cache is refreshed,
+	// tickets are created using the previous round's expiration params. If zero, expiration params are not cached
+	RefreshJitter time.Duration
+
+	// CompositeSessionID enables using the ticket params' recipient address together with the
+	// recipient rand hash as the session ID so that sessions for different recipients cannot collide
+	// if the recipients provide the same recipient rand hash. If false, the recipient rand hash is used as the session ID
+	CompositeSessionID bool
+
+	// StrictSequential guarantees that the nonces of the tickets handed out for a session form a gapless
+	// sequence. A session's nonce is only advanced after all tickets of a batch are signed, so batches for
+	// the same session are signed one at a time instead of concurrently, and operations that would skip
+	// nonces such as AdvanceNonce return ErrNonceGap
+	StrictSequential bool
+
+	// ReconcileInterval is the interval at which the reconciler checks if the sender's deposit and
+	// reserve can still back a ticket for each session once the sender is started. If zero, the reconciler is not run
+	ReconcileInterval time.Duration
+
+	// ReconcileAction is the action taken by the reconciler for sessions that can no longer be backed
+	ReconcileAction ReconcileAction
+
+	// ValidationCacheTTL enables caching a successful validation of a session's ticket params with the sender
+	// info it used so that ticket creation for the session does not fetch the sender info again until the TTL
+	// passes. The sender is still validated against the cached info every time, e.g. to reject a deposit that
+	// unlocks soon. A cached validation only covers batches with at most as many tickets as the validated batch
+	// and is cleared if the reconciler sees a different deposit. If zero, ticket params are validated every time
+	// tickets are created
+	ValidationCacheTTL time.Duration
+
+	// DepositCoordinator is consulted after ticket params are validated when creating tickets so
+	// that multiple processes sharing the sender's deposit do not collectively over-commit it. Each batch
+	// reserves the total EV of its tickets. If nil, reservations are not coordinated with other processes
+	DepositCoordinator DepositCoordinator
+
+	// PeriodicRevalidate enables re-validating a session's ticket params against freshly fetched sender
+	// info after every PeriodicRevalidate tickets signed for a batch so that large batches stop early if
+	// the sender's deposit or reserve is drained while the batch is being signed. If zero, ticket params
+	// are validated once at the start of each batch. Validating once is recommended when fetching the
+	// sender info is expensive relative to signing (e.g. sender info served by a remote node) while a
+	// value of a few thousand bounds the work wasted on batches of tens of thousands of tickets
+	PeriodicRevalidate int
+
+	// Observer is notified of the sender's activity, e.g. to export metrics. If nil, no observer is notified
+	Observer SenderObserver
+
+	// CertainWinAction is the action taken for ticket params with a win probability that implies every
+	// ticket wins. Defaults to CertainWinAllow
+	CertainWinAction CertainWinAction
+
+	// MaxConcurrentBatches limits the number of ticket batches that are signed at the same time across all
+	// sessions. Creating a batch waits until fewer than MaxConcurrentBatches batches are being signed.
+	// If zero, the number of batches signed at the same time is not limited
+	MaxConcurrentBatches int
+
+	// ThrottleSingleTickets makes batches of a single ticket count towards MaxConcurrentBatches.
+	// By default single tickets are created without waiting for other batches
+	ThrottleSingleTickets bool
+
+	// RecentNonceWindow is the number of nonces most recently issued for each session that are remembered
+	// as a safety net against reissuing a nonce for a session. Creating tickets that would reuse one of these
+	// nonces fails with ErrDuplicateNonce. If zero, recently issued nonces are not remembered
+	RecentNonceWindow int
+
+	// DepositOracle is consulted for the sender's info before the SenderManager when validating ticket params.
+	// The SenderManager is used if the oracle fails, has no info for the sender or its info is not fresh enough.
+	// If nil, the sender's info is always fetched from the SenderManager
+	DepositOracle DepositOracle
+
+	// DepositOracleMaxAge is the max age of info returned by the DepositOracle that is used instead of
+	// the SenderManager's info. If zero, info from the oracle is used regardless of its age
+	DepositOracleMaxAge time.Duration
+
+	// MaxNoncePerSession is the highest nonce used for tickets of a session. Creating tickets that would
+	// exceed it fails with ErrSessionNonceLimit so that the caller starts a new session with fresh ticket
+	// params from the recipient. If zero, nonces are only limited by the range of uint32
+	MaxNoncePerSession uint32
+
+	// HashFunc computes the hash of the tickets that are signed by the sender, e.g. to interoperate with
+	// alternative ticket schemes. If nil, Ticket.Hash is used
+	HashFunc HashFunc
+
+	// AuditSink records every decision made by ValidateTicketParams. Decisions are passed to the sink
+	// asynchronously by a goroutine started by Start and are dropped if the queue of decisions is full.
+	// If nil, decisions are not recorded
+	AuditSink AuditSink
+
+	// AuditQueueSize is the max number of decisions waiting to be recorded by the AuditSink.
+	// If zero, a default size is used
+	AuditQueueSize int
+
+	// StagedPolicy is an additional check of ticket params, such as a stricter policy that is being rolled out,
+	// that runs after the regular checks passed. It is called with the same arguments as EVPolicy and
+	// should return a non-nil error if the tickets would not be acceptable
+	StagedPolicy func(params *TicketParams, numTickets int, info SenderInfo) error
+
+	// StagedPolicyMode controls whether StagedPolicy rejects ticket params. Defaults to StagedPolicyShadow,
+	// which only logs and emits ShadowValidationFailed events for ticket params that it would reject
+	StagedPolicyMode StagedPolicyMode
+
+	// Signers are the signers of additional funded accounts that can sign the tickets of a session.
+	// The signer passed to NewSender is always the first candidate passed to the SignerSelector
+	Signers []Signer
+
+	// SignerSelector selects the signer of a new session from the signer passed to NewSender and Signers.
+	// The selected signer signs all tickets of the session and the session's ticket params are validated
+	// against the deposit and reserve of its account. If nil, the signer passed to NewSender is used
+	SignerSelector SignerSelector
+
+	// HashVersion is the version of the ticket hashing scheme implemented by HashFunc that is reported by Domain.
+	// Only used if HashFunc is set
+	HashVersion string
+
+	// RejectSubWeiEV enables rejecting ticket params with an EV of less than one wei with ErrSubWeiEV.
+	// Such params are economically meaningless and usually indicate a misconfigured recipient
+	RejectSubWeiEV bool
+
+	// SignatureFormat is the layout of the ticket signatures returned by the sender. Signatures produced by the
+	// signer are checked and converted to the format. Defaults to SignatureFormatSigner which returns the
+	// signatures of the signer unchanged
+	SignatureFormat SignatureFormat
+
+	// ReusedSessionAction is the action taken when a session is started with the session ID of a recently ended
+	// session and a nonce lower than the last nonce of the ended session. Defaults to ReusedSessionAllow
+	ReusedSessionAction ReusedSessionAction
+
+	// EndedSessionWindow is the number of recently ended sessions that are remembered for ReusedSessionAction.
+	// Defaults to 1000
+	EndedSessionWindow int
+
+	// ChainHeadRound returns the current round of the chain head, e.g. the currentRound of the RoundsManager
+	// contract, that the creation round of tickets is compared to for MaxRoundLag
+	ChainHeadRound func() *big.Int
+
+	// MaxRoundLag is the max number of rounds that the creation round of tickets can be behind the round returned
+	// by ChainHeadRound. Creating tickets fails with ErrStaleRound if the gap is larger. If zero or if
+	// ChainHeadRound is nil, the creation round is not compared to the chain head round
+	MaxRoundLag int64
+
+	// NonceFilterSize is the size in bits of a Bloom filter of the nonces issued for each session that answers
+	// MightHaveIssued. The filter has no false negatives and its false positive rate grows with the number of
+	// issued nonces: with m bits, k hash functions and n issued nonces it is about (1 - e^(-k*n/m))^k.
+	// If zero, no filter is kept
+	NonceFilterSize int
+
+	// NonceFilterHashes is the number of hash functions of the nonce filter. Defaults to 4
+	NonceFilterHashes int
+
+	// PolicySource provides the maxEV, depositMultiplier and minEV used for validation. The limits of the
+	// source take precedence over the maxEV and depositMultiplier passed to NewSender. If nil, the limits
+	// passed to NewSender are used
+	PolicySource PolicySource
+
+	// PolicyPollInterval is the interval at which the policy is reloaded from the PolicySource once the
+	// sender is started. If zero, the policy is only loaded once by NewSender
+	PolicyPollInterval time.Duration
+
+	// TrackingMemoryBudget is the max estimated number of bytes retained by the issuance logs and the sets of
+	// recently issued nonces of all sessions together. When the budget is exceeded the oldest records and nonces
+	// across all sessions are evicted first, even if their session's IssuanceLogSize or RecentNonceWindow is
+	// not reached, so duplicate nonces are only detected within the nonces that are still tracked.
+	// The nonce filters of NonceFilterSize have a fixed size and are not charged against the budget.
+	// If zero, the memory of the tracking structures is only bounded per session
+	TrackingMemoryBudget int64
+
+	// IdempotencyWindow is the time for which the batch created for an idempotency key passed to
+	// CreateTicketBatchWithKey is returned for requests with the same key. Defaults to 1 minute
+	IdempotencyWindow time.Duration
+
+	// NonceThresholds are percentages of MaxNoncePerSession, or of the range of uint32 if no MaxNoncePerSession
+	// is configured, at which OnNonceThreshold is called, e.g. 50 and 90 to rotate sessions before they reach
+	// their nonce limit. Each percentage must be between 1 and 100
+	NonceThresholds []int
+
+	// OnNonceThreshold is called with the nonce of the first ticket of a session that crosses one of the
+	// NonceThresholds and the percentage of the threshold. Each threshold is reported at most once per session.
+	// It is called synchronously by the call that created the ticket and must not block
+	OnNonceThreshold func(sessionID string, nonce uint32, thresholdPct int)
+
+	// SessionStoreRetry decides whether and when failed Save and Load calls of the SessionStore are retried,
+	// e.g. an ExponentialBackoff, so that transient store failures do not fail ticket creation right away.
+	// If nil, failed calls are not retried
+	SessionStoreRetry RetryPolicy
+
+	// SessionStoreRetryTimeout is the max total time that a failed SessionStore call is retried for, after which
+	// the error of the last attempt is returned. Defaults to 1 second
+	SessionStoreRetryTimeout time.Duration
+
+	// MaxRecipientEV is the max total EV of the tickets created for a single recipient across all of its sessions,
+	// including sessions that have ended. Creating tickets that would exceed it fails with ErrRecipientEVLimit.
+	// If nil, the EV committed to recipients is tracked but not limited
+	MaxRecipientEV *big.Rat
 }
 
 type session struct {
+	// ticketsCreated is the number of tickets created for the session. It is the first field
+	// so that it is 64-bit aligned for atomic access on 32-bit platforms
+	ticketsCreated uint64
+
+	// startedAt is the time at which the session was started
+	startedAt time.Time
+
 	senderNonce uint32
+	// nonceMu serializes nonce updates that must be persisted before they are applied
+	nonceMu sync.Mutex
+
+	// trusted is set to 1 if ticket params validation should be skipped for the session
+	trusted uint32
+
+	// stale is set to 1 if tickets should no longer be created for the session
+	stale uint32
 
 	ticketParams TicketParams
+
+	policy SessionPolicy
+
+	// signer is the signer selected for the session by the SignerSelector that signs all of its tickets.
+	// If nil, the tickets are signed by the sender's signer
+	signer Signer
+
+	issuanceLog *issuanceLog
+
+	// builtTickets are the tickets built for the session with BuildTicket
+	builtTickets builtTickets
+
+	// validation is the session's last successful validation if ValidationCacheTTL is set
+	validation   *validationCacheEntry
+	validationMu sync.Mutex
+
+	// quotaRound is the round that quotaTickets were created in
+	quotaRound   int64
+	quotaTickets int
+	quotaMu      sync.Mutex
+
+	lastTicket   *lastTicket
+	lastTicketMu sync.Mutex
+
+	// recentNonces is the set of nonces recently issued for the session if RecentNonceWindow is set
+	recentNonces *recentNonces
+
+	// nonceFilter is the Bloom filter of the nonces issued for the session if NonceFilterSize is set
+	nonceFilter *nonceFilter
+
+	// nextNonceThreshold is the index of the next of the sender's nonce thresholds that the session did not cross
+	nextNonceThreshold uint32
+
+	// validations are the outcomes of the most recent validations of the session
+	validations validationHistory
 }
 
 type sender struct {
+	// highestRound is the highest last initialized round seen by the sender. It is the first field
+	// so that it is 64-bit aligned for atomic access on 32-bit platforms. The counters of dropped
+	// events and audits follow it for the same reason
+	highestRound  int64
+	droppedEvents uint64
+	droppedAudits uint64
+
 	signer            Signer
+	signers           []Signer
 	timeManager       TimeManager
 	senderManager     SenderManager
 	maxEV             *big.Rat
 	depositMultiplier int
 
+	cfg SenderConfig
+
+	depositCoordinator DepositCoordinator
+
+	// signingSlots limits the number of batches signed at the same time if MaxConcurrentBatches is set
+	signingSlots chan struct{}
+
+	// frozen is 1 while ticket creation is stopped with Freeze
+	frozen uint32
+
+	events chan SenderEvent
+
+	// audits is the queue of validation decisions for the AuditSink. Nil if no AuditSink is configured
+	audits chan ValidationDecision
+
+	signingLatency latencyHistogram
+
+	expirationCache expirationParamsCache
+
 	sessions sync.Map
+
+	// sessionLocks serializes starting and ending a session with ticket creation for the session
+	sessionLocks sessionLocks
+
+	// endedSessions are the recently ended sessions if a ReusedSessionAction is configured
+	endedSessions endedSessions
+
+	// policy is the Policy last loaded from the PolicySource
+	policy atomic.Value
+
+	// trackingBudget caps the memory of the tracking structures of all sessions if TrackingMemoryBudget is set
+	trackingBudget *trackingBudget
+
+	// idempotentBatches are the batches recently created with CreateTicketBatchWithKey
+	idempotentBatches idempotentBatches
+
+	// recipientEVs is the committed EV of each recipient
+	recipientEVs recipientEVs
+
+	// nonceThresholds are the NonceThresholds ordered by nonce
+	nonceThresholds []nonceThreshold
+
+	quit chan struct{}
 }
 
-// NewSender creates a new Sender instance.
-func NewSender(signer Signer, timeManager TimeManager, senderManager SenderManager, maxEV *big.Rat, depositMultiplier int) Sender {
-	return &sender{
-		signer:            signer,
-		timeManager:       timeManager,
-		senderManager:     senderManager,
-		maxEV:             maxEV,
-		depositMultiplier: depositMultiplier,
-	}
+// releaseTracking removes the memory of a session's tracking structures from the
+// TrackingMemoryBudget when the session is removed
+func (s *sender) releaseTracking(session *session) {
+	session.issuanceLog.release()
+	session.recentNonces.release()
 }
 
-func (s *sender) StartSession(ticketParams TicketParams) string {
-	sessionID := ticketParams.RecipientRandHash.Hex()
+// expirationParamsCache holds the expiration params of the last initialized round
+type expirationParamsCache struct {
+	params *TicketExpirationParams
+	// refreshAt is the time after which params are refreshed for a new round
+	refreshAt time.Time
+	mu        sync.Mutex
+}
 
-	s.sessions.Store(sessionID, &session{
-		ticketParams: ticketParams,
-		senderNonce:  0,
-	})
+// NewSender creates a new Sender instance.
+// An error is returned if maxEV or MaxRecipientEV is negative or if the policy of the configured PolicySource cannot be loaded
+// or is invalid, e.g. because its minEV is greater than its maxEV, so that a misconfigured sender that
+// would reject every ticket fails fast
+func NewSender(signer Signer, timeManager TimeManager, senderManager SenderManager, maxEV *big.Rat, depositMultiplier int, cfg SenderConfig) (Sender, error) {
+	if maxEV == nil {
+		return nil, errors.New("missing maxEV")
+	}
 
-	return sessionID
-}
+	if maxEV.Sign() < 0 {
+		return nil, fmt.Errorf("maxEV %v is negative", maxEV.FloatString(5))
+	}
 
-// EV returns the ticket EV for a session
-func (s *sender) EV(sessionID string) (*big.Rat, error) {
-	session, err := s.loadSession(sessionID)
+	if cfg.MaxRecipientEV != nil && cfg.MaxRecipientEV.Sign() < 0 {
+		return nil, fmt.Errorf("max recipient EV %v is negative", cfg.MaxRecipientEV.FloatString(5))
+	}
+
+	nonceThresholds, err := newNonceThresholds(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return ticketEV(session.ticketParams.FaceValue, session.ticketParams.WinProb), nil
-}
+	eventBufferSize := cfg.EventBufferSize
+	if eventBufferSize <= 0 {
+		eventBufferSize = defaultEventBufferSize
+	}
 
-func (s *sender) validateSender(info *SenderInfo) error {
-	maxWithdrawRound := new(big.Int).Add(s.timeManager.LastInitializedRound(), big.NewInt(1))
-	if info.WithdrawRound.Int64() != 0 && info.WithdrawRound.Cmp(maxWithdrawRound) != 1 {
-		return ErrSenderValidation{fmt.Errorf("unable to validate sender: deposit and reserve is set to unlock soon")}
+	depositCoordinator := cfg.DepositCoordinator
+	if depositCoordinator == nil {
+		depositCoordinator = localDepositCoordinator{}
 	}
 
-	if info.Reserve.FundsRemaining.Cmp(big.NewInt(0)) == 0 {
-		return ErrSenderValidation{errors.New("no sender reserve")}
+	s := &sender{
+		signer:             signer,
+		signers:            append([]Signer{signer}, cfg.Signers...),
+		timeManager:        timeManager,
+		senderManager:      senderManager,
+		maxEV:              maxEV,
+		depositMultiplier:  depositMultiplier,
+		cfg:                cfg,
+		depositCoordinator: depositCoordinator,
+		signingSlots:       newSigningSlots(cfg.MaxConcurrentBatches),
+		events:             make(chan SenderEvent, eventBufferSize),
+		audits:             newAuditQueue(cfg),
+		trackingBudget:     newTrackingBudget(cfg.TrackingMemoryBudget),
+		nonceThresholds:    nonceThresholds,
+		quit:               make(chan struct{}),
 	}
 
-	if info.Deposit.Cmp(big.NewInt(0)) == 0 {
-		return ErrSenderValidation{errors.New("no sender deposit")}
+	if err := s.reloadPolicy(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return s, nil
 }
 
-// CreateTicketBatch returns a ticket batch of the specified size
-func (s *sender) CreateTicketBatch(sessionID string, size int) (*TicketBatch, error) {
-	session, err := s.loadSession(sessionID)
+func (s *sender) StartSession(ticketParams TicketParams) string {
+	sessionID, err := s.StartSessionWithPolicy(ticketParams, SessionPolicy{})
 	if err != nil {
-		return nil, err
+		glog.Errorf("error starting session, session not started sessionID=%v err=%v", sessionID, err)
 	}
 
-	if err := s.validateTicketParams(&session.ticketParams, size); err != nil {
-		return nil, err
-	}
+	return sessionID
+}
 
-	ticketParams := &session.ticketParams
+// StartSessionWithPolicy creates a session for a given set of ticket params using a policy
+// that overrides the sender's defaults for the session
+func (s *sender) StartSessionWithPolicy(ticketParams TicketParams, policy SessionPolicy) (string, error) {
+	if policy.DepositMultiplier < 0 {
+		return s.sessionID(&ticketParams), fmt.Errorf("session deposit multiplier must be greater than 0, but %v provided", policy.DepositMultiplier)
+	}
 
-	expirationParams := ticketParams.ExpirationParams
-	// Ensure backwards compatbility
-	// If no expirationParams are included by O
-	// B sets the values based upon its last seen round
-	if expirationParams == nil || expirationParams.CreationRound == 0 || expirationParams.CreationRoundBlockHash == (ethcommon.Hash{}) {
-		expirationParams = s.expirationParams()
+	if policy.MaxTicketsPerRound < 0 {
+		return s.sessionID(&ticketParams), fmt.Errorf("session max tickets per round must be greater than 0, but %v provided", policy.MaxTicketsPerRound)
 	}
 
-	batch := &TicketBatch{
-		TicketParams:           ticketParams,
-		TicketExpirationParams: expirationParams,
-		Sender:                 s.signer.Account().Address,
+	if err := s.validateExpirationRoundOffset(policy.ExpirationRoundOffset); err != nil {
+		return s.sessionID(&ticketParams), err
 	}
 
-	for i := 0; i < size; i++ {
-		senderNonce := atomic.AddUint32(&session.senderNonce, 1)
-		ticket := NewTicket(&session.ticketParams, expirationParams, s.signer.Account().Address, senderNonce)
-		sig, err := s.signer.Sign(ticket.Hash().Bytes())
+	if s.cfg.ParamsTransform != nil {
+		transformed, err := s.cfg.ParamsTransform(ticketParams)
 		if err != nil {
-			return nil, errors.Wrapf(err, "error signing ticket for session: %v", sessionID)
+			return s.sessionID(&ticketParams), errors.Wrap(err, "error transforming ticket params")
 		}
+		ticketParams = transformed
+	}
 
-		batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: senderNonce, Sig: sig})
+	if policy.ExpirationBlock != nil {
+		if err := s.validateExpirationOverride(&ticketParams, policy.ExpirationBlock); err != nil {
+			return s.sessionID(&ticketParams), err
+		}
+		ticketParams.ExpirationBlock = policy.ExpirationBlock
 	}
 
-	return batch, nil
-}
+	sessionID := s.sessionID(&ticketParams)
+	policy.Metadata = copyMetadata(policy.Metadata)
 
-// ValidateTicketParams checks if ticket params are acceptable
-func (s *sender) ValidateTicketParams(ticketParams *TicketParams) error {
-	// Check for sending a single ticket
-	return s.validateTicketParams(ticketParams, 1)
-}
+	if err := s.checkCertainWin(sessionID, &ticketParams); err != nil {
+		return sessionID, err
+	}
 
-// validateTicketParams checks if ticket params are acceptable for a specific number of tickets
-func (s *sender) validateTicketParams(ticketParams *TicketParams, numTickets int) error {
-	info, err := s.senderManager.GetSenderInfo(s.signer.Account().Address)
+	signer, err := s.selectSigner(&ticketParams)
 	if err != nil {
-		return err
+		return sessionID, err
 	}
 
-	// validate sender
-	if err := s.validateSender(info); err != nil {
-		return err
-	}
+	unlock := s.sessionLocks.lock(sessionID)
+	defer unlock()
 
-	ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
-	totalEV := ev.Mul(ev, new(big.Rat).SetInt64(int64(numTickets)))
-	if totalEV.Cmp(s.maxEV) > 0 {
-		return fmt.Errorf("total ticket EV %v for %v tickets > max total ticket EV %v", totalEV.FloatString(5), numTickets, s.maxEV.FloatString(5))
+	var senderNonce uint32
+	if s.cfg.SessionStore != nil {
+		var nonce uint32
+		err := s.retrySessionStore(func() error {
+			var err error
+			nonce, err = s.cfg.SessionStore.Load(sessionID)
+			return err
+		})
+		if err != nil {
+			return sessionID, errors.Wrapf(err, "error loading session nonce: %v", sessionID)
+		}
+		senderNonce = nonce
 	}
 
-	maxFaceValue := new(big.Int).Div(info.Deposit, big.NewInt(int64(s.depositMultiplier)))
-	if ticketParams.FaceValue.Cmp(maxFaceValue) > 0 {
-		return fmt.Errorf("ticket faceValue %v > max faceValue %v", ticketParams.FaceValue, maxFaceValue)
+	if err := s.checkReusedSession(sessionID, senderNonce); err != nil {
+		return sessionID, err
 	}
 
-	if ticketParams.ExpirationBlock.Int64() == 0 {
-		return nil
+	if replaced, err := s.loadSession(sessionID); err == nil {
+		s.releaseTracking(replaced)
 	}
 
-	latestBlock := s.timeManager.LastSeenBlock()
-	if ticketParams.ExpirationBlock.Cmp(latestBlock) <= 0 {
-		return ErrTicketParamsExpired
-	}
+	s.sessions.Store(sessionID, &session{
+		ticketParams: ticketParams,
+		senderNonce:  senderNonce,
+		startedAt:    timeNow(),
+		policy:       policy,
+		signer:       signer,
+		issuanceLog:  newIssuanceLog(s.cfg.IssuanceLogSize, s.trackingBudget),
+		recentNonces: newRecentNonces(s.cfg.RecentNonceWindow, s.trackingBudget),
+		nonceFilter:  newNonceFilter(s.cfg.NonceFilterSize, s.cfg.NonceFilterHashes),
+	})
 
-	return nil
+	s.emit(SenderEvent{Type: SessionStarted, SessionID: sessionID})
+
+	return sessionID, nil
+}
+
+// validateExpirationOverride checks that an expiration block override has not passed
+// and does not extend the validity window of the ticket params
+func (s *sender) validateExpirationOverride(ticketParams *TicketParams, expirationBlock *big.Int) error {
+	latestBlock := s.timeManager.LastSeenBlock()
+	if expirationBlock.Cmp(latestBlock) <= 0 {
+		return errors.Wrapf(ErrTicketParamsExpired, "session expiration block %v <= latest block %v", expirationBlock, latestBlock)
+	}
+
+	if ticketParams.ExpirationBlock != nil && ticketParams.ExpirationBlock.Int64() != 0 && expirationBlock.Cmp(ticketParams.ExpirationBlock) > 0 {
+		return fmt.Errorf("session expiration block %v > ticket params expiration block %v", expirationBlock, ticketParams.ExpirationBlock)
+	}
+
+	return nil
+}
+
+// sessionID returns the session ID for a set of ticket params
+func (s *sender) sessionID(ticketParams *TicketParams) string {
+	if s.cfg.CompositeSessionID {
+		return ticketParams.Recipient.Hex() + "-" + ticketParams.RecipientRandHash.Hex()
+	}
+
+	return ticketParams.RecipientRandHash.Hex()
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+
+	return copied
+}
+
+// EndSession removes a session. Subsequent calls for the session ID will fail
+// until a new session is started with the same ticket params
+func (s *sender) EndSession(sessionID string) {
+	unlock := s.sessionLocks.lock(sessionID)
+	defer unlock()
+
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	s.sessions.Delete(sessionID)
+	s.recordEndedSession(sessionID, session)
+	s.releaseTracking(session)
+
+	s.emit(SenderEvent{Type: SessionEnded, SessionID: sessionID})
+}
+
+// EndSessionSync removes a session after persisting the session's nonce with the configured SessionStore,
+// flushing the store for the session if it implements SessionStoreFlusher. The session is not removed if
+// the nonce cannot be persisted so that ending the session can be retried
+func (s *sender) EndSessionSync(sessionID string) error {
+	unlock := s.sessionLocks.lock(sessionID)
+	defer unlock()
+
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.SessionStore != nil {
+		session.nonceMu.Lock()
+		defer session.nonceMu.Unlock()
+
+		if err := s.persistNonce(sessionID, atomic.LoadUint32(&session.senderNonce)); err != nil {
+			return err
+		}
+
+		if flusher, ok := s.cfg.SessionStore.(SessionStoreFlusher); ok {
+			if err := flusher.Flush(sessionID); err != nil {
+				return errors.Wrapf(err, "error flushing nonce for session: %v", sessionID)
+			}
+		}
+	}
+
+	s.sessions.Delete(sessionID)
+	s.recordEndedSession(sessionID, session)
+	s.releaseTracking(session)
+
+	s.emit(SenderEvent{Type: SessionEnded, SessionID: sessionID})
+
+	return nil
+}
+
+// Events returns a channel that receives events describing changes in the state of the sender.
+// Events are dropped instead of blocking the sender if the consumer does not keep up
+func (s *sender) Events() <-chan SenderEvent {
+	return s.events
+}
+
+// DroppedEvents returns the number of events that were dropped because the events channel was full
+func (s *sender) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&s.droppedEvents)
+}
+
+// EV returns the ticket EV for a session
+func (s *sender) EV(sessionID string) (*big.Rat, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ticketEV(session.ticketParams.FaceValue, session.ticketParams.WinProb), nil
+}
+
+// MaxFaceValue returns the highest face value that is currently accepted for tickets of a session.
+// It is the sender's usable deposit divided by the session's deposit multiplier, which is the ceiling
+// that the face value of the session's ticket params is validated against
+func (s *sender) MaxFaceValue(sessionID string) (*big.Int, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.sessionSenderInfo(session)
+	if err != nil {
+		return nil, SenderInfoError{err}
+	}
+
+	return s.maxFaceValue(info, s.sessionDepositMultiplier(session)), nil
+}
+
+// maxFaceValue returns the highest face value that the sender's deposit backs with a deposit multiplier
+func (s *sender) maxFaceValue(info *SenderInfo, depositMultiplier int) *big.Int {
+	return new(big.Int).Div(s.usableDeposit(info), big.NewInt(int64(depositMultiplier)))
+}
+
+// ListSessions returns information about all sessions ordered by session ID
+func (s *sender) ListSessions() []SessionInfo {
+	var infos []SessionInfo
+	s.sessions.Range(func(key, value interface{}) bool {
+		session := value.(*session)
+		infos = append(infos, SessionInfo{
+			SessionID:    key.(string),
+			SenderNonce:  atomic.LoadUint32(&session.senderNonce),
+			TicketParams: session.ticketParams,
+			Metadata:     copyMetadata(session.policy.Metadata),
+		})
+		return true
+	})
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].SessionID < infos[j].SessionID })
+
+	return infos
+}
+
+// SessionsForRecipient returns information about the sessions whose ticket params
+// are for a recipient ordered by session ID
+func (s *sender) SessionsForRecipient(recipient ethcommon.Address) []SessionInfo {
+	var infos []SessionInfo
+	for _, info := range s.ListSessions() {
+		if info.TicketParams.Recipient == recipient {
+			infos = append(infos, info)
+		}
+	}
+
+	return infos
+}
+
+// SnapshotNonces returns the current nonce of every session keyed by session ID.
+// Each session's nonce is read atomically so every value is a nonce that the session actually had
+// and is at least as recent as any nonce handed out before SnapshotNonces was called. Sessions are read
+// one after another while tickets may still be created, so the snapshot is not an instantaneous view
+// of all sessions and sessions started or ended during the call may or may not be included
+func (s *sender) SnapshotNonces() map[string]uint32 {
+	nonces := make(map[string]uint32)
+	s.sessions.Range(func(key, value interface{}) bool {
+		nonces[key.(string)] = atomic.LoadUint32(&value.(*session).senderNonce)
+		return true
+	})
+
+	return nonces
+}
+
+// AdvanceNonce sets the nonce of a session to max(current nonce, to) so that the next
+// ticket created for the session uses a nonce greater than to. The nonce never moves backwards
+func (s *sender) AdvanceNonce(sessionID string, to uint32) error {
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.nonceMu.Lock()
+	defer session.nonceMu.Unlock()
+
+	if to <= atomic.LoadUint32(&session.senderNonce) {
+		return nil
+	}
+
+	if s.cfg.StrictSequential {
+		return ErrNonceGap
+	}
+
+	if s.cfg.StrictPersistence {
+		if err := s.persistNonce(sessionID, to); err != nil {
+			return err
+		}
+	}
+
+	for {
+		current := atomic.LoadUint32(&session.senderNonce)
+		if to <= current {
+			break
+		}
+
+		if atomic.CompareAndSwapUint32(&session.senderNonce, current, to) {
+			break
+		}
+	}
+
+	if !s.cfg.StrictPersistence {
+		if err := s.persistNonce(sessionID, to); err != nil {
+			glog.Errorf("error persisting session nonce sessionID=%v nonce=%v err=%v", sessionID, to, err)
+		}
+	}
+
+	return nil
+}
+
+// MarkTrusted disables ticket params validation when creating tickets for a session.
+// Tickets created for a trusted session are still stamped with a unique nonce and valid
+// expiration params, but are not checked against the sender's deposit, reserve or maxEV
+// so this should only be used for trusted recipients or testing
+func (s *sender) MarkTrusted(sessionID string) error {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreUint32(&session.trusted, 1)
+
+	glog.Warningf("Ticket params validation disabled for trusted session sessionID=%v recipient=%x faceValue=%v winProb=%v", sessionID, session.ticketParams.Recipient, session.ticketParams.FaceValue, WinProbFloat(session.ticketParams.WinProb))
+
+	return nil
+}
+
+// MarkSessionStale flags a session as obsolete, e.g. because the recipient advertised new ticket
+// params. Creating tickets for a stale session returns ErrSessionStale so that the caller starts a
+// new session with the recipient's new ticket params. Starting a session with the same ID replaces
+// the stale session
+func (s *sender) MarkSessionStale(sessionID string) error {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreUint32(&session.stale, 1)
+
+	return nil
+}
+
+// IssuanceLog returns the most recent tickets created for a session ordered from oldest to newest.
+// The number of records kept for each session is bounded by the configured IssuanceLogSize
+func (s *sender) IssuanceLog(sessionID string) ([]IssuanceRecord, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.issuanceLog.list(), nil
+}
+
+// VerifyTicket checks if the params, sender and expiration params of a ticket, which may have been
+// constructed externally, match the ticket that the sender would create for a session.
+// If there are any mismatches, the returned error describes all of them
+func (s *sender) VerifyTicket(sessionID string, ticket *Ticket) error {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	expirationParams, err := s.sessionExpirationParams(session)
+	if err != nil {
+		return err
+	}
+
+	params := &session.ticketParams
+	sender := s.sessionSigner(session).Account().Address
+
+	var mismatches []string
+	if !bigIntEqual(ticket.FaceValue, params.FaceValue) {
+		mismatches = append(mismatches, fmt.Sprintf("faceValue %v != %v", ticket.FaceValue, params.FaceValue))
+	}
+	if !bigIntEqual(ticket.WinProb, params.WinProb) {
+		mismatches = append(mismatches, fmt.Sprintf("winProb %v != %v", ticket.WinProb, params.WinProb))
+	}
+	if ticket.RecipientRandHash != params.RecipientRandHash {
+		mismatches = append(mismatches, fmt.Sprintf("recipientRandHash %x != %x", ticket.RecipientRandHash, params.RecipientRandHash))
+	}
+	if ticket.Recipient != params.Recipient {
+		mismatches = append(mismatches, fmt.Sprintf("recipient %x != %x", ticket.Recipient, params.Recipient))
+	}
+	if ticket.Sender != sender {
+		mismatches = append(mismatches, fmt.Sprintf("sender %x != %x", ticket.Sender, sender))
+	}
+	if ticket.CreationRound != expirationParams.CreationRound {
+		mismatches = append(mismatches, fmt.Sprintf("creationRound %v != %v", ticket.CreationRound, expirationParams.CreationRound))
+	}
+	if ticket.CreationRoundBlockHash != expirationParams.CreationRoundBlockHash {
+		mismatches = append(mismatches, fmt.Sprintf("creationRoundBlockHash %x != %x", ticket.CreationRoundBlockHash, expirationParams.CreationRoundBlockHash))
+	}
+
+	if len(mismatches) > 0 {
+		return errors.Errorf("ticket does not match session %v: %v", sessionID, strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
+
+// CreateBatchSplitByRound returns ticket batches for a contiguous sequence of size nonces.
+// The expiration params are re-read before signing each ticket and a new batch is started whenever
+// they change, so each batch is stamped with the expiration params of the round its tickets were created in
+func (s *sender) CreateBatchSplitByRound(sessionID string, size int) ([]*TicketBatch, error) {
+	if size < 1 {
+		return nil, ErrEmptyBatch
+	}
+
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, reservation, err := s.reserveTickets(sessionID, size, reserveOpts{})
+	if err != nil {
+		return nil, err
+	}
+	defer reservation.release()
+
+	var (
+		batches []*TicketBatch
+		issued  []issuedTicket
+	)
+	err = s.issueNonces(sessionID, session, size, func(firstNonce uint32) error {
+		var batch *TicketBatch
+		for i := 0; i < size; i++ {
+			expirationParams, err := s.issuableExpirationParams(session)
+			if err != nil {
+				return err
+			}
+
+			if batch == nil || !expirationParamsEqual(batch.TicketExpirationParams, expirationParams) {
+				batch = &TicketBatch{
+					TicketParams:           &session.ticketParams,
+					TicketExpirationParams: expirationParams,
+					Sender:                 s.sessionSigner(session).Account().Address,
+				}
+				batches = append(batches, batch)
+			}
+
+			senderNonce := firstNonce + uint32(i)
+			ticket, sig, err := s.signTicket(sessionID, session, expirationParams, senderNonce)
+			if err != nil {
+				return err
+			}
+
+			issued = append(issued, ticket)
+			batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: senderNonce, Sig: sig})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.commit()
+	s.recordIssuedTickets(sessionID, session, issued)
+
+	for _, batch := range batches {
+		s.observeBatch(sessionID, batch)
+	}
+	s.recordLastBatchTicket(session, batches[len(batches)-1])
+
+	return batches, nil
+}
+
+func (s *sender) validateSender(info *SenderInfo) error {
+	if s.cfg.RejectFrozenSender && info.Status == SenderStatusFrozen {
+		return ErrSenderFrozen
+	}
+
+	if err := s.validateWithdrawal(info); err != nil {
+		return err
+	}
+
+	maxWithdrawRound := new(big.Int).Add(s.timeManager.LastInitializedRound(), big.NewInt(1))
+	if info.WithdrawRound.Int64() != 0 && info.WithdrawRound.Cmp(maxWithdrawRound) != 1 {
+		return ErrSenderValidation{fmt.Errorf("unable to validate sender: deposit and reserve is set to unlock soon")}
+	}
+
+	if info.Reserve.FundsRemaining.Cmp(big.NewInt(0)) == 0 {
+		return ErrSenderValidation{errors.New("no sender reserve")}
+	}
+
+	if info.Deposit.Cmp(big.NewInt(0)) == 0 {
+		return ErrSenderValidation{errors.New("no sender deposit")}
+	}
+
+	return nil
+}
+
+// CreateTicketBatch returns a ticket batch of the specified size.
+// The only state shared between concurrent calls is the per-session nonce which is incremented
+// atomically, so calls for different sessions proceed independently of each other.
+// If StrictSequential is enabled, calls for the same session are serialized.
+// Starting or ending a session waits for in-flight calls for the session to return
+func (s *sender) CreateTicketBatch(sessionID string, size int) (*TicketBatch, error) {
+	return s.CreateTicketBatchCtx(context.Background(), sessionID, size)
+}
+
+// CreateTicketBatchCtx returns a ticket batch of the specified size like CreateTicketBatch and stops signing
+// the tickets of the batch once the context is done, returning ctx.Err(). The nonces reserved for a cancelled
+// batch, including the nonces of tickets that were already signed, are given back to the session unless
+// nonces were allocated for the session after they were reserved, so that cancelling a batch does not leave
+// a gap in the session's nonces
+func (s *sender) CreateTicketBatchCtx(ctx context.Context, sessionID string, size int) (*TicketBatch, error) {
+	return s.createTicketBatch(ctx, sessionID, size, s.issueNonces)
+}
+
+// nonceIssuer allocates numTickets consecutive nonces for a session and calls sign with the first nonce
+type nonceIssuer func(sessionID string, session *session, numTickets int, sign func(firstNonce uint32) error) error
+
+// createTicketBatch returns a ticket batch of the specified size with nonces allocated by issue
+func (s *sender) createTicketBatch(ctx context.Context, sessionID string, size int, issue nonceIssuer) (*TicketBatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if size < 1 {
+		return nil, ErrEmptyBatch
+	}
+
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	// The reservation waits for a signing slot before the expiration params are fetched so they are fresh
+	// when the batch is signed
+	session, reservation, err := s.reserveTickets(sessionID, size, reserveOpts{})
+	if err != nil {
+		return nil, err
+	}
+	defer reservation.release()
+
+	ticketParams := &session.ticketParams
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &TicketBatch{
+		TicketParams:           ticketParams,
+		TicketExpirationParams: expirationParams,
+		Sender:                 s.sessionSigner(session).Account().Address,
+		SenderParams:           make([]*TicketSenderParams, 0, size),
+	}
+
+	// The sender params of the batch are allocated at once instead of once per ticket
+	senderParams := make([]TicketSenderParams, size)
+	issued := make([]issuedTicket, 0, size)
+	err = issue(sessionID, session, size, func(firstNonce uint32) error {
+		for i := 0; i < size; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := s.revalidateSession(sessionID, session, i, size); err != nil {
+				return err
+			}
+
+			senderNonce := firstNonce + uint32(i)
+			ticket, sig, err := s.signTicket(sessionID, session, expirationParams, senderNonce)
+			if err != nil {
+				return err
+			}
+
+			issued = append(issued, ticket)
+			senderParams[i] = TicketSenderParams{SenderNonce: senderNonce, Sig: sig}
+			batch.SenderParams = append(batch.SenderParams, &senderParams[i])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.commit()
+	s.recordIssuedTickets(sessionID, session, issued)
+
+	s.observeBatch(sessionID, batch)
+	s.recordLastBatchTicket(session, batch)
+
+	return batch, nil
+}
+
+// RefreshBatch re-signs the tickets of a batch created for a session using the session's current
+// expiration params. The refreshed batch uses the same nonces as the old batch so no new nonces
+// are allocated for the session. Only nonces that were already allocated for the session can be
+// refreshed and each nonce can only appear once in the batch. Refreshed tickets are not recorded
+// in the session's issuance log because they replace tickets that were already issued
+func (s *sender) RefreshBatch(sessionID string, oldBatch *TicketBatch) (*TicketBatch, error) {
+	return s.RefreshBatchExcept(sessionID, oldBatch, nil)
+}
+
+// RefreshBatchExcept re-signs the tickets of a batch created for a session like RefreshBatch except for the
+// tickets with the nonces in skipNonces, e.g. because they were already delivered or redeemed. The skipped
+// nonces are absent from the refreshed batch. ErrEmptyBatch is returned if every ticket of the batch is skipped
+func (s *sender) RefreshBatchExcept(sessionID string, oldBatch *TicketBatch, skipNonces []uint32) (*TicketBatch, error) {
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, err := s.loadIssuableSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := s.sessionSigner(session)
+	if oldBatch.RecipientRandHash != session.ticketParams.RecipientRandHash || oldBatch.Sender != signer.Account().Address {
+		return nil, errors.Errorf("batch was not created for session: %v", sessionID)
+	}
+
+	if err := checkRefreshNonces(oldBatch.SenderParams, atomic.LoadUint32(&session.senderNonce)); err != nil {
+		return nil, errors.Wrapf(err, "batch was not created for session: %v", sessionID)
+	}
+
+	refresh := skipSenderParams(oldBatch.SenderParams, skipNonces)
+	if len(refresh) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	if err := s.validateSession(sessionID, session, len(refresh)); err != nil {
+		return nil, err
+	}
+
+	release := s.acquireSigningSlot(len(refresh))
+	defer release()
+
+	expirationParams, err := s.issuableExpirationParams(session)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &TicketBatch{
+		TicketParams:           &session.ticketParams,
+		TicketExpirationParams: expirationParams,
+		Sender:                 signer.Account().Address,
+		SenderParams:           make([]*TicketSenderParams, 0, len(refresh)),
+	}
+
+	for _, senderParams := range refresh {
+		ticket := NewTicket(&session.ticketParams, expirationParams, signer.Account().Address, senderParams.SenderNonce)
+		sig, err := s.sign(signer, s.ticketHash(ticket).Bytes())
+		if err != nil {
+			return nil, SignerError{errors.Wrapf(err, "error signing refreshed ticket for session: %v", sessionID)}
+		}
+
+		batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: senderParams.SenderNonce, Sig: sig})
+	}
+
+	return batch, nil
+}
+
+// skipSenderParams returns the sender params whose nonces are not in skipNonces
+func skipSenderParams(senderParams []*TicketSenderParams, skipNonces []uint32) []*TicketSenderParams {
+	if len(skipNonces) == 0 {
+		return senderParams
+	}
+
+	skip := make(map[uint32]bool, len(skipNonces))
+	for _, nonce := range skipNonces {
+		skip[nonce] = true
+	}
+
+	var kept []*TicketSenderParams
+	for _, params := range senderParams {
+		if !skip[params.SenderNonce] {
+			kept = append(kept, params)
+		}
+	}
+
+	return kept
+}
+
+// checkRefreshNonces returns an error if a nonce of a batch to refresh appears more than once
+// or was not allocated for a session with the current nonce
+func checkRefreshNonces(senderParams []*TicketSenderParams, current uint32) error {
+	seen := make(map[uint32]bool, len(senderParams))
+	for _, params := range senderParams {
+		nonce := params.SenderNonce
+		if nonce == 0 || nonce > current {
+			return fmt.Errorf("nonce %v was not issued, current nonce %v", nonce, current)
+		}
+
+		if seen[nonce] {
+			return fmt.Errorf("duplicate nonce %v", nonce)
+		}
+		seen[nonce] = true
+	}
+
+	return nil
+}
+
+// PeekTicket returns a signed sample ticket for a session that can be shown to a recipient
+// during protocol negotiation. The ticket uses nonce 0, which is never used by normal ticket
+// creation, and a face value of 0 so it is not redeemable for any value if it is leaked.
+// Creating a peek ticket does not affect the session's nonce
+func (s *sender) PeekTicket(sessionID string) (*Ticket, []byte, error) {
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expirationParams, err := s.sessionExpirationParams(session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ticket := NewTicket(&session.ticketParams, expirationParams, s.sessionSigner(session).Account().Address, 0)
+	ticket.FaceValue = big.NewInt(0)
+
+	sig, err := s.sign(s.sessionSigner(session), s.ticketHash(ticket).Bytes())
+	if err != nil {
+		return nil, nil, SignerError{errors.Wrapf(err, "error signing peek ticket for session: %v", sessionID)}
+	}
+
+	return ticket, sig, nil
+}
+
+// CreateTicketAtRound creates a signed ticket for a session with expiration params for the provided
+// round and block hash instead of those of the last initialized round. This is an advanced API meant for
+// deterministic tests and for replaying historical tickets: the round is not checked against the
+// TimeManager, bypasses the expiration params cache and round regression check and does not count
+// towards the session's round quota. The session's ticket params are validated and a nonce is allocated
+// for the ticket as for CreateTicketBatch
+func (s *sender) CreateTicketAtRound(sessionID string, round int64, blockHash [32]byte) (*Ticket, []byte, error) {
+	unlock := s.sessionLocks.rlock(sessionID)
+	defer unlock()
+
+	session, reservation, err := s.reserveTickets(sessionID, 1, reserveOpts{skipQuota: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reservation.release()
+
+	expirationParams := &TicketExpirationParams{
+		CreationRound:          round,
+		CreationRoundBlockHash: ethcommon.BytesToHash(blockHash[:]),
+	}
+
+	var (
+		issued issuedTicket
+		sig    []byte
+	)
+	err = s.issueNonces(sessionID, session, 1, func(senderNonce uint32) error {
+		var err error
+		issued, sig, err = s.signTicket(sessionID, session, expirationParams, senderNonce)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reservation.commit()
+	s.recordIssuedTicket(sessionID, session, issued.ticket, issued.hash)
+	s.recordLastTicket(session, issued.ticket, sig)
+
+	return issued.ticket, sig, nil
+}
+
+// validateSession checks if the ticket params of a session are acceptable for a specific
+// number of tickets unless the session is trusted
+func (s *sender) validateSession(sessionID string, session *session, numTickets int) error {
+	if atomic.LoadUint32(&session.trusted) == 1 {
+		return nil
+	}
+
+	err := s.validateUntrustedSession(sessionID, session, numTickets)
+	session.validations.record(err == nil)
+
+	return err
+}
+
+// validateUntrustedSession checks if the ticket params of a session that is not trusted
+// are acceptable for a specific number of tickets
+func (s *sender) validateUntrustedSession(sessionID string, session *session, numTickets int) error {
+	// The sender info is not fetched if the session has a cached successful validation
+	info := s.cachedSenderInfo(session, numTickets)
+	cached := info != nil
+	if !cached {
+		var err error
+		info, err = s.sessionSenderInfo(session)
+		if err != nil {
+			s.emit(SenderEvent{Type: ValidationFailed, SessionID: sessionID, Err: err})
+			return SenderInfoError{err}
+		}
+	}
+
+	if err := s.validateSessionParams(session, numTickets, info, cached); err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, SessionID: sessionID, Err: err})
+		return ValidationError{err}
+	}
+
+	if err := s.reserveDeposit(&session.ticketParams, numTickets); err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, SessionID: sessionID, Err: err})
+		return ValidationError{err}
+	}
+
+	return nil
+}
+
+// validateSessionParams checks if a session's ticket params are acceptable for a specific number
+// of tickets. If info is the sender info of the session's cached successful validation, only the
+// sender and the expiration of the ticket params are checked because the other checks only depend
+// on the ticket params and the sender info
+func (s *sender) validateSessionParams(session *session, numTickets int, info *SenderInfo, cached bool) error {
+	if cached {
+		if err := s.validateSender(info); err != nil {
+			return err
+		}
+
+		return s.validateParamsExpiration(&session.ticketParams)
+	}
+
+	if err := s.validateTicketParamsWithInfo(&session.ticketParams, numTickets, s.sessionDepositMultiplier(session), info); err != nil {
+		return err
+	}
+
+	s.cacheValidation(session, numTickets, info)
+
+	return nil
+}
+
+// issuedTicket is a ticket signed for a session that is recorded with recordIssuedTickets
+// once all tickets of its batch were signed
+type issuedTicket struct {
+	ticket *Ticket
+	hash   ethcommon.Hash
+}
+
+// signTicket creates and signs a ticket for a session with the provided expiration params and nonce.
+// The ticket is not recorded in the session's issuance log so that the tickets of a batch that fails
+// to be created are never recorded
+func (s *sender) signTicket(sessionID string, session *session, expirationParams *TicketExpirationParams, senderNonce uint32) (issuedTicket, []byte, error) {
+	ticket := NewTicket(&session.ticketParams, expirationParams, s.sessionSigner(session).Account().Address, senderNonce)
+	hash := s.ticketHash(ticket)
+	sig, err := s.sign(s.sessionSigner(session), hash.Bytes())
+	if err != nil {
+		return issuedTicket{}, nil, SignerError{errors.Wrapf(err, "error signing ticket for session: %v", sessionID)}
+	}
+
+	return issuedTicket{ticket: ticket, hash: hash}, sig, nil
+}
+
+// TicketHash returns the hash of a ticket that is signed by the sender
+func (s *sender) TicketHash(ticket *Ticket) ethcommon.Hash {
+	return s.ticketHash(ticket)
+}
+
+// ticketHash returns the hash of a ticket using the configured HashFunc or Ticket.Hash if none is configured
+func (s *sender) ticketHash(ticket *Ticket) ethcommon.Hash {
+	if s.cfg.HashFunc != nil {
+		return s.cfg.HashFunc(ticket)
+	}
+
+	return ticket.Hash()
+}
+
+// recordIssuedTickets records the signed tickets of a batch that was created for a session
+func (s *sender) recordIssuedTickets(sessionID string, session *session, issued []issuedTicket) {
+	for _, t := range issued {
+		s.recordIssuedTicket(sessionID, session, t.ticket, t.hash)
+	}
+}
+
+// recordIssuedTicket records a signed ticket in the session's issuance log, emits a TicketCreated event and
+// reports the nonce thresholds crossed by the ticket
+func (s *sender) recordIssuedTicket(sessionID string, session *session, ticket *Ticket, hash ethcommon.Hash) {
+	session.issuanceLog.append(IssuanceRecord{
+		SenderNonce: ticket.SenderNonce,
+		FaceValue:   ticket.FaceValue,
+		Timestamp:   timeNow(),
+		Hash:        hash,
+	})
+
+	s.emit(SenderEvent{Type: TicketCreated, SessionID: sessionID, SenderNonce: ticket.SenderNonce})
+	s.checkNonceThresholds(sessionID, session, ticket.SenderNonce)
+}
+
+// SigningStats returns the latency percentiles of the signer's Sign calls
+func (s *sender) SigningStats() SigningStats {
+	return s.signingLatency.stats()
+}
+
+// sign signs a message with a signer of the sender and records the latency of the call
+func (s *sender) sign(signer Signer, msg []byte) ([]byte, error) {
+	start := timeNow()
+	sig, err := s.safeSign(signer, msg)
+	latency := timeNow().Sub(start)
+	s.signingLatency.observe(latency)
+	s.observeSign(latency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.formatSignature(sig)
+}
+
+// safeSign signs a message with a signer and returns ErrSignerPanic if the signer panics
+func (s *sender) safeSign(signer Signer, msg []byte) (sig []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Errorf("Recovered from signer panic err=%v stack=%s", r, debug.Stack())
+			sig, err = nil, errors.Wrapf(ErrSignerPanic, "%v", r)
+		}
+	}()
+
+	return signer.Sign(msg)
+}
+
+// ValidateTicketParams checks if ticket params are acceptable
+func (s *sender) ValidateTicketParams(ticketParams *TicketParams) error {
+	info, err := s.getSenderInfo()
+	if err == nil {
+		// Check for sending a single ticket
+		err = s.validateTicketParamsWithInfo(ticketParams, 1, s.policyDepositMultiplier(), info)
+	}
+
+	s.audit(ticketParams, info, err)
+
+	if err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, Err: err})
+		return err
+	}
+
+	return nil
+}
+
+// ValidateParamsBatch checks if each of a list of ticket params is acceptable, e.g. to rank the params
+// advertised by multiple recipients, and returns an error or nil for each entry in the list. The sender
+// info is fetched once for the whole list and the entries are validated concurrently. If the sender info
+// cannot be fetched, the error is returned for every entry
+func (s *sender) ValidateParamsBatch(paramsList []TicketParams) []error {
+	errs := make([]error, len(paramsList))
+	if len(paramsList) == 0 {
+		return errs
+	}
+
+	info, err := s.getSenderInfo()
+	if err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, Err: err})
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	var wg sync.WaitGroup
+	for i := range paramsList {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Check for sending a single ticket
+			if err := s.validateTicketParamsWithInfo(&paramsList[i], 1, s.policyDepositMultiplier(), info); err != nil {
+				s.emit(SenderEvent{Type: ValidationFailed, Err: err})
+				errs[i] = err
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// ValidateTicketParamsCtx checks if ticket params are acceptable and returns ctx.Err() if the context
+// is done before the sender info is fetched. The SenderManager does not accept a context so a GetSenderInfo
+// call that is abandoned because of the context keeps running in the background until it returns
+func (s *sender) ValidateTicketParamsCtx(ctx context.Context, ticketParams *TicketParams) error {
+	err := func() error {
+		info, err := s.getSenderInfoCtx(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Check for sending a single ticket
+		return s.validateTicketParamsWithInfo(ticketParams, 1, s.policyDepositMultiplier(), info)
+	}()
+	if err != nil {
+		s.emit(SenderEvent{Type: ValidationFailed, Err: err})
+		return err
+	}
+
+	return nil
+}
+
+// getSenderInfoCtx fetches the sender info and returns ctx.Err() if the context is done first
+func (s *sender) getSenderInfoCtx(ctx context.Context) (*SenderInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		info *SenderInfo
+		err  error
+	}
+
+	resCh := make(chan result, 1)
+	go func() {
+		info, err := s.getSenderInfo()
+		resCh <- result{info, err}
+	}()
+
+	select {
+	case res := <-resCh:
+		return res.info, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// validateTicketParamsWithInfo checks if ticket params are acceptable for a specific number of tickets
+// using the provided sender info
+func (s *sender) validateTicketParamsWithInfo(ticketParams *TicketParams, numTickets int, depositMultiplier int, info *SenderInfo) error {
+	// Params come from untrusted recipients so check that the values can be used before doing any math with them
+	if err := validateParamsValues(ticketParams); err != nil {
+		return err
+	}
+
+	// validate sender
+	if err := s.validateSender(info); err != nil {
+		return err
+	}
+
+	if err := s.validateCertainWin(ticketParams); err != nil {
+		return err
+	}
+
+	if err := s.validateSubWeiEV(ticketParams); err != nil {
+		return err
+	}
+
+	if err := s.validateMinEV(ticketParams); err != nil {
+		return err
+	}
+
+	if err := s.validateEV(ticketParams, numTickets, info); err != nil {
+		return err
+	}
+
+	maxFaceValue := s.maxFaceValue(info, depositMultiplier)
+	if ticketParams.FaceValue.Cmp(maxFaceValue) > 0 {
+		return fmt.Errorf("ticket faceValue %v > max faceValue %v", ticketParams.FaceValue, maxFaceValue)
+	}
+
+	if err := s.validateParamsExpiration(ticketParams); err != nil {
+		return err
+	}
+
+	return s.validateStagedPolicy(ticketParams, numTickets, info)
+}
+
+// validateParamsValues checks that ticket params can be encoded in a ticket, i.e. FaceValue and WinProb
+// are set and fit in a uint256. A nil ExpirationBlock is treated as no expiration
+func validateParamsValues(ticketParams *TicketParams) error {
+	if ticketParams == nil {
+		return errors.Wrap(ErrInvalidTicketParams, "missing ticket params")
+	}
+
+	if err := validateUint256("faceValue", ticketParams.FaceValue); err != nil {
+		return err
+	}
+
+	if err := validateUint256("winProb", ticketParams.WinProb); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateUint256 checks that a ticket param value is set and in the range of a uint256
+func validateUint256(name string, x *big.Int) error {
+	if x == nil {
+		return errors.Wrapf(ErrInvalidTicketParams, "missing %v", name)
+	}
+
+	if x.Sign() < 0 {
+		return errors.Wrapf(ErrInvalidTicketParams, "%v %v is negative", name, x)
+	}
+
+	if x.BitLen() > uint256Size*8 {
+		return errors.Wrapf(ErrInvalidTicketParams, "%v %v does not fit in a uint256", name, x)
+	}
+
+	return nil
+}
+
+// validateParamsExpiration checks that the expiration block of ticket params has not passed
+func (s *sender) validateParamsExpiration(ticketParams *TicketParams) error {
+	if ticketParams.ExpirationBlock == nil || ticketParams.ExpirationBlock.Sign() == 0 {
+		return nil
+	}
+
+	latestBlock := s.timeManager.LastSeenBlock()
+	if ticketParams.ExpirationBlock.Cmp(latestBlock) <= 0 {
+		return ErrTicketParamsExpired
+	}
+
+	return nil
+}
+
+// validateEV checks the total EV of numTickets tickets using the configured EVPolicy
+// or against maxEV if no policy is configured
+func (s *sender) validateEV(ticketParams *TicketParams, numTickets int, info *SenderInfo) error {
+	if s.cfg.EVPolicy != nil {
+		return s.cfg.EVPolicy(ticketParams, numTickets, *info)
+	}
+
+	maxEV := s.policyMaxEV()
+	if cmpTicketEV(ticketParams.FaceValue, ticketParams.WinProb, numTickets, maxEV) > 0 {
+		ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+		totalEV := ev.Mul(ev, new(big.Rat).SetInt64(int64(numTickets)))
+		return fmt.Errorf("total ticket EV %v for %v tickets > max total ticket EV %v", totalEV.FloatString(5), numTickets, maxEV.FloatString(5))
+	}
+
+	return nil
+}
+
+// oneWei is the min ticket EV if RejectSubWeiEV is enabled
+var oneWei = big.NewRat(1, 1)
+
+// validateSubWeiEV returns ErrSubWeiEV for ticket params with an EV of less than one wei if RejectSubWeiEV is enabled
+func (s *sender) validateSubWeiEV(ticketParams *TicketParams) error {
+	if !s.cfg.RejectSubWeiEV {
+		return nil
+	}
+
+	if cmpTicketEV(ticketParams.FaceValue, ticketParams.WinProb, 1, oneWei) < 0 {
+		ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+		return errors.Wrapf(ErrSubWeiEV, "ticket EV %v", ev.FloatString(5))
+	}
+
+	return nil
+}
+
+// sessionDepositMultiplier returns the deposit multiplier to use for a session
+func (s *sender) sessionDepositMultiplier(session *session) int {
+	if session.policy.DepositMultiplier > 0 {
+		return session.policy.DepositMultiplier
+	}
+
+	return s.policyDepositMultiplier()
+}
+
+// issueNonces allocates numTickets consecutive nonces for a session and calls sign with the first nonce.
+// If StrictSequential is enabled, the session's nonce is locked while sign runs and is only advanced
+// if sign succeeds so that a failed batch does not leave a gap in the session's nonces.
+// If RecentNonceWindow is set, ErrDuplicateNonce is returned without calling sign if any of the
+// nonces was recently issued for the session.
+// ErrSessionNonceLimit is returned without consuming any nonces if MaxNoncePerSession would be exceeded
+func (s *sender) issueNonces(sessionID string, session *session, numTickets int, sign func(firstNonce uint32) error) error {
+	if !s.cfg.StrictSequential {
+		lastNonce, err := s.reserveNonces(sessionID, session, numTickets)
+		if err != nil {
+			return err
+		}
+
+		firstNonce := lastNonce - uint32(numTickets) + 1
+		err = session.recentNonces.check(firstNonce, numTickets)
+		if err == nil {
+			err = sign(firstNonce)
+		}
+		if err != nil {
+			// Give the nonces back unless nonces were allocated for the session since they were reserved
+			atomic.CompareAndSwapUint32(&session.senderNonce, lastNonce, lastNonce-uint32(numTickets))
+			return err
+		}
+
+		session.recentNonces.add(firstNonce, numTickets)
+		session.nonceFilter.add(firstNonce, numTickets)
+		atomic.AddUint64(&session.ticketsCreated, uint64(numTickets))
+
+		return nil
+	}
+
+	session.nonceMu.Lock()
+	defer session.nonceMu.Unlock()
+
+	current := atomic.LoadUint32(&session.senderNonce)
+	if err := s.checkNonceLimit(current, numTickets); err != nil {
+		return err
+	}
+
+	if err := session.recentNonces.check(current+1, numTickets); err != nil {
+		return err
+	}
+
+	if err := sign(current + 1); err != nil {
+		return err
+	}
+
+	lastNonce := current + uint32(numTickets)
+	if err := s.persistNonce(sessionID, lastNonce); err != nil {
+		if s.cfg.StrictPersistence {
+			return err
+		}
+		glog.Errorf("error persisting session nonce sessionID=%v nonce=%v err=%v", sessionID, lastNonce, err)
+	}
+
+	atomic.StoreUint32(&session.senderNonce, lastNonce)
+	session.recentNonces.add(current+1, numTickets)
+	session.nonceFilter.add(current+1, numTickets)
+	atomic.AddUint64(&session.ticketsCreated, uint64(numTickets))
+
+	return nil
+}
+
+// reserveNonces advances the nonce of a session by numTickets and returns the last reserved nonce.
+// If StrictPersistence is enabled, the nonce is only advanced after it was persisted
+func (s *sender) reserveNonces(sessionID string, session *session, numTickets int) (uint32, error) {
+	if !s.cfg.StrictPersistence {
+		lastNonce, err := s.addNonces(session, numTickets)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := s.persistNonce(sessionID, lastNonce); err != nil {
+			glog.Errorf("error persisting session nonce sessionID=%v nonce=%v err=%v", sessionID, lastNonce, err)
+		}
+		return lastNonce, nil
+	}
+
+	session.nonceMu.Lock()
+	defer session.nonceMu.Unlock()
+
+	current := atomic.LoadUint32(&session.senderNonce)
+	if err := s.checkNonceLimit(current, numTickets); err != nil {
+		return 0, err
+	}
+
+	lastNonce := current + uint32(numTickets)
+	if err := s.persistNonce(sessionID, lastNonce); err != nil {
+		return 0, err
+	}
+
+	atomic.StoreUint32(&session.senderNonce, lastNonce)
+
+	return lastNonce, nil
+}
+
+// addNonces atomically advances the nonce of a session by numTickets and returns the new nonce.
+// ErrSessionNonceLimit is returned without advancing the nonce if MaxNoncePerSession would be exceeded
+func (s *sender) addNonces(session *session, numTickets int) (uint32, error) {
+	if s.cfg.MaxNoncePerSession == 0 {
+		return atomic.AddUint32(&session.senderNonce, uint32(numTickets)), nil
+	}
+
+	for {
+		current := atomic.LoadUint32(&session.senderNonce)
+		if err := s.checkNonceLimit(current, numTickets); err != nil {
+			return 0, err
+		}
+
+		if atomic.CompareAndSwapUint32(&session.senderNonce, current, current+uint32(numTickets)) {
+			return current + uint32(numTickets), nil
+		}
+	}
+}
+
+// checkNonceLimit returns ErrSessionNonceLimit if creating numTickets tickets for a session with
+// the current nonce would exceed MaxNoncePerSession
+func (s *sender) checkNonceLimit(current uint32, numTickets int) error {
+	if s.cfg.MaxNoncePerSession == 0 {
+		return nil
+	}
+
+	if uint64(current)+uint64(numTickets) > uint64(s.cfg.MaxNoncePerSession) {
+		return errors.Wrapf(ErrSessionNonceLimit, "nonce %v + %v tickets > max nonce %v", current, numTickets, s.cfg.MaxNoncePerSession)
+	}
+
+	return nil
+}
+
+func (s *sender) persistNonce(sessionID string, senderNonce uint32) error {
+	if s.cfg.SessionStore == nil {
+		return nil
+	}
+
+	err := s.retrySessionStore(func() error {
+		return s.cfg.SessionStore.Save(sessionID, senderNonce)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error persisting nonce for session: %v", sessionID)
+	}
+
+	return nil
+}
+
+// reserveRoundQuota reserves numTickets from the session's ticket quota for the current round
+// and returns ErrRoundQuotaExhausted if the quota would be exceeded. The quota is reset when
+// the TimeManager reports a new last initialized round.
+// The reserved tickets are given back to the quota by release unless keep is called first,
+// so callers should defer release and call keep once the tickets were created
+func (s *sender) reserveRoundQuota(session *session, numTickets int) (*quotaReservation, error) {
+	maxTickets := s.cfg.MaxTicketsPerRound
+	if session.policy.MaxTicketsPerRound > 0 {
+		maxTickets = session.policy.MaxTicketsPerRound
+	}
+
+	if maxTickets <= 0 {
+		return nil, nil
+	}
+
+	round := s.timeManager.LastInitializedRound().Int64()
+
+	session.quotaMu.Lock()
+	defer session.quotaMu.Unlock()
+
+	if session.quotaRound != round {
+		session.quotaRound = round
+		session.quotaTickets = 0
+	}
+
+	if session.quotaTickets+numTickets > maxTickets {
+		return nil, ErrRoundQuotaExhausted
+	}
+
+	session.quotaTickets += numTickets
+
+	return &quotaReservation{session: session, round: round, numTickets: numTickets}, nil
+}
+
+// quotaReservation is a number of tickets reserved from a session's quota for a round.
+// A nil quotaReservation is a reservation for a session without a quota
+type quotaReservation struct {
+	session    *session
+	round      int64
+	numTickets int
+	kept       bool
+}
+
+// keep keeps the reserved tickets in the session's quota
+func (r *quotaReservation) keep() {
+	if r != nil {
+		r.kept = true
+	}
+}
+
+// release gives the reserved tickets back to the session's quota unless keep was called
+// or the quota was reset for a new round
+func (r *quotaReservation) release() {
+	if r == nil || r.kept {
+		return
+	}
+
+	r.session.quotaMu.Lock()
+	defer r.session.quotaMu.Unlock()
+
+	if r.session.quotaRound == r.round {
+		r.session.quotaTickets -= r.numTickets
+	}
+	r.kept = true
+}
+
+// sessionExpirationParams returns the expiration params to use for tickets created for a session.
+// The session's expiration round offset is only applied if the ticket params do not include expiration params
+func (s *sender) sessionExpirationParams(session *session) (*TicketExpirationParams, error) {
+	expirationParams := session.ticketParams.ExpirationParams
+	// Ensure backwards compatbility
+	// If no expirationParams are included by O
+	// B sets the values based upon its last seen round
+	if expirationParams == nil || expirationParams.CreationRound == 0 || expirationParams.CreationRoundBlockHash == (ethcommon.Hash{}) {
+		params, err := s.expirationParams()
+		if err != nil {
+			return nil, err
+		}
+
+		return s.offsetExpirationParams(session, params)
+	}
+
+	return expirationParams, nil
+}
+
+func (s *sender) expirationParams() (*TicketExpirationParams, error) {
+	if s.cfg.RefreshJitter > 0 {
+		return s.cachedExpirationParams()
+	}
+
+	return s.fetchExpirationParams()
+}
+
+// cachedExpirationParams returns the cached expiration params of the last initialized round.
+// The cache is refreshed immediately if it is empty or the round regressed and after a random
+// delay within the RefreshJitter window if a new round is seen
+func (s *sender) cachedExpirationParams() (*TicketExpirationParams, error) {
+	round := s.timeManager.LastInitializedRound().Int64()
+
+	c := &s.expirationCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.params != nil && round >= c.params.CreationRound {
+		if round > c.params.CreationRound && c.refreshAt.IsZero() {
+			c.refreshAt = timeNow().Add(randJitter(s.cfg.RefreshJitter))
+		}
+
+		if round == c.params.CreationRound || timeNow().Before(c.refreshAt) {
+			params := *c.params
+			return &params, nil
+		}
+	}
+
+	params, err := s.fetchExpirationParams()
+	if err != nil {
+		return nil, err
+	}
+
+	c.params = params
+	c.refreshAt = time.Time{}
+
+	cached := *params
+	return &cached, nil
 }
 
-func (s *sender) expirationParams() *TicketExpirationParams {
+func (s *sender) fetchExpirationParams() (*TicketExpirationParams, error) {
 	round := s.timeManager.LastInitializedRound()
 	blkHash := s.timeManager.LastInitializedBlockHash()
 
+	if err := s.checkRoundRegression(round.Int64()); err != nil {
+		return nil, RoundError{err}
+	}
+
 	return &TicketExpirationParams{
 		CreationRound:          round.Int64(),
 		CreationRoundBlockHash: blkHash,
+	}, nil
+}
+
+// checkRoundRegression records the highest round seen and returns ErrRoundRegression if
+// the provided round is lower than it unless round regressions are allowed
+func (s *sender) checkRoundRegression(round int64) error {
+	for {
+		highest := atomic.LoadInt64(&s.highestRound)
+		if round < highest {
+			if s.cfg.AllowRoundRegression {
+				return nil
+			}
+			return errors.Wrapf(ErrRoundRegression, "round %v < highest seen round %v", round, highest)
+		}
+
+		if round == highest {
+			return nil
+		}
+
+		if atomic.CompareAndSwapInt64(&s.highestRound, highest, round) {
+			if highest != 0 {
+				s.emit(SenderEvent{Type: RoundChanged, Round: round})
+			}
+			return nil
+		}
 	}
 }
 
 func (s *sender) loadSession(sessionID string) (*session, error) {
 	tempSession, ok := s.sessions.Load(sessionID)
 	if !ok {
-		return nil, errors.Errorf("error loading session: %x", sessionID)
+		return nil, errors.Wrapf(ErrUnknownSession, "error loading session: %x", sessionID)
 	}
 
 	return tempSession.(*session), nil
 }
+
+// loadIssuableSession loads a session that tickets can be created for. ErrSenderFrozen is returned
+// if the sender is frozen, ErrSessionStale is returned if the session was marked stale and
+// ErrSessionExpired is returned if the deadline of the session passed
+func (s *sender) loadIssuableSession(sessionID string) (*session, error) {
+	if err := s.checkFrozen(); err != nil {
+		return nil, err
+	}
+
+	session, err := s.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic.LoadUint32(&session.stale) == 1 {
+		return nil, errors.Wrapf(ErrSessionStale, "error loading session: %x", sessionID)
+	}
+
+	if deadline := session.policy.Deadline; !deadline.IsZero() && timeNow().After(deadline) {
+		return nil, errors.Wrapf(ErrSessionExpired, "error loading session: %x deadline=%v", sessionID, deadline)
+	}
+
+	return session, nil
+}
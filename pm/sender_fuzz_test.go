@@ -0,0 +1,87 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// fuzzBigInt builds a *big.Int from fuzzer input. A sign of 0 returns nil, a negative sign
+// returns the negated value of b and a positive sign returns the value of b
+func fuzzBigInt(b []byte, sign int8) *big.Int {
+	if sign == 0 {
+		return nil
+	}
+
+	x := new(big.Int).SetBytes(b)
+	if sign < 0 {
+		x.Neg(x)
+	}
+
+	return x
+}
+
+// FuzzValidateTicketParams feeds ticket params with arbitrary values into ValidateTicketParams and StartSession
+// and checks that:
+//
+// - Neither call panics, including for a nil ExpirationBlock
+// - Params with a missing, negative or larger than uint256 FaceValue or WinProb are rejected with ErrInvalidTicketParams
+// - Accepted params have a face value <= max face value and an EV <= max EV
+// - Tickets can be created for a session if and only if its params are accepted by ValidateTicketParams
+// - Tickets created for a session have the face value and win prob of the params
+func FuzzValidateTicketParams(f *testing.F) {
+	// Edge cases with nil, negative and oversized values are in testdata/fuzz/FuzzValidateTicketParams
+	f.Add([]byte{}, int8(1), []byte{}, int8(1), []byte{0x64}, int8(1), []byte{1})
+	f.Add([]byte{0x03, 0xe8}, int8(1), []byte{0x01, 0x00}, int8(1), []byte{}, int8(1), []byte{})
+
+	f.Fuzz(func(t *testing.T, faceValue []byte, faceValueSign int8, winProb []byte, winProbSign int8, expirationBlock []byte, expirationBlockSign int8, recipientRandHash []byte) {
+		sender := defaultSender(t)
+
+		ticketParams := defaultTicketParams(t, RandAddress())
+		ticketParams.FaceValue = fuzzBigInt(faceValue, faceValueSign)
+		ticketParams.WinProb = fuzzBigInt(winProb, winProbSign)
+		ticketParams.ExpirationBlock = fuzzBigInt(expirationBlock, expirationBlockSign)
+		ticketParams.RecipientRandHash = ethcommon.BytesToHash(recipientRandHash)
+
+		validateErr := sender.ValidateTicketParams(&ticketParams)
+
+		inRange := func(x *big.Int) bool {
+			return x != nil && x.Sign() >= 0 && x.BitLen() <= 256
+		}
+		if !inRange(ticketParams.FaceValue) || !inRange(ticketParams.WinProb) {
+			if errors.Cause(validateErr) != ErrInvalidTicketParams {
+				t.Fatalf("expected %v for faceValue=%v winProb=%v but got %v", ErrInvalidTicketParams, ticketParams.FaceValue, ticketParams.WinProb, validateErr)
+			}
+		}
+
+		if validateErr == nil {
+			maxFaceValue := new(big.Int).Div(big.NewInt(100000), big.NewInt(2))
+			if ticketParams.FaceValue.Cmp(maxFaceValue) > 0 {
+				t.Fatalf("accepted faceValue %v > max faceValue %v", ticketParams.FaceValue, maxFaceValue)
+			}
+			if ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb); ev.Cmp(sender.maxEV) > 0 {
+				t.Fatalf("accepted EV %v > max EV %v", ev.FloatString(5), sender.maxEV.FloatString(5))
+			}
+		}
+
+		sessionID, err := sender.StartSessionWithPolicy(ticketParams, SessionPolicy{})
+		if err != nil {
+			t.Fatalf("unexpected error starting session: %v", err)
+		}
+
+		batch, batchErr := sender.CreateTicketBatch(sessionID, 1)
+		if (validateErr == nil) != (batchErr == nil) {
+			t.Fatalf("ValidateTicketParams returned %v but CreateTicketBatch returned %v", validateErr, batchErr)
+		}
+		if batchErr != nil {
+			return
+		}
+
+		ticket := batch.Tickets()[0]
+		if ticket.FaceValue.Cmp(ticketParams.FaceValue) != 0 || ticket.WinProb.Cmp(ticketParams.WinProb) != 0 {
+			t.Fatalf("ticket faceValue=%v winProb=%v != params faceValue=%v winProb=%v", ticket.FaceValue, ticket.WinProb, ticketParams.FaceValue, ticketParams.WinProb)
+		}
+	})
+}
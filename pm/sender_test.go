@@ -1,13 +1,18 @@
 package pm
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +37,39 @@ func TestStartSession_GivenSomeRecipientRandHash_UsesItAsSessionId(t *testing.T)
 	}
 }
 
+func TestStartSession_CompositeSessionID_AvoidsCollisions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ticketParams0 := defaultTicketParams(t, RandAddress())
+	ticketParams1 := defaultTicketParams(t, RandAddress())
+	ticketParams1.RecipientRandHash = ticketParams0.RecipientRandHash
+	ticketParams1.FaceValue = big.NewInt(100)
+
+	// Sessions for different recipients with the same recipient rand hash collide by default
+	sender := defaultSender(t)
+	assert.Equal(sender.StartSession(ticketParams0), sender.StartSession(ticketParams1))
+	require.Len(sender.ListSessions(), 1)
+	assert.Equal(ticketParams1.Recipient, sender.ListSessions()[0].TicketParams.Recipient)
+
+	sender = defaultSender(t)
+	sender.cfg.CompositeSessionID = true
+	sessionID0 := sender.StartSession(ticketParams0)
+	sessionID1 := sender.StartSession(ticketParams1)
+	assert.NotEqual(sessionID0, sessionID1)
+	assert.Equal(ticketParams0.Recipient.Hex()+"-"+ticketParams0.RecipientRandHash.Hex(), sessionID0)
+	require.Len(sender.ListSessions(), 2)
+
+	batch0, err := sender.CreateTicketBatch(sessionID0, 1)
+	require.Nil(err)
+	batch1, err := sender.CreateTicketBatch(sessionID1, 1)
+	require.Nil(err)
+	assert.Equal(ticketParams0.Recipient, batch0.Recipient)
+	assert.Equal(uint32(1), batch0.SenderParams[0].SenderNonce)
+	assert.Equal(ticketParams1.Recipient, batch1.Recipient)
+	assert.Equal(uint32(1), batch1.SenderParams[0].SenderNonce)
+}
+
 func TestStartSession_GivenConcurrentUsage_RecordsAllSessions(t *testing.T) {
 	sender := defaultSender(t)
 	recipient := ethcommon.Address{}
@@ -65,6 +103,99 @@ func TestStartSession_GivenConcurrentUsage_RecordsAllSessions(t *testing.T) {
 	}
 }
 
+func TestStartSession_ParamsTransform_StoresTransformedParams(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ParamsTransform = func(params TicketParams) (TicketParams, error) {
+		params.WinProb = new(big.Int).Div(params.WinProb, big.NewInt(2))
+		return params, nil
+	}
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.WinProb = big.NewInt(1000)
+	sessionID := sender.StartSession(ticketParams)
+
+	session, err := sender.loadSession(sessionID)
+	require.Nil(err)
+	assert.Equal(big.NewInt(500), session.ticketParams.WinProb)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(big.NewInt(500), batch.WinProb)
+	// The caller's params should not be modified
+	assert.Equal(big.NewInt(1000), ticketParams.WinProb)
+}
+
+func TestStartSession_ParamsTransformError_DoesNotStartSession(t *testing.T) {
+	sender := defaultSender(t)
+	sender.cfg.ParamsTransform = func(params TicketParams) (TicketParams, error) {
+		return params, errors.New("ParamsTransform error")
+	}
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+	assert.Equal(t, ticketParams.RecipientRandHash.Hex(), sessionID)
+
+	_, ok := sender.sessions.Load(sessionID)
+	assert.False(t, ok)
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.Contains(t, err.Error(), "error loading session")
+}
+
+func TestStartSessionWithPolicy_InvalidDepositMultiplier_ReturnsError(t *testing.T) {
+	sender := defaultSender(t)
+
+	sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{DepositMultiplier: -1})
+	assert.EqualError(t, err, "session deposit multiplier must be greater than 0, but -1 provided")
+
+	_, ok := sender.sessions.Load(sessionID)
+	assert.False(t, ok)
+}
+
+func TestStartSessionWithPolicy_ParamsTransformError_ReturnsError(t *testing.T) {
+	sender := defaultSender(t)
+	sender.cfg.ParamsTransform = func(params TicketParams) (TicketParams, error) {
+		return params, errors.New("ParamsTransform error")
+	}
+
+	_, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+	assert.EqualError(t, err, "error transforming ticket params: ParamsTransform error")
+}
+
+func TestStartSessionWithPolicy_DepositMultiplier(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// deposit = 1000
+	// sender depositMultiplier = 2 -> maxFaceValue = 500
+	// session depositMultiplier = 5 -> maxFaceValue = 200
+	sender := defaultSender(t)
+	senderAddr := sender.signer.Account().Address
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.info[senderAddr].Deposit = big.NewInt(1000)
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(300)
+	defaultSessionID, err := sender.StartSessionWithPolicy(ticketParams, SessionPolicy{})
+	require.Nil(err)
+
+	ticketParams.RecipientRandHash = RandHash()
+	conservativeSessionID, err := sender.StartSessionWithPolicy(ticketParams, SessionPolicy{DepositMultiplier: 5})
+	require.Nil(err)
+
+	_, err = sender.CreateTicketBatch(defaultSessionID, 1)
+	assert.Nil(err)
+
+	_, err = sender.CreateTicketBatch(conservativeSessionID, 1)
+	assert.EqualError(err, maxFaceValueErrStr(ticketParams.FaceValue, big.NewInt(200)))
+
+	// ValidateTicketParams uses the sender's deposit multiplier
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+}
+
 func TestSenderEV_NonExistantSession_ReturnsError(t *testing.T) {
 	sender := defaultSender(t)
 
@@ -91,6 +222,232 @@ func TestSenderEV(t *testing.T) {
 	assert.Zero(ticketEV(ticketParams.FaceValue, ticketParams.WinProb).Cmp(ev))
 }
 
+func TestListSessions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	assert.Empty(sender.ListSessions())
+
+	ticketParams0 := defaultTicketParams(t, RandAddress())
+	ticketParams1 := defaultTicketParams(t, RandAddress())
+	sessionID0 := sender.StartSession(ticketParams0)
+	sessionID1 := sender.StartSession(ticketParams1)
+
+	_, err := sender.CreateTicketBatch(sessionID1, 3)
+	require.Nil(err)
+
+	infos := sender.ListSessions()
+	require.Len(infos, 2)
+	assert.True(infos[0].SessionID < infos[1].SessionID)
+
+	for _, info := range infos {
+		switch info.SessionID {
+		case sessionID0:
+			assert.Equal(uint32(0), info.SenderNonce)
+			assert.Equal(ticketParams0, info.TicketParams)
+		case sessionID1:
+			assert.Equal(uint32(3), info.SenderNonce)
+			assert.Equal(ticketParams1, info.TicketParams)
+		}
+	}
+}
+
+func TestSnapshotNonces(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	assert.Empty(sender.SnapshotNonces())
+
+	sessionID0 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	sessionID1 := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID1, 3)
+	require.Nil(err)
+
+	assert.Equal(map[string]uint32{sessionID0: 0, sessionID1: 3}, sender.SnapshotNonces())
+}
+
+func TestSnapshotNonces_ConcurrentCreation(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sessionIDs := []string{
+		sender.StartSession(defaultTicketParams(t, RandAddress())),
+		sender.StartSession(defaultTicketParams(t, RandAddress())),
+	}
+
+	const batches = 100
+	completed := make([]uint32, len(sessionIDs))
+
+	var wg sync.WaitGroup
+	for i, sessionID := range sessionIDs {
+		wg.Add(1)
+		go func(i int, sessionID string) {
+			defer wg.Done()
+			for j := 0; j < batches; j++ {
+				if _, err := sender.CreateTicketBatch(sessionID, 2); err != nil {
+					t.Error(err)
+					return
+				}
+				atomic.AddUint32(&completed[i], 2)
+			}
+		}(i, sessionID)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	prev := make(map[string]uint32)
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+
+		var minNonces []uint32
+		for i := range sessionIDs {
+			minNonces = append(minNonces, atomic.LoadUint32(&completed[i]))
+		}
+
+		snapshot := sender.SnapshotNonces()
+		for i, sessionID := range sessionIDs {
+			assert.True(snapshot[sessionID] >= minNonces[i])
+			assert.True(snapshot[sessionID] >= prev[sessionID])
+			assert.True(snapshot[sessionID] <= 2*batches)
+		}
+		prev = snapshot
+	}
+
+	for _, sessionID := range sessionIDs {
+		assert.Equal(uint32(2*batches), prev[sessionID])
+	}
+}
+
+func TestSessionsForRecipient(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	recipient0 := RandAddress()
+	recipient1 := RandAddress()
+	assert.Empty(sender.SessionsForRecipient(recipient0))
+
+	sessionIDs0 := []string{
+		sender.StartSession(defaultTicketParams(t, recipient0)),
+		sender.StartSession(defaultTicketParams(t, recipient0)),
+	}
+	sessionID1 := sender.StartSession(defaultTicketParams(t, recipient1))
+	_, err := sender.CreateTicketBatch(sessionIDs0[1], 2)
+	require.Nil(err)
+
+	infos := sender.SessionsForRecipient(recipient0)
+	require.Len(infos, 2)
+	assert.True(infos[0].SessionID < infos[1].SessionID)
+	for _, info := range infos {
+		assert.Contains(sessionIDs0, info.SessionID)
+		assert.Equal(recipient0, info.TicketParams.Recipient)
+		if info.SessionID == sessionIDs0[1] {
+			assert.Equal(uint32(2), info.SenderNonce)
+		}
+	}
+
+	infos = sender.SessionsForRecipient(recipient1)
+	require.Len(infos, 1)
+	assert.Equal(sessionID1, infos[0].SessionID)
+
+	sender.EndSession(sessionID1)
+	assert.Empty(sender.SessionsForRecipient(recipient1))
+	assert.Empty(sender.SessionsForRecipient(RandAddress()))
+}
+
+func TestListSessions_Metadata(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	metadata := map[string]string{"orchestrator": "https://127.0.0.1:8935", "streamID": "foo"}
+	sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{Metadata: metadata})
+	require.Nil(err)
+
+	// Changes to the caller's map are not reflected in the session
+	metadata["streamID"] = "bar"
+
+	infos := sender.ListSessions()
+	require.Len(infos, 1)
+	assert.Equal(sessionID, infos[0].SessionID)
+	assert.Equal(map[string]string{"orchestrator": "https://127.0.0.1:8935", "streamID": "foo"}, infos[0].Metadata)
+
+	// Changes to the returned map are not reflected in the session
+	infos[0].Metadata["streamID"] = "baz"
+	assert.Equal("foo", sender.ListSessions()[0].Metadata["streamID"])
+
+	sender.StartSession(defaultTicketParams(t, RandAddress()))
+	for _, info := range sender.ListSessions() {
+		if info.SessionID != sessionID {
+			assert.Nil(info.Metadata)
+		}
+	}
+}
+
+func TestAdvanceNonce_NonExistantSession_ReturnsError(t *testing.T) {
+	sender := defaultSender(t)
+
+	err := sender.AdvanceNonce("foo", 1)
+	assert.Equal(t, ErrUnknownSession, errors.Cause(err))
+}
+
+func TestAdvanceNonce(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	// Advance to a higher nonce
+	require.Nil(sender.AdvanceNonce(sessionID, 10))
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(11), batch.SenderParams[0].SenderNonce)
+
+	// Advance to a lower nonce is a no-op
+	require.Nil(sender.AdvanceNonce(sessionID, 5))
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(12), batch.SenderParams[0].SenderNonce)
+
+	// Advance to the current nonce is a no-op
+	require.Nil(sender.AdvanceNonce(sessionID, 12))
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(13), batch.SenderParams[0].SenderNonce)
+}
+
+func TestAdvanceNonce_ConcurrentCalls_UsesMaxNonce(t *testing.T) {
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	var wg sync.WaitGroup
+	wg.Add(100)
+	for i := 1; i <= 100; i++ {
+		go func(to uint32) {
+			sender.AdvanceNonce(sessionID, to)
+			wg.Done()
+		}(uint32(i))
+	}
+	wg.Wait()
+
+	infos := sender.ListSessions()
+	require.Len(t, infos, 1)
+	assert.Equal(t, uint32(100), infos[0].SenderNonce)
+}
+
 func TestSender_ValidateSender(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -167,6 +524,137 @@ func TestSender_ValidateSender(t *testing.T) {
 	assert.True(ok)
 }
 
+func TestCreateTicketBatch_FrozenSender(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	senderAddr := sender.signer.Account().Address
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.info[senderAddr].Status = SenderStatusFrozen
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// Status is ignored by default
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	sender.cfg.RejectFrozenSender = true
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrSenderFrozen, errors.Cause(err))
+
+	// Frozen status takes precedence over other sender validation errors
+	sm.info[senderAddr].Deposit = big.NewInt(0)
+	assert.Equal(ErrSenderFrozen, sender.ValidateTicketParams(&TicketParams{FaceValue: big.NewInt(0), WinProb: big.NewInt(0)}))
+	sm.info[senderAddr].Deposit = big.NewInt(100000)
+
+	sm.info[senderAddr].Status = SenderStatusActive
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	sm.info[senderAddr].Status = SenderStatusUnknown
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+}
+
+func TestCreateTicketBatch_MaxTicketsPerRound(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxTicketsPerRound = 3
+	tm := sender.timeManager.(*stubTimeManager)
+
+	_, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{MaxTicketsPerRound: -1})
+	assert.EqualError(err, "session max tickets per round must be greater than 0, but -1 provided")
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	// Batch that exceeds the quota does not consume nonces
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	assert.Equal(ErrRoundQuotaExhausted, err)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrRoundQuotaExhausted, err)
+
+	// Advancing the round resets the quota
+	tm.round = new(big.Int).Add(tm.round, big.NewInt(1))
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	assert.Nil(err)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrRoundQuotaExhausted, err)
+
+	// Session policy overrides the sender's quota
+	policySessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{MaxTicketsPerRound: 5})
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(policySessionID, 5)
+	assert.Nil(err)
+	_, err = sender.CreateTicketBatch(policySessionID, 1)
+	assert.Equal(ErrRoundQuotaExhausted, err)
+}
+
+func TestCreateTicketBatch_MaxTicketsPerRound_FailedBatchesRefundQuota(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxTicketsPerRound = 3
+	signer := &flakySigner{failEvery: 2}
+	signer.account = sender.signer.Account()
+	sender.signer = signer
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// The second signature fails
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	assert.EqualError(errors.Cause(err), "Sign error")
+	_, err = sender.CreateBatchSplitByRound(sessionID, 3)
+	assert.EqualError(errors.Cause(err), "Sign error")
+
+	// Failed batches do not use up the quota
+	signer.failEvery = 100
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrRoundQuotaExhausted, err)
+}
+
+func TestCreateTicketBatch_ExpirationBlockOverride(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	tm.lastSeenBlock = big.NewInt(50)
+
+	// Override that has already passed is rejected
+	_, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{ExpirationBlock: big.NewInt(50)})
+	assert.Equal(ErrTicketParamsExpired, errors.Cause(err))
+	assert.Empty(sender.ListSessions())
+
+	// Override later than the ticket params' expiration block is rejected
+	_, err = sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{ExpirationBlock: big.NewInt(101)})
+	assert.EqualError(err, "session expiration block 101 > ticket params expiration block 100")
+	assert.Empty(sender.ListSessions())
+
+	sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{ExpirationBlock: big.NewInt(80)})
+	require.Nil(err)
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(big.NewInt(80), batch.ExpirationBlock)
+
+	ticket := NewTicket(batch.TicketParams, batch.TicketExpirationParams, batch.Sender, batch.SenderParams[0].SenderNonce)
+	assert.Equal(big.NewInt(80), ticket.ParamsExpirationBlock)
+
+	// Tickets are no longer created once the override has passed
+	tm.lastSeenBlock = big.NewInt(80)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrTicketParamsExpired, errors.Cause(err))
+}
+
 func TestCreateTicketBatch_NonExistantSession_ReturnsError(t *testing.T) {
 	sender := defaultSender(t)
 
@@ -184,6 +672,36 @@ func TestCreateTicketBatch_GetSenderInfoError_ReturnsError(t *testing.T) {
 	assert.EqualError(t, err, "GetSenderInfo error")
 }
 
+func TestCreateTicketBatch_TrustedSession_SkipsValidation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.err = errors.New("GetSenderInfo error")
+	tm := sender.timeManager.(*stubTimeManager)
+
+	assert.Equal(ErrUnknownSession, errors.Cause(sender.MarkTrusted("foo")))
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "GetSenderInfo error")
+
+	require.Nil(sender.MarkTrusted(sessionID))
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+	assert.Equal(uint32(2), batch.SenderParams[1].SenderNonce)
+	assert.Equal(tm.round.Int64(), batch.CreationRound)
+	assert.Equal(ethcommon.Hash(tm.blkHash), batch.CreationRoundBlockHash)
+
+	// Other sessions are still validated
+	sessionID = sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "GetSenderInfo error")
+}
+
 func TestCreateTicketBatch_EVTooHigh_ReturnsError(t *testing.T) {
 	// Test single ticket EV too high
 	sender := defaultSender(t)
@@ -331,100 +849,1434 @@ func TestCreateTicketBatch_UsesSessionParamsInBatch(t *testing.T) {
 	}, batch.TicketExpirationParams)
 }
 
-func TestCreateTicketBatch_SingleTicket(t *testing.T) {
-	sender := defaultSender(t)
-	am := sender.signer.(*stubSigner)
+func TestCreateTicketBatch_RoundRegression_ReturnsError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	tm.round = big.NewInt(10)
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(int64(10), batch.CreationRound)
+
+	tm.round = big.NewInt(9)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrRoundRegression, errors.Cause(err))
+
+	// The highest seen round is still accepted
+	tm.round = big.NewInt(10)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	tm.round = big.NewInt(11)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	tm.round = big.NewInt(10)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrRoundRegression, errors.Cause(err))
+
+	// Regression is allowed if configured
+	sender.cfg.AllowRoundRegression = true
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(int64(10), batch.CreationRound)
+}
+
+func TestCreateTicketBatch_RefreshJitter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	sender.cfg.RefreshJitter = 10 * time.Second
+	tm := sender.timeManager.(*stubTimeManager)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(int64(5), batch.CreationRound)
+
+	// Cached params are used until the jittered refresh time
+	tm.round = big.NewInt(6)
+	tm.blkHash = [32]byte{6}
+	roundStart := now
+	for {
+		batch, err = sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(err)
+		if batch.CreationRound != 5 {
+			break
+		}
+		assert.Equal(ethcommon.Hash([32]byte{5}), batch.CreationRoundBlockHash)
+		now = now.Add(100 * time.Millisecond)
+	}
+
+	assert.True(now.Sub(roundStart) < sender.cfg.RefreshJitter)
+	assert.Equal(int64(6), batch.CreationRound)
+	assert.Equal(ethcommon.Hash([32]byte{6}), batch.CreationRoundBlockHash)
+
+	// Round regressions are not delayed
+	tm.round = big.NewInt(5)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrRoundRegression, errors.Cause(err))
+}
+
+func TestCreateTicketBatch_RefreshJitter_StaggersSenders(t *testing.T) {
+	defer func(f func(time.Duration) time.Duration) { randJitter = f }(randJitter)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	jitters := []time.Duration{time.Second, 3 * time.Second}
+	randJitter = func(window time.Duration) time.Duration {
+		jitter := jitters[0]
+		jitters = jitters[1:]
+		return jitter
+	}
+
+	tm := &stubTimeManager{round: big.NewInt(5), blkHash: [32]byte{5}, lastSeenBlock: big.NewInt(0)}
+	var senders []*sender
+	var sessionIDs []string
+	for i := 0; i < 2; i++ {
+		s := defaultSender(t)
+		s.timeManager = tm
+		s.cfg.RefreshJitter = 5 * time.Second
+		sessionIDs = append(sessionIDs, s.StartSession(defaultTicketParams(t, RandAddress())))
+		_, err := s.CreateTicketBatch(sessionIDs[i], 1)
+		require.Nil(t, err)
+		senders = append(senders, s)
+	}
+
+	tm.round = big.NewInt(6)
+	rounds := func() []int64 {
+		var rounds []int64
+		for i, s := range senders {
+			batch, err := s.CreateTicketBatch(sessionIDs[i], 1)
+			require.Nil(t, err)
+			rounds = append(rounds, batch.CreationRound)
+		}
+		return rounds
+	}
+
+	assert.Equal(t, []int64{5, 5}, rounds())
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, []int64{6, 5}, rounds())
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, []int64{6, 6}, rounds())
+}
+
+func TestCreateTicketBatch_SingleTicket(t *testing.T) {
+	sender := defaultSender(t)
+	am := sender.signer.(*stubSigner)
 	am.signShouldFail = false
 	am.saveSignRequest = true
 	am.signResponse = RandBytes(42)
 	ticketParams := defaultTicketParams(t, RandAddress())
 	sessionID := sender.StartSession(ticketParams)
 
-	batch, err := sender.CreateTicketBatch(sessionID, 1)
-	require.Nil(t, err)
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(t, err)
+
+	assert := assert.New(t)
+	assert.Equal(1, len(batch.SenderParams))
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+	assert.Equal(am.signResponse, batch.SenderParams[0].Sig)
+	assert.Equal(batch.Tickets()[0].Hash().Bytes(), am.signRequests[0])
+}
+
+func TestCreateTicketBatch_EmptyBatch_ReturnsError(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxTicketsPerRound = 1
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	<-sender.Events()
+
+	for _, size := range []int{0, -1} {
+		batch, err := sender.CreateTicketBatch(sessionID, size)
+		assert.Nil(batch)
+		assert.Equal(ErrEmptyBatch, err)
+
+		batches, err := sender.CreateBatchSplitByRound(sessionID, size)
+		assert.Nil(batches)
+		assert.Equal(ErrEmptyBatch, err)
+	}
+
+	// Empty batches do not consume nonces or the session's quota
+	assert.Equal(uint32(0), sender.ListSessions()[0].SenderNonce)
+	assert.Len(sender.Events(), 0)
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+}
+
+func TestCreateTicketBatch_MultipleTickets(t *testing.T) {
+	sender := defaultSender(t)
+	am := sender.signer.(*stubSigner)
+	am.signShouldFail = false
+	am.saveSignRequest = true
+	am.signResponse = RandBytes(42)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 4)
+	require.Nil(t, err)
+
+	assert := assert.New(t)
+	assert.Equal(4, len(batch.SenderParams))
+
+	tickets := batch.Tickets()
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(uint32(i+1), batch.SenderParams[i].SenderNonce)
+		assert.Equal(am.signResponse, batch.SenderParams[i].Sig)
+		assert.Equal(tickets[i].Hash().Bytes(), am.signRequests[i])
+	}
+}
+
+func TestCreateTicketBatch_SigningError_ReturnsError(t *testing.T) {
+	sender := defaultSender(t)
+	recipient := RandAddress()
+	ticketParams := defaultTicketParams(t, recipient)
+	sessionID := sender.StartSession(ticketParams)
+	am := sender.signer.(*stubSigner)
+	am.signShouldFail = true
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.Contains(t, err.Error(), "error signing")
+}
+
+func TestCreateTicketBatch_SessionStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	store := newStubSessionStore()
+	sender.cfg.SessionStore = store
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+	assert.Equal(uint32(3), store.nonces[sessionID])
+
+	// Persistence failures do not fail ticket creation by default
+	store.saveShouldFail = true
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(4), batch.SenderParams[0].SenderNonce)
+	assert.Equal(uint32(3), store.nonces[sessionID])
+
+	// Restarting the session restores the persisted nonce
+	store.saveShouldFail = false
+	require.Nil(sender.AdvanceNonce(sessionID, 10))
+	sender.EndSession(sessionID)
+	assert.Equal(sessionID, sender.StartSession(ticketParams))
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(11), batch.SenderParams[0].SenderNonce)
+
+	store.loadShouldFail = true
+	_, err = sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+	assert.Contains(err.Error(), "stub session store load error")
+}
+
+// bufferedSessionStore buffers saved nonces until they are flushed to the underlying store
+type bufferedSessionStore struct {
+	*stubSessionStore
+	pending         map[string]uint32
+	flushShouldFail bool
+	mu              sync.Mutex
+}
+
+func (ss *bufferedSessionStore) Save(sessionID string, senderNonce uint32) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if senderNonce > ss.pending[sessionID] {
+		ss.pending[sessionID] = senderNonce
+	}
+
+	return nil
+}
+
+func (ss *bufferedSessionStore) Flush(sessionID string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.flushShouldFail {
+		return errors.New("flush error")
+	}
+
+	return ss.stubSessionStore.Save(sessionID, ss.pending[sessionID])
+}
+
+func TestEndSessionSync(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	store := newStubSessionStore()
+	sender.cfg.SessionStore = store
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+
+	// Failed persistence of the final nonce keeps the session
+	store.saveShouldFail = true
+	require.Nil(sender.AdvanceNonce(sessionID, 7))
+	err = sender.EndSessionSync(sessionID)
+	assert.Contains(err.Error(), "stub session store save error")
+	assert.Len(sender.ListSessions(), 1)
+
+	store.saveShouldFail = false
+	require.Nil(sender.EndSessionSync(sessionID))
+	assert.Equal(uint32(7), store.nonces[sessionID])
+	assert.Empty(sender.ListSessions())
+	assert.Equal(ErrUnknownSession, errors.Cause(sender.EndSessionSync(sessionID)))
+
+	// Restarting the session continues from the final nonce
+	assert.Equal(sessionID, sender.StartSession(ticketParams))
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(8), batch.SenderParams[0].SenderNonce)
+
+	// Buffering stores are flushed before the session is removed
+	buffered := &bufferedSessionStore{stubSessionStore: newStubSessionStore(), pending: make(map[string]uint32)}
+	sender.cfg.SessionStore = buffered
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	assert.Equal(uint32(0), buffered.nonces[sessionID])
+
+	buffered.flushShouldFail = true
+	err = sender.EndSessionSync(sessionID)
+	assert.Contains(err.Error(), "error flushing nonce for session")
+	assert.Len(sender.ListSessions(), 1)
+
+	buffered.flushShouldFail = false
+	require.Nil(sender.EndSessionSync(sessionID))
+	assert.Equal(uint32(10), buffered.nonces[sessionID])
+	assert.Empty(sender.ListSessions())
+
+	// Sessions are ended without a store
+	sender.cfg.SessionStore = nil
+	sessionID = sender.StartSession(defaultTicketParams(t, RandAddress()))
+	require.Nil(sender.EndSessionSync(sessionID))
+	assert.Empty(sender.ListSessions())
+}
+
+func TestCreateTicketBatch_StrictPersistence_SaveError_DoesNotAdvanceNonce(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	store := newStubSessionStore()
+	sender.cfg.SessionStore = store
+	sender.cfg.StrictPersistence = true
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	store.saveShouldFail = true
+	batch, err := sender.CreateTicketBatch(sessionID, 3)
+	assert.Nil(batch)
+	assert.Contains(err.Error(), "stub session store save error")
+	assert.Equal(uint32(2), sender.ListSessions()[0].SenderNonce)
+
+	err = sender.AdvanceNonce(sessionID, 10)
+	assert.Contains(err.Error(), "stub session store save error")
+	assert.Equal(uint32(2), sender.ListSessions()[0].SenderNonce)
+
+	store.saveShouldFail = false
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+	assert.Equal(uint32(3), store.nonces[sessionID])
+}
+
+func TestCreateTicketAtRound(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.RefreshJitter = time.Minute
+	am := sender.signer.(*stubSigner)
+	am.saveSignRequest = true
+	am.signResponse = RandBytes(42)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	// The round is older than the last initialized round
+	blockHash := [32]byte{2, 3}
+	ticket, sig, err := sender.CreateTicketAtRound(sessionID, 2, blockHash)
+	require.Nil(err)
+	assert.Equal(int64(2), ticket.CreationRound)
+	assert.Equal(ethcommon.Hash(blockHash), ticket.CreationRoundBlockHash)
+	assert.Equal(uint32(2), ticket.SenderNonce)
+	assert.Equal(ticketParams.Recipient, ticket.Recipient)
+	assert.Equal(am.signResponse, sig)
+	assert.Equal(ticket.Hash().Bytes(), am.signRequests[len(am.signRequests)-1])
+
+	// Regular ticket creation still uses the last initialized round
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(int64(5), batch.CreationRound)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+
+	_, _, err = sender.CreateTicketAtRound("foo", 2, blockHash)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	sender.senderManager.(*stubSenderManager).err = errors.New("GetSenderInfo error")
+	_, _, err = sender.CreateTicketAtRound(sessionID, 2, blockHash)
+	_, ok := err.(SenderInfoError)
+	assert.True(ok)
+}
+
+func TestMarkSessionStale(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	recipient := RandAddress()
+	oldParams := defaultTicketParams(t, recipient)
+	oldSessionID := sender.StartSession(oldParams)
+
+	assert.Equal(ErrUnknownSession, errors.Cause(sender.MarkSessionStale("foo")))
+
+	_, err := sender.CreateTicketBatch(oldSessionID, 2)
+	require.Nil(err)
+	require.Nil(sender.MarkSessionStale(oldSessionID))
+
+	_, err = sender.CreateTicketBatch(oldSessionID, 1)
+	assert.Equal(ErrSessionStale, errors.Cause(err))
+	_, err = sender.CreateBatchSplitByRound(oldSessionID, 1)
+	assert.Equal(ErrSessionStale, errors.Cause(err))
+	_, err = sender.CreateMultiRecipientBatch([]BatchRequest{{SessionID: oldSessionID, Size: 1}})
+	assert.Equal(ErrSessionStale, errors.Cause(err))
+	_, _, err = sender.CreateTicketAtRound(oldSessionID, 5, [32]byte{5})
+	assert.Equal(ErrSessionStale, errors.Cause(err))
+	assert.Equal(ErrSessionStale, errors.Cause(sender.Ready(oldSessionID)))
+
+	// The stale session is kept until it is ended or replaced
+	assert.Len(sender.ListSessions(), 1)
+
+	// Start a fresh session with the recipient's new params
+	newParams := defaultTicketParams(t, recipient)
+	newSessionID := sender.StartSession(newParams)
+	assert.NotEqual(oldSessionID, newSessionID)
+	batch, err := sender.CreateTicketBatch(newSessionID, 1)
+	require.Nil(err)
+	assert.Equal(newParams.RecipientRandHash, batch.RecipientRandHash)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+
+	// Restarting the stale session replaces it
+	assert.Equal(oldSessionID, sender.StartSession(oldParams))
+	_, err = sender.CreateTicketBatch(oldSessionID, 1)
+	assert.Nil(err)
+}
+
+func TestSessionDeadline(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1600000000, 0)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	deadline := now.Add(time.Minute)
+	sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{Deadline: deadline})
+	require.Nil(err)
+	noDeadlineSessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	// Tickets can still be created at the deadline
+	now = deadline
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	now = deadline.Add(time.Second)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrSessionExpired, errors.Cause(err))
+	_, err = sender.CreateBatchSplitByRound(sessionID, 1)
+	assert.Equal(ErrSessionExpired, errors.Cause(err))
+	_, _, err = sender.BuildTicket(sessionID)
+	assert.Equal(ErrSessionExpired, errors.Cause(err))
+	assert.Equal(ErrSessionExpired, errors.Cause(sender.Ready(sessionID)))
+
+	// No nonces are consumed after the deadline
+	assert.Equal(map[string]uint32{sessionID: 3, noDeadlineSessionID: 0}, sender.SnapshotNonces())
+
+	// Sessions without a deadline are not affected
+	_, err = sender.CreateTicketBatch(noDeadlineSessionID, 1)
+	assert.Nil(err)
+}
+
+// panickingSigner panics on every Sign call
+type panickingSigner struct {
+	stubSigner
+}
+
+func (s *panickingSigner) Sign(msg []byte) ([]byte, error) {
+	panic("signer exploded")
+}
+
+func TestCreateTicketBatch_SignerPanic_ReturnsErrorAndReleasesNonces(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	signer := sender.signer.(*stubSigner)
+	sender.signer = &panickingSigner{stubSigner: *signer}
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	batch, err := sender.CreateTicketBatch(sessionID, 3)
+	assert.Nil(batch)
+	require.NotNil(err)
+	assert.Equal(ErrSignerPanic, errors.Cause(err))
+	assert.Contains(err.Error(), "signer exploded")
+	_, ok := err.(SignerError)
+	assert.True(ok)
+	assert.Equal(uint32(0), sender.ListSessions()[0].SenderNonce)
+
+	_, _, err = sender.PeekTicket(sessionID)
+	assert.Equal(ErrSignerPanic, errors.Cause(err))
+
+	sender.signer = signer
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+}
+
+// flakySigner fails every failEvery-th Sign call
+type flakySigner struct {
+	stubSigner
+	calls     int
+	failEvery int
+	mu        sync.Mutex
+}
+
+func (s *flakySigner) Sign(msg []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls%s.failEvery == 0 {
+		return nil, errors.New("Sign error")
+	}
+	return s.signResponse, nil
+}
+
+func TestCreateTicketBatchCtx_CancelledBatchReturnsNonces(t *testing.T) {
+	for _, strictSequential := range []bool{false, true} {
+		t.Run(fmt.Sprintf("StrictSequential=%v", strictSequential), func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sender := defaultSender(t)
+			sender.cfg.StrictSequential = strictSequential
+			sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+			_, err := sender.CreateTicketBatch(sessionID, 2)
+			require.Nil(err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			signer := &hookSigner{onSign: func(calls int) {
+				if calls == 3 {
+					cancel()
+				}
+			}}
+			signer.account = sender.signer.Account()
+			sender.signer = signer
+
+			batch, err := sender.CreateTicketBatchCtx(ctx, sessionID, 5)
+			assert.Nil(batch)
+			assert.Equal(context.Canceled, err)
+			assert.Equal(3, signer.calls)
+
+			// The next ticket uses the nonce following the last batch that was not cancelled
+			batch, err = sender.CreateTicketBatch(sessionID, 1)
+			require.Nil(err)
+			assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+
+			// A batch is not created for a context that is already done
+			_, err = sender.CreateTicketBatchCtx(ctx, sessionID, 1)
+			assert.Equal(context.Canceled, err)
+			assert.Equal(4, signer.calls)
+		})
+	}
+}
+
+func TestCreateTicketBatchCtx_CancelledBatchDoesNotReuseLaterNonces(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Nonces are allocated for the session by another batch while the cancelled batch is signed
+	var concurrent *TicketBatch
+	signer := &hookSigner{onSign: func(calls int) {
+		if calls == 1 {
+			var err error
+			concurrent, err = sender.CreateTicketBatch(sessionID, 2)
+			require.Nil(err)
+			cancel()
+		}
+	}}
+	signer.account = sender.signer.Account()
+	sender.signer = signer
+
+	_, err := sender.CreateTicketBatchCtx(ctx, sessionID, 3)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(uint32(4), concurrent.SenderParams[0].SenderNonce)
+
+	// The nonces of the cancelled batch cannot be given back without reusing the nonces of the other batch
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(6), batch.SenderParams[0].SenderNonce)
+}
+
+func TestCreateTicketBatch_StrictSequential_GaplessNonces(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.StrictSequential = true
+	sender.signer = &flakySigner{stubSigner: *sender.signer.(*stubSigner), failEvery: 3}
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	var (
+		nonces   []uint32
+		failures int
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(size int) {
+			defer wg.Done()
+
+			var batchNonces []uint32
+			var err error
+			if size%2 == 0 {
+				var batch *TicketBatch
+				batch, err = sender.CreateTicketBatch(sessionID, size)
+				if err == nil {
+					for _, senderParams := range batch.SenderParams {
+						batchNonces = append(batchNonces, senderParams.SenderNonce)
+					}
+				}
+			} else {
+				var batches []*TicketBatch
+				batches, err = sender.CreateBatchSplitByRound(sessionID, size)
+				for _, batch := range batches {
+					for _, senderParams := range batch.SenderParams {
+						batchNonces = append(batchNonces, senderParams.SenderNonce)
+					}
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures++
+				return
+			}
+			nonces = append(nonces, batchNonces...)
+		}(i%3 + 1)
+	}
+	wg.Wait()
+
+	require.NotZero(failures)
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	for i, nonce := range nonces {
+		assert.Equal(uint32(i+1), nonce)
+	}
+	assert.Equal(uint32(len(nonces)), sender.ListSessions()[0].SenderNonce)
+
+	// Advancing the nonce would create a gap
+	assert.Equal(ErrNonceGap, sender.AdvanceNonce(sessionID, uint32(len(nonces)+2)))
+	assert.Nil(sender.AdvanceNonce(sessionID, 1))
+	assert.Equal(uint32(len(nonces)), sender.ListSessions()[0].SenderNonce)
+}
+
+func TestCreateTicketBatch_StrictSequential_SessionStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.StrictSequential = true
+	sender.cfg.StrictPersistence = true
+	store := newStubSessionStore()
+	sender.cfg.SessionStore = store
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	assert.Equal(uint32(2), store.nonces[sessionID])
+
+	store.saveShouldFail = true
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	assert.Contains(err.Error(), "stub session store save error")
+	assert.Equal(uint32(2), sender.ListSessions()[0].SenderNonce)
+
+	store.saveShouldFail = false
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+}
+
+func TestCreateTicketBatch_CategorizesErrors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	tm := sender.timeManager.(*stubTimeManager)
+	am := sender.signer.(*stubSigner)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// Signer failure
+	am.signShouldFail = true
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	_, ok := err.(SignerError)
+	assert.True(ok)
+	assert.Contains(err.Error(), "error signing ticket for session")
+	_, _, err = sender.PeekTicket(sessionID)
+	_, ok = err.(SignerError)
+	assert.True(ok)
+	am.signShouldFail = false
+
+	// Round lookup failure
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	tm.round = big.NewInt(4)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok = err.(RoundError)
+	assert.True(ok)
+	assert.Equal(ErrRoundRegression, errors.Cause(err))
+	_, err = sender.CreateBatchSplitByRound(sessionID, 1)
+	_, ok = err.(RoundError)
+	assert.True(ok)
+	tm.round = big.NewInt(5)
+
+	// Sender info failure
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok = err.(SenderInfoError)
+	assert.True(ok)
+	assert.Equal(sm.err, errors.Cause(err))
+	sm.err = nil
+
+	// Validation failure
+	sm.info[sender.signer.Account().Address].Deposit = big.NewInt(0)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	validationErr, ok := err.(ValidationError)
+	require.True(ok)
+	_, ok = validationErr.Cause().(ErrSenderValidation)
+	assert.True(ok)
+	assert.EqualError(err, "no sender deposit")
+
+	// Argument errors are not categorized
+	_, err = sender.CreateTicketBatch("foo", 1)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+	_, err = sender.CreateTicketBatch(sessionID, 0)
+	assert.Equal(ErrEmptyBatch, err)
+}
+
+func TestCreateTicketBatch_ConcurrentCallsForSameSession_SenderNonceIncrementsCorrectly(t *testing.T) {
+	totalBatches := 100
+	lock := sync.RWMutex{}
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+
+	var wg sync.WaitGroup
+	wg.Add(totalBatches)
+	var tickets []*Ticket
+	for i := 0; i < totalBatches; i++ {
+		go func() {
+			batch, _ := sender.CreateTicketBatch(sessionID, 2)
+
+			lock.Lock()
+			tickets = append(tickets, batch.Tickets()...)
+			lock.Unlock()
+
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	totalTickets := totalBatches * 2
+
+	assert := assert.New(t)
+
+	sessionUntyped, ok := sender.sessions.Load(sessionID)
+	require.True(t, ok)
+
+	session := sessionUntyped.(*session)
+	assert.Equal(uint32(totalTickets), session.senderNonce)
+
+	uniqueNonces := make(map[uint32]bool)
+	for _, ticket := range tickets {
+		uniqueNonces[ticket.SenderNonce] = true
+	}
+
+	assert.Equal(totalTickets, len(uniqueNonces))
+}
+
+type slowSigner struct {
+	stubSigner
+	delay time.Duration
+}
+
+func (s *slowSigner) Sign(msg []byte) ([]byte, error) {
+	time.Sleep(s.delay)
+	return s.signResponse, nil
+}
+
+// roundAdvancingSigner advances the round of a stubTimeManager after signing a number of messages
+type roundAdvancingSigner struct {
+	stubSigner
+	tm    *stubTimeManager
+	after int
+}
+
+func (s *roundAdvancingSigner) Sign(msg []byte) ([]byte, error) {
+	s.after--
+	if s.after == 0 {
+		s.tm.round = new(big.Int).Add(s.tm.round, big.NewInt(1))
+		s.tm.blkHash = [32]byte{6}
+	}
+	return s.signResponse, nil
+}
+
+func TestCreateBatchSplitByRound(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	sender.signer = &roundAdvancingSigner{stubSigner: *sender.signer.(*stubSigner), tm: tm, after: 2}
+
+	_, err := sender.CreateBatchSplitByRound("foo", 1)
+	assert.Contains(err.Error(), "unknown session")
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	batches, err := sender.CreateBatchSplitByRound(sessionID, 5)
+	require.Nil(err)
+	require.Len(batches, 2)
+
+	assert.Equal(int64(5), batches[0].CreationRound)
+	assert.Equal(ethcommon.Hash([32]byte{5}), batches[0].CreationRoundBlockHash)
+	require.Len(batches[0].SenderParams, 2)
+	assert.Equal(int64(6), batches[1].CreationRound)
+	assert.Equal(ethcommon.Hash([32]byte{6}), batches[1].CreationRoundBlockHash)
+	require.Len(batches[1].SenderParams, 3)
+
+	var nonce uint32
+	for _, batch := range batches {
+		for _, senderParams := range batch.SenderParams {
+			nonce++
+			assert.Equal(nonce, senderParams.SenderNonce)
+		}
+	}
+	assert.Equal(uint32(5), sender.ListSessions()[0].SenderNonce)
+
+	// Batches that do not span a round change are not split
+	batches, err = sender.CreateBatchSplitByRound(sessionID, 2)
+	require.Nil(err)
+	require.Len(batches, 1)
+	assert.Equal(int64(6), batches[0].CreationRound)
+	assert.Equal(uint32(6), batches[0].SenderParams[0].SenderNonce)
+}
+
+func TestCreateTicketBatch_LargeBatch_DoesNotBlockOtherSessions(t *testing.T) {
+	sender := defaultSender(t)
+	sender.signer = &slowSigner{stubSigner: *sender.signer.(*stubSigner), delay: time.Millisecond}
+
+	largeSessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	smallSessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	largeDone := make(chan struct{})
+	go func() {
+		_, err := sender.CreateTicketBatch(largeSessionID, 1000)
+		assert.Nil(t, err)
+		close(largeDone)
+	}()
+
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		_, err := sender.CreateTicketBatch(smallSessionID, 1)
+		require.Nil(t, err)
+		assert.True(t, time.Since(start) < 200*time.Millisecond)
+	}
+
+	select {
+	case <-largeDone:
+		t.Error("expected large batch to still be in progress")
+	default:
+	}
+
+	<-largeDone
+}
+
+func TestIssuanceLog(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 3
+
+	_, err := sender.IssuanceLog("foo")
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(50)
+	sessionID := sender.StartSession(ticketParams)
+
+	records, err := sender.IssuanceLog(sessionID)
+	require.Nil(err)
+	assert.Empty(records)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	records, err = sender.IssuanceLog(sessionID)
+	require.Nil(err)
+	require.Len(records, 2)
+	for i, ticket := range batch.Tickets() {
+		assert.Equal(ticket.SenderNonce, records[i].SenderNonce)
+		assert.Equal(ticket.Hash(), records[i].Hash)
+		assert.Equal(big.NewInt(50), records[i].FaceValue)
+		assert.Equal(now, records[i].Timestamp)
+	}
+
+	// Oldest records are evicted
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	records, err = sender.IssuanceLog(sessionID)
+	require.Nil(err)
+	assert.Equal([]uint32{2, 3, 4}, recordNonces(records))
+
+	// Records are not appended for failed signing
+	sender.signer.(*stubSigner).signShouldFail = true
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.NotNil(err)
+	records, err = sender.IssuanceLog(sessionID)
+	require.Nil(err)
+	assert.Equal([]uint32{2, 3, 4}, recordNonces(records))
+}
+
+func TestVerifyTicket_NonExistantSession_ReturnsError(t *testing.T) {
+	sender := defaultSender(t)
+
+	err := sender.VerifyTicket("foo", &Ticket{})
+	assert.Equal(t, ErrUnknownSession, errors.Cause(err))
+}
 
+func TestVerifyTicket(t *testing.T) {
 	assert := assert.New(t)
-	assert.Equal(1, len(batch.SenderParams))
-	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
-	assert.Equal(am.signResponse, batch.SenderParams[0].Sig)
-	assert.Equal(batch.Tickets()[0].Hash().Bytes(), am.signRequests[0])
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1111)
+	ticketParams.WinProb = big.NewInt(2222)
+	sessionID := sender.StartSession(ticketParams)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	validTicket := func() *Ticket {
+		return batch.Tickets()[0]
+	}
+
+	// Ticket created by the sender is valid
+	assert.Nil(sender.VerifyTicket(sessionID, validTicket()))
+
+	ticket := validTicket()
+	ticket.FaceValue = big.NewInt(1)
+	assert.EqualError(sender.VerifyTicket(sessionID, ticket), fmt.Sprintf("ticket does not match session %v: faceValue 1 != 1111", sessionID))
+
+	ticket = validTicket()
+	ticket.WinProb = big.NewInt(2)
+	assert.EqualError(sender.VerifyTicket(sessionID, ticket), fmt.Sprintf("ticket does not match session %v: winProb 2 != 2222", sessionID))
+
+	ticket = validTicket()
+	ticket.RecipientRandHash = RandHash()
+	assert.Contains(sender.VerifyTicket(sessionID, ticket).Error(), "recipientRandHash")
+
+	ticket = validTicket()
+	ticket.Recipient = RandAddress()
+	assert.Contains(sender.VerifyTicket(sessionID, ticket).Error(), "recipient ")
+
+	ticket = validTicket()
+	ticket.Sender = RandAddress()
+	assert.Contains(sender.VerifyTicket(sessionID, ticket).Error(), "sender ")
+
+	ticket = validTicket()
+	ticket.CreationRound = 99
+	assert.EqualError(sender.VerifyTicket(sessionID, ticket), fmt.Sprintf("ticket does not match session %v: creationRound 99 != %v", sessionID, tm.round))
+
+	ticket = validTicket()
+	ticket.CreationRoundBlockHash = RandHash()
+	assert.Contains(sender.VerifyTicket(sessionID, ticket).Error(), "creationRoundBlockHash")
+
+	// Multiple mismatches are all reported
+	ticket = validTicket()
+	ticket.FaceValue = big.NewInt(1)
+	ticket.WinProb = big.NewInt(2)
+	assert.EqualError(sender.VerifyTicket(sessionID, ticket), fmt.Sprintf("ticket does not match session %v: faceValue 1 != 1111, winProb 2 != 2222", sessionID))
+
+	// Ticket stamped with a stale round no longer matches
+	tm.round = new(big.Int).Add(tm.round, big.NewInt(1))
+	assert.Contains(sender.VerifyTicket(sessionID, validTicket()).Error(), "creationRound")
 }
 
-func TestCreateTicketBatch_MultipleTickets(t *testing.T) {
+func TestRefreshBatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
 	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	tm := sender.timeManager.(*stubTimeManager)
 	am := sender.signer.(*stubSigner)
-	am.signShouldFail = false
 	am.saveSignRequest = true
-	am.signResponse = RandBytes(42)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.RefreshBatch("foo", &TicketBatch{})
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	oldBatch, err := sender.CreateTicketBatch(sessionID, 3)
+	require.Nil(err)
+
+	tm.round = big.NewInt(6)
+	tm.blkHash = [32]byte{6}
+	am.signRequests = nil
+	batch, err := sender.RefreshBatch(sessionID, oldBatch)
+	require.Nil(err)
+
+	assert.Equal(int64(6), batch.CreationRound)
+	assert.Equal(ethcommon.Hash{6}, batch.CreationRoundBlockHash)
+	require.Len(batch.SenderParams, 3)
+	for i, ticket := range batch.Tickets() {
+		assert.Equal(oldBatch.SenderParams[i].SenderNonce, ticket.SenderNonce)
+		assert.Equal(ticket.Hash().Bytes(), am.signRequests[i])
+	}
+
+	// No new nonces are allocated
+	session, err := sender.loadSession(sessionID)
+	require.Nil(err)
+	assert.Equal(uint32(3), session.senderNonce)
+
+	// Refreshed tickets are not recorded as new tickets
+	assert.Len(session.issuanceLog.list(), 3)
+
+	// Batch for a different session is rejected
+	otherSessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err = sender.RefreshBatch(otherSessionID, oldBatch)
+	assert.EqualError(err, fmt.Sprintf("batch was not created for session: %v", otherSessionID))
+
+	// Nonces that were not issued for the session are rejected
+	forged := *oldBatch
+	forged.SenderParams = []*TicketSenderParams{{SenderNonce: 4}}
+	_, err = sender.RefreshBatch(sessionID, &forged)
+	assert.EqualError(err, fmt.Sprintf("batch was not created for session: %v: nonce 4 was not issued, current nonce 3", sessionID))
+
+	forged.SenderParams = []*TicketSenderParams{{SenderNonce: 0}}
+	_, err = sender.RefreshBatch(sessionID, &forged)
+	assert.EqualError(err, fmt.Sprintf("batch was not created for session: %v: nonce 0 was not issued, current nonce 3", sessionID))
+
+	// Duplicate nonces are rejected so that a nonce is not signed twice
+	forged.SenderParams = []*TicketSenderParams{{SenderNonce: 2}, {SenderNonce: 2}}
+	_, err = sender.RefreshBatch(sessionID, &forged)
+	assert.EqualError(err, fmt.Sprintf("batch was not created for session: %v: duplicate nonce 2", sessionID))
+	assert.Equal(uint32(3), session.senderNonce)
+
+	// Validation errors are returned
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.RefreshBatch(sessionID, oldBatch)
+	assert.EqualError(err, "GetSenderInfo error")
+}
+
+func TestRefreshBatch_UsesSessionExpirationParams(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
 	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.ExpirationParams = &TicketExpirationParams{CreationRound: 4, CreationRoundBlockHash: ethcommon.Hash{4}}
 	sessionID := sender.StartSession(ticketParams)
 
-	batch, err := sender.CreateTicketBatch(sessionID, 4)
-	require.Nil(t, err)
+	oldBatch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	batch, err := sender.RefreshBatch(sessionID, oldBatch)
+	require.Nil(err)
+	assert.Equal(ticketParams.ExpirationParams, batch.TicketExpirationParams)
+}
 
+func TestRefreshBatchExcept(t *testing.T) {
 	assert := assert.New(t)
-	assert.Equal(4, len(batch.SenderParams))
+	require := require.New(t)
 
-	tickets := batch.Tickets()
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	am := sender.signer.(*stubSigner)
+	am.saveSignRequest = true
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
 
-	for i := 0; i < 4; i++ {
-		assert.Equal(uint32(i+1), batch.SenderParams[i].SenderNonce)
-		assert.Equal(am.signResponse, batch.SenderParams[i].Sig)
-		assert.Equal(tickets[i].Hash().Bytes(), am.signRequests[i])
+	oldBatch, err := sender.CreateTicketBatch(sessionID, 4)
+	require.Nil(err)
+
+	tm.round = big.NewInt(6)
+	tm.blkHash = [32]byte{6}
+	am.signRequests = nil
+	batch, err := sender.RefreshBatchExcept(sessionID, oldBatch, []uint32{2, 4, 7})
+	require.Nil(err)
+
+	// Skipped nonces are absent from the refreshed batch and are not signed
+	assert.Equal(int64(6), batch.CreationRound)
+	require.Len(batch.SenderParams, 2)
+	require.Len(am.signRequests, 2)
+	for i, ticket := range batch.Tickets() {
+		assert.Equal([]uint32{1, 3}[i], ticket.SenderNonce)
+		assert.Equal(ticket.Hash().Bytes(), am.signRequests[i])
 	}
+
+	// The old batch is still checked before nonces are skipped
+	forged := *oldBatch
+	forged.SenderParams = []*TicketSenderParams{{SenderNonce: 1}, {SenderNonce: 5}}
+	_, err = sender.RefreshBatchExcept(sessionID, &forged, []uint32{5})
+	assert.EqualError(err, fmt.Sprintf("batch was not created for session: %v: nonce 5 was not issued, current nonce 4", sessionID))
+
+	_, err = sender.RefreshBatchExcept(sessionID, oldBatch, []uint32{1, 2, 3, 4})
+	assert.Equal(ErrEmptyBatch, err)
 }
 
-func TestCreateTicketBatch_SigningError_ReturnsError(t *testing.T) {
+func TestPeekTicket(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
 	sender := defaultSender(t)
-	recipient := RandAddress()
-	ticketParams := defaultTicketParams(t, recipient)
-	sessionID := sender.StartSession(ticketParams)
 	am := sender.signer.(*stubSigner)
+	am.saveSignRequest = true
+	am.signResponse = RandBytes(42)
+
+	_, _, err := sender.PeekTicket("foo")
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1111)
+	sessionID := sender.StartSession(ticketParams)
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	ticket, sig, err := sender.PeekTicket(sessionID)
+	require.Nil(err)
+	assert.Equal(uint32(0), ticket.SenderNonce)
+	assert.Equal(big.NewInt(0), ticket.FaceValue)
+	assert.Equal(ticketParams.WinProb, ticket.WinProb)
+	assert.Equal(ticketParams.RecipientRandHash, ticket.RecipientRandHash)
+	assert.Equal(am.signResponse, sig)
+	assert.Equal(ticket.Hash().Bytes(), am.signRequests[len(am.signRequests)-1])
+	// Session params are not modified
+	assert.Equal(big.NewInt(1111), ticketParams.FaceValue)
+
+	// The live nonce is not affected
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+	assert.Equal(big.NewInt(1111), batch.FaceValue)
+
 	am.signShouldFail = true
+	_, _, err = sender.PeekTicket(sessionID)
+	assert.Contains(err.Error(), "error signing peek ticket")
+}
 
-	_, err := sender.CreateTicketBatch(sessionID, 1)
-	assert.Contains(t, err.Error(), "error signing")
+type clockSigner struct {
+	stubSigner
+	now       time.Time
+	latencies []time.Duration
 }
 
-func TestCreateTicketBatch_ConcurrentCallsForSameSession_SenderNonceIncrementsCorrectly(t *testing.T) {
-	totalBatches := 100
-	lock := sync.RWMutex{}
+func (s *clockSigner) Sign(msg []byte) ([]byte, error) {
+	s.now = s.now.Add(s.latencies[0])
+	s.latencies = s.latencies[1:]
+	return s.signResponse, nil
+}
+
+func TestSigningStats(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	signer := &clockSigner{now: time.Now()}
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return signer.now }
+
+	sender := defaultSender(t)
+	signer.stubSigner = *sender.signer.(*stubSigner)
+	sender.signer = signer
+
+	assert.Equal(SigningStats{}, sender.SigningStats())
+
+	for i := 100; i > 0; i-- {
+		signer.latencies = append(signer.latencies, time.Duration(i)*time.Millisecond)
+	}
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID, 100)
+	require.Nil(err)
+
+	stats := sender.SigningStats()
+	assert.Equal(uint64(100), stats.Count)
+	assertLatency(t, 50*time.Millisecond, stats.P50)
+	assertLatency(t, 90*time.Millisecond, stats.P90)
+	assertLatency(t, 99*time.Millisecond, stats.P99)
+
+	// Peek tickets are also measured
+	signer.latencies = []time.Duration{time.Second}
+	_, _, err = sender.PeekTicket(sessionID)
+	require.Nil(err)
+	assert.Equal(uint64(101), sender.SigningStats().Count)
+}
+
+// blockingSenderManager blocks GetSenderInfo calls until unblock is closed
+type blockingSenderManager struct {
+	*stubSenderManager
+	unblock chan struct{}
+}
+
+func (s *blockingSenderManager) GetSenderInfo(addr ethcommon.Address) (*SenderInfo, error) {
+	<-s.unblock
+	return s.stubSenderManager.GetSenderInfo(addr)
+}
+
+func TestValidateTicketParamsCtx(t *testing.T) {
+	assert := assert.New(t)
+
 	sender := defaultSender(t)
+	sm := &blockingSenderManager{stubSenderManager: sender.senderManager.(*stubSenderManager), unblock: make(chan struct{})}
+	sender.senderManager = sm
 	ticketParams := defaultTicketParams(t, RandAddress())
-	sessionID := sender.StartSession(ticketParams)
 
-	var wg sync.WaitGroup
-	wg.Add(totalBatches)
-	var tickets []*Ticket
-	for i := 0; i < totalBatches; i++ {
-		go func() {
-			batch, _ := sender.CreateTicketBatch(sessionID, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- sender.ValidateTicketParamsCtx(ctx, &ticketParams)
+	}()
+
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.Equal(context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("ValidateTicketParamsCtx did not return after context was canceled")
+	}
+	assert.Equal(SenderEvent{Type: ValidationFailed, Err: context.Canceled}, <-sender.Events())
 
-			lock.Lock()
-			tickets = append(tickets, batch.Tickets()...)
-			lock.Unlock()
+	// Context that is already done does not call GetSenderInfo
+	assert.Equal(context.Canceled, sender.ValidateTicketParamsCtx(ctx, &ticketParams))
 
-			wg.Done()
-		}()
+	close(sm.unblock)
+	assert.Nil(sender.ValidateTicketParamsCtx(context.Background(), &ticketParams))
+
+	ticketParams.FaceValue = big.NewInt(100000)
+	assert.EqualError(sender.ValidateTicketParamsCtx(context.Background(), &ticketParams), "ticket faceValue 100000 > max faceValue 50000")
+}
+
+func TestValidateParamsBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sm := &countingSenderManager{stubSenderManager: sender.senderManager.(*stubSenderManager)}
+	sender.senderManager = sm
+
+	valid := defaultTicketParams(t, RandAddress())
+	faceValueTooHigh := defaultTicketParams(t, RandAddress())
+	faceValueTooHigh.FaceValue = big.NewInt(60000)
+	evTooHigh := defaultTicketParams(t, RandAddress())
+	evTooHigh.FaceValue = big.NewInt(200)
+	evTooHigh.WinProb = new(big.Int).Set(maxWinProb)
+	expired := defaultTicketParams(t, RandAddress())
+	expired.ExpirationBlock = big.NewInt(-1)
+
+	paramsList := []TicketParams{valid, faceValueTooHigh, valid, evTooHigh, expired}
+	errs := sender.ValidateParamsBatch(paramsList)
+	assert.Len(errs, len(paramsList))
+	assert.Nil(errs[0])
+	assert.EqualError(errs[1], maxFaceValueErrStr(big.NewInt(60000), big.NewInt(50000)))
+	assert.Nil(errs[2])
+	assert.EqualError(errs[3], maxEVErrStr(big.NewRat(200, 1), 1, big.NewRat(100, 1)))
+	assert.Equal(ErrTicketParamsExpired, errs[4])
+
+	// The sender info is fetched once for the whole list
+	assert.Equal(1, sm.calls)
+
+	// Sender info errors are returned for every entry
+	sm.err = errors.New("GetSenderInfo error")
+	errs = sender.ValidateParamsBatch(paramsList[:2])
+	assert.Len(errs, 2)
+	for _, err := range errs {
+		assert.EqualError(err, "GetSenderInfo error")
 	}
-	wg.Wait()
+	assert.Equal(2, sm.calls)
 
-	totalTickets := totalBatches * 2
+	assert.Empty(sender.ValidateParamsBatch(nil))
+	assert.Equal(2, sm.calls)
+}
 
+func TestMaxNoncePerSession(t *testing.T) {
+	for _, strictSequential := range []bool{false, true} {
+		t.Run(fmt.Sprintf("StrictSequential=%v", strictSequential), func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sender := defaultSender(t)
+			sender.cfg.MaxNoncePerSession = 5
+			sender.cfg.StrictSequential = strictSequential
+			sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+			batch, err := sender.CreateTicketBatch(sessionID, 3)
+			require.Nil(err)
+			assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+
+			// The limit is checked for the whole batch and no nonces are consumed
+			_, err = sender.CreateTicketBatch(sessionID, 3)
+			assert.Equal(ErrSessionNonceLimit, errors.Cause(err))
+			assert.EqualError(err, "nonce 3 + 3 tickets > max nonce 5: "+ErrSessionNonceLimit.Error())
+
+			batch, err = sender.CreateTicketBatch(sessionID, 2)
+			require.Nil(err)
+			assert.Equal(uint32(4), batch.SenderParams[0].SenderNonce)
+			assert.Equal(uint32(5), batch.SenderParams[1].SenderNonce)
+
+			_, err = sender.CreateTicketBatch(sessionID, 1)
+			assert.Equal(ErrSessionNonceLimit, errors.Cause(err))
+
+			// A new session starts from a fresh nonce
+			sessionID = sender.StartSession(defaultTicketParams(t, RandAddress()))
+			batch, err = sender.CreateTicketBatch(sessionID, 1)
+			require.Nil(err)
+			assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+		})
+	}
+}
+
+func TestHashFunc(t *testing.T) {
 	assert := assert.New(t)
+	require := require.New(t)
 
-	sessionUntyped, ok := sender.sessions.Load(sessionID)
-	require.True(t, ok)
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	sender.cfg.HashFunc = func(ticket *Ticket) ethcommon.Hash {
+		return crypto.Keccak256Hash([]byte("v2"), ticket.Hash().Bytes())
+	}
+	am := sender.signer.(*stubSigner)
+	am.saveSignRequest = true
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
 
-	session := sessionUntyped.(*session)
-	assert.Equal(uint32(totalTickets), session.senderNonce)
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	ticket, _, err := sender.CreateTicketAtRound(sessionID, 3, [32]byte{3})
+	require.Nil(err)
 
-	uniqueNonces := make(map[uint32]bool)
-	for _, ticket := range tickets {
-		uniqueNonces[ticket.SenderNonce] = true
+	tickets := append(batch.Tickets(), ticket)
+	require.Len(am.signRequests, 3)
+	records, err := sender.IssuanceLog(sessionID)
+	require.Nil(err)
+	require.Len(records, 3)
+
+	for i, ticket := range tickets {
+		hash := sender.TicketHash(ticket)
+		assert.NotEqual(ticket.Hash(), hash)
+		assert.Equal(hash, sender.TicketHash(ticket))
+		assert.Equal(hash.Bytes(), am.signRequests[i])
+		assert.Equal(hash, records[i].Hash)
 	}
+	assert.NotEqual(sender.TicketHash(tickets[0]), sender.TicketHash(tickets[1]))
+	assert.Nil(sender.VerifyTicket(sessionID, tickets[0]))
 
-	assert.Equal(totalTickets, len(uniqueNonces))
+	// Ticket.Hash is used by default
+	sender.cfg.HashFunc = nil
+	assert.Equal(ticket.Hash(), sender.TicketHash(ticket))
+}
+
+func TestMaxFaceValue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sm := &countingSenderManager{stubSenderManager: sender.senderManager.(*stubSenderManager)}
+	sender.senderManager = sm
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	otherID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{DepositMultiplier: 4})
+	require.Nil(err)
+
+	maxFaceValue, err := sender.MaxFaceValue(sessionID)
+	require.Nil(err)
+	assert.Equal(big.NewInt(50000), maxFaceValue)
+	assert.Equal(1, sm.calls)
+
+	maxFaceValue, err = sender.MaxFaceValue(otherID)
+	require.Nil(err)
+	assert.Equal(big.NewInt(25000), maxFaceValue)
+
+	// The max face value is the value that the session's ticket params are validated against
+	for id, maxFaceValue := range map[string]int64{sessionID: 50000, otherID: 25000} {
+		session, err := sender.loadSession(id)
+		require.Nil(err)
+
+		session.ticketParams.FaceValue = big.NewInt(maxFaceValue)
+		_, err = sender.CreateTicketBatch(id, 1)
+		assert.Nil(err)
+
+		session.ticketParams.FaceValue = big.NewInt(maxFaceValue + 1)
+		_, err = sender.CreateTicketBatch(id, 1)
+		assert.EqualError(err, maxFaceValueErrStr(big.NewInt(maxFaceValue+1), big.NewInt(maxFaceValue)))
+	}
+
+	// Pending withdrawals are subtracted from the deposit if configured
+	sender.cfg.WithdrawalAction = WithdrawalSubtract
+	info := sm.info[sender.signer.Account().Address]
+	info.WithdrawRound = big.NewInt(10)
+	info.PendingWithdrawal = big.NewInt(20000)
+	maxFaceValue, err = sender.MaxFaceValue(sessionID)
+	require.Nil(err)
+	assert.Equal(big.NewInt(40000), maxFaceValue)
+
+	_, err = sender.MaxFaceValue("foo")
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.MaxFaceValue(sessionID)
+	_, ok := err.(SenderInfoError)
+	assert.True(ok)
 }
 
 func TestValidateTicketParams_EVTooHigh_ReturnsError(t *testing.T) {
@@ -440,6 +2292,46 @@ func TestValidateTicketParams_EVTooHigh_ReturnsError(t *testing.T) {
 	assert.EqualError(t, err, expErrStr)
 }
 
+func TestValidateTicketParams_EVPolicy_ReplacesMaxEVCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.maxEV = big.NewRat(100, 1)
+	tm := sender.timeManager.(*stubTimeManager)
+	senderAddr := sender.signer.Account().Address
+	sm := sender.senderManager.(*stubSenderManager)
+
+	var policyNumTickets int
+	var policyInfo SenderInfo
+	sender.cfg.EVPolicy = func(params *TicketParams, numTickets int, info SenderInfo) error {
+		policyNumTickets = numTickets
+		policyInfo = info
+		if tm.LastInitializedRound().Cmp(big.NewInt(10)) >= 0 {
+			return errors.New("round too high")
+		}
+		return nil
+	}
+
+	// EV > maxEV is accepted by the policy
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(1000)
+	ticketParams.WinProb = maxWinProb
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+	assert.Equal(1, policyNumTickets)
+	assert.Equal(*sm.info[senderAddr], policyInfo)
+
+	sessionID := sender.StartSession(ticketParams)
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	assert.Nil(err)
+	assert.Equal(3, policyNumTickets)
+
+	// Policy rejects based on the round
+	tm.round = big.NewInt(10)
+	assert.EqualError(sender.ValidateTicketParams(&ticketParams), "round too high")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "round too high")
+}
+
 func TestValidateTicketParams_FaceValueTooHigh_ReturnsError(t *testing.T) {
 	assert := assert.New(t)
 
@@ -573,7 +2465,11 @@ func defaultSender(t *testing.T) *sender {
 		Reserve:       &ReserveInfo{FundsRemaining: big.NewInt(10)},
 		WithdrawRound: big.NewInt(0),
 	}
-	s := NewSender(am, tm, sm, big.NewRat(100, 1), 2)
+	s, err := NewSender(am, tm, sm, big.NewRat(100, 1), 2, SenderConfig{})
+	if err != nil {
+		// t is nil when called from benchmarks
+		panic(err)
+	}
 	return s.(*sender)
 }
 
@@ -597,3 +2493,36 @@ func maxFaceValueErrStr(faceValue, maxFaceValue *big.Int) string {
 func maxEVErrStr(ev *big.Rat, numTickets int, maxEV *big.Rat) string {
 	return fmt.Sprintf("total ticket EV %v for %v tickets > max total ticket EV %v", ev.FloatString(5), numTickets, maxEV.FloatString(5))
 }
+
+func TestRejectSubWeiEV(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+
+	// faceValue 1000 wei with a 1 in 10000 win probability has an EV of 0.1 wei
+	params := defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(1000)
+	params.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(10000))
+
+	// Sub-wei EV is accepted unless rejecting it is enabled
+	assert.Nil(sender.ValidateTicketParams(&params))
+
+	sender.cfg.RejectSubWeiEV = true
+	err := sender.ValidateTicketParams(&params)
+	assert.Equal(ErrSubWeiEV, errors.Cause(err))
+	assert.EqualError(err, "ticket EV 0.10000: "+ErrSubWeiEV.Error())
+
+	sessionID := sender.StartSession(params)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrSubWeiEV, errors.Cause(err))
+
+	// Zero EV is rejected
+	params.WinProb = big.NewInt(0)
+	assert.Equal(ErrSubWeiEV, errors.Cause(sender.ValidateTicketParams(&params)))
+
+	// An EV of exactly 1 wei is accepted
+	params.FaceValue = big.NewInt(1)
+	params.WinProb = new(big.Int).Set(maxWinProb)
+	require.Nil(sender.ValidateTicketParams(&params))
+}
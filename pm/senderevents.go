@@ -0,0 +1,82 @@
+package pm
+
+import "sync/atomic"
+
+// defaultEventBufferSize is the size of the sender's event channel buffer
+// if no size is configured
+const defaultEventBufferSize = 100
+
+// SenderEventType identifies the type of a sender event
+type SenderEventType int
+
+const (
+	// SessionStarted is emitted when a session is started
+	SessionStarted SenderEventType = iota
+	// SessionEnded is emitted when a session is ended
+	SessionEnded
+	// TicketCreated is emitted for each ticket that is created and signed
+	TicketCreated
+	// ValidationFailed is emitted when ticket params fail validation
+	ValidationFailed
+	// RoundChanged is emitted when the sender observes a new last initialized round
+	RoundChanged
+	// SessionExhausted is emitted by the reconciler for a session that can no longer
+	// be backed by the sender's deposit and reserve
+	SessionExhausted
+	// ShadowValidationFailed is emitted when the staged policy would reject ticket params
+	// while it runs in shadow mode. The ticket params are not rejected
+	ShadowValidationFailed
+)
+
+func (t SenderEventType) String() string {
+	switch t {
+	case SessionStarted:
+		return "SessionStarted"
+	case SessionEnded:
+		return "SessionEnded"
+	case TicketCreated:
+		return "TicketCreated"
+	case ValidationFailed:
+		return "ValidationFailed"
+	case RoundChanged:
+		return "RoundChanged"
+	case SessionExhausted:
+		return "SessionExhausted"
+	case ShadowValidationFailed:
+		return "ShadowValidationFailed"
+	default:
+		return "Unknown"
+	}
+}
+
+// SenderEvent describes a change in the state of a sender
+type SenderEvent struct {
+	Type SenderEventType
+
+	// SessionID is the session that the event is for. It is empty for
+	// RoundChanged events and for ValidationFailed events that are not for a session.
+	// For ShadowValidationFailed events it is the session ID of the ticket params
+	SessionID string
+
+	// SenderNonce is the nonce of the created ticket for TicketCreated events
+	SenderNonce uint32
+
+	// Round is the new round for RoundChanged events
+	Round int64
+
+	// Err is the validation error for ValidationFailed and ShadowValidationFailed events and the reason
+	// that the session cannot be backed for SessionExhausted events
+	Err error
+}
+
+// emit notifies the sender's observer of an event and sends the event to the sender's event
+// channel without blocking. If the channel buffer is full the event is dropped
+func (s *sender) emit(event SenderEvent) {
+	s.observeEvent(event)
+
+	select {
+	case s.events <- event:
+	default:
+		atomic.AddUint64(&s.droppedEvents, 1)
+	}
+}
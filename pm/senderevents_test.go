@@ -0,0 +1,104 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderEvents_SimpleFlow(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	sm := sender.senderManager.(*stubSenderManager)
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	tm.round = new(big.Int).Add(tm.round, big.NewInt(1))
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	require.NotNil(err)
+
+	sender.EndSession(sessionID)
+	// Ending an unknown session does not emit an event
+	sender.EndSession(sessionID)
+
+	expEvents := []SenderEvent{
+		{Type: SessionStarted, SessionID: sessionID},
+		{Type: TicketCreated, SessionID: sessionID, SenderNonce: 1},
+		{Type: TicketCreated, SessionID: sessionID, SenderNonce: 2},
+		{Type: RoundChanged, Round: tm.round.Int64()},
+		{Type: TicketCreated, SessionID: sessionID, SenderNonce: 3},
+		{Type: ValidationFailed, SessionID: sessionID, Err: sm.err},
+		{Type: SessionEnded, SessionID: sessionID},
+	}
+
+	events := sender.Events()
+	for _, expEvent := range expEvents {
+		select {
+		case event := <-events:
+			assert.Equal(expEvent, event)
+		default:
+			t.Fatalf("expected event %v", expEvent.Type)
+		}
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %v", event.Type)
+	default:
+	}
+
+	assert.Zero(sender.DroppedEvents())
+
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+}
+
+func TestSenderEvents_ValidateTicketParams(t *testing.T) {
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.err = errors.New("GetSenderInfo error")
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	require.NotNil(t, sender.ValidateTicketParams(&ticketParams))
+
+	assert.Equal(t, SenderEvent{Type: ValidationFailed, Err: sm.err}, <-sender.Events())
+}
+
+func TestSenderEvents_FullBuffer_DropsEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.events = make(chan SenderEvent, 2)
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	_, err := sender.CreateTicketBatch(sessionID, 3)
+	assert.Nil(err)
+
+	assert.Equal(uint64(2), sender.DroppedEvents())
+	assert.Equal(SessionStarted, (<-sender.Events()).Type)
+	assert.Equal(TicketCreated, (<-sender.Events()).Type)
+}
+
+func TestSenderEventType_String(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("SessionStarted", SessionStarted.String())
+	assert.Equal("SessionEnded", SessionEnded.String())
+	assert.Equal("TicketCreated", TicketCreated.String())
+	assert.Equal("ValidationFailed", ValidationFailed.String())
+	assert.Equal("RoundChanged", RoundChanged.String())
+	assert.Equal("SessionExhausted", SessionExhausted.String())
+	assert.Equal("ShadowValidationFailed", ShadowValidationFailed.String())
+	assert.Equal("Unknown", SenderEventType(-1).String())
+}
@@ -0,0 +1,118 @@
+package pm
+
+import (
+	"math/big"
+	"sort"
+)
+
+// SessionDiff describes how a session differs between two senders
+type SessionDiff struct {
+	SessionID string
+
+	// A is the session as reported by the first sender or nil if the session is missing
+	A *SessionInfo
+
+	// B is the session as reported by the second sender or nil if the session is missing
+	B *SessionInfo
+
+	// Fields contains the names of the fields that differ when the session is present
+	// in both senders
+	Fields []string
+}
+
+// DiffSenders compares the sessions of two senders and returns a diff for each session
+// that is only present in one of the senders or that has a different nonce or ticket params.
+// The returned diffs are ordered by session ID and are empty if the senders have identical state
+func DiffSenders(a, b Sender) []SessionDiff {
+	aInfos := make(map[string]SessionInfo)
+	for _, info := range a.ListSessions() {
+		aInfos[info.SessionID] = info
+	}
+
+	bInfos := make(map[string]SessionInfo)
+	for _, info := range b.ListSessions() {
+		bInfos[info.SessionID] = info
+	}
+
+	var diffs []SessionDiff
+	for id, aInfo := range aInfos {
+		aInfo := aInfo
+		bInfo, ok := bInfos[id]
+		if !ok {
+			diffs = append(diffs, SessionDiff{SessionID: id, A: &aInfo})
+			continue
+		}
+
+		if fields := diffSessionInfo(&aInfo, &bInfo); len(fields) > 0 {
+			diffs = append(diffs, SessionDiff{SessionID: id, A: &aInfo, B: &bInfo, Fields: fields})
+		}
+	}
+
+	for id, bInfo := range bInfos {
+		bInfo := bInfo
+		if _, ok := aInfos[id]; !ok {
+			diffs = append(diffs, SessionDiff{SessionID: id, B: &bInfo})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].SessionID < diffs[j].SessionID })
+
+	return diffs
+}
+
+func diffSessionInfo(a, b *SessionInfo) []string {
+	var fields []string
+
+	if a.SenderNonce != b.SenderNonce {
+		fields = append(fields, "SenderNonce")
+	}
+
+	ap, bp := &a.TicketParams, &b.TicketParams
+	if ap.Recipient != bp.Recipient {
+		fields = append(fields, "Recipient")
+	}
+	if !bigIntEqual(ap.FaceValue, bp.FaceValue) {
+		fields = append(fields, "FaceValue")
+	}
+	if !bigIntEqual(ap.WinProb, bp.WinProb) {
+		fields = append(fields, "WinProb")
+	}
+	if ap.RecipientRandHash != bp.RecipientRandHash {
+		fields = append(fields, "RecipientRandHash")
+	}
+	if !bigIntEqual(ap.Seed, bp.Seed) {
+		fields = append(fields, "Seed")
+	}
+	if !bigIntEqual(ap.ExpirationBlock, bp.ExpirationBlock) {
+		fields = append(fields, "ExpirationBlock")
+	}
+	if !bigRatEqual(ap.PricePerPixel, bp.PricePerPixel) {
+		fields = append(fields, "PricePerPixel")
+	}
+	if !expirationParamsEqual(ap.ExpirationParams, bp.ExpirationParams) {
+		fields = append(fields, "ExpirationParams")
+	}
+
+	return fields
+}
+
+func bigIntEqual(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+func bigRatEqual(a, b *big.Rat) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+func expirationParamsEqual(a, b *TicketExpirationParams) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,78 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSenders_IdenticalSenders_ReturnsEmptyDiff(t *testing.T) {
+	a := defaultSender(t)
+	b := defaultSender(t)
+
+	assert.Empty(t, DiffSenders(a, b))
+
+	for i := 0; i < 3; i++ {
+		ticketParams := defaultTicketParams(t, RandAddress())
+		a.StartSession(ticketParams)
+		b.StartSession(ticketParams)
+	}
+	sessionID := a.ListSessions()[0].SessionID
+	_, err := a.CreateTicketBatch(sessionID, 2)
+	require.Nil(t, err)
+	_, err = b.CreateTicketBatch(sessionID, 2)
+	require.Nil(t, err)
+
+	assert.Empty(t, DiffSenders(a, b))
+}
+
+func TestDiffSenders_MissingSessions(t *testing.T) {
+	assert := assert.New(t)
+
+	a := defaultSender(t)
+	b := defaultSender(t)
+
+	aOnly := a.StartSession(defaultTicketParams(t, RandAddress()))
+	bOnly := b.StartSession(defaultTicketParams(t, RandAddress()))
+
+	diffs := DiffSenders(a, b)
+	assert.Len(diffs, 2)
+	for _, diff := range diffs {
+		switch diff.SessionID {
+		case aOnly:
+			assert.NotNil(diff.A)
+			assert.Nil(diff.B)
+		case bOnly:
+			assert.Nil(diff.A)
+			assert.NotNil(diff.B)
+		default:
+			t.Errorf("unexpected session in diff: %v", diff.SessionID)
+		}
+		assert.Empty(diff.Fields)
+	}
+}
+
+func TestDiffSenders_NonceAndParamsDifferences(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	a := defaultSender(t)
+	b := defaultSender(t)
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := a.StartSession(ticketParams)
+	ticketParams.FaceValue = big.NewInt(99)
+	b.StartSession(ticketParams)
+
+	_, err := a.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	diffs := DiffSenders(a, b)
+	require.Len(diffs, 1)
+	assert.Equal(sessionID, diffs[0].SessionID)
+	assert.Equal(uint32(1), diffs[0].A.SenderNonce)
+	assert.Equal(uint32(0), diffs[0].B.SenderNonce)
+	assert.Equal([]string{"SenderNonce", "FaceValue"}, diffs[0].Fields)
+}
@@ -0,0 +1,100 @@
+package pm
+
+import (
+	"sort"
+	"sync"
+)
+
+// sessionLocks serializes lifecycle operations for a session ID with ticket creation for the session ID.
+// Each session ID has its own lock so that operations for a session never block operations for other sessions.
+// Ticket creation holds the read lock of a session so that tickets can still be created concurrently for the
+// session while starting and ending the session hold the write lock. A lock is removed once it is no longer
+// held or waited for, so no lock state is left behind when sessions end
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+// sessionLock is the lock of a session ID with the number of callers that hold or wait for it
+type sessionLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// acquire returns the lock for a session ID and registers the caller as a user of the lock
+func (l *sessionLocks) acquire(sessionID string) *sessionLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locks == nil {
+		l.locks = make(map[string]*sessionLock)
+	}
+
+	lock, ok := l.locks[sessionID]
+	if !ok {
+		lock = &sessionLock{}
+		l.locks[sessionID] = lock
+	}
+	lock.refs++
+
+	return lock
+}
+
+// release unregisters a user of the lock for a session ID and removes the lock if it has no users left
+func (l *sessionLocks) release(sessionID string, lock *sessionLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, sessionID)
+	}
+}
+
+// rlock acquires the read lock for a session ID and returns a function that releases it
+func (l *sessionLocks) rlock(sessionID string) func() {
+	lock := l.acquire(sessionID)
+	lock.RLock()
+
+	return func() {
+		lock.RUnlock()
+		l.release(sessionID, lock)
+	}
+}
+
+// lock acquires the write lock for a session ID and returns a function that releases it
+func (l *sessionLocks) lock(sessionID string) func() {
+	lock := l.acquire(sessionID)
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+		l.release(sessionID, lock)
+	}
+}
+
+// lockAll acquires the write locks for a list of session IDs and returns a function that releases them.
+// Each session ID is locked once and session IDs are locked in sorted order so that concurrent calls
+// cannot deadlock
+func (l *sessionLocks) lockAll(sessionIDs []string) func() {
+	seen := make(map[string]bool)
+	var sorted []string
+	for _, sessionID := range sessionIDs {
+		if !seen[sessionID] {
+			seen[sessionID] = true
+			sorted = append(sorted, sessionID)
+		}
+	}
+	sort.Strings(sorted)
+
+	unlocks := make([]func(), len(sorted))
+	for i, sessionID := range sorted {
+		unlocks[i] = l.lock(sessionID)
+	}
+
+	return func() {
+		for j := len(unlocks) - 1; j >= 0; j-- {
+			unlocks[j]()
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package pm
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowSessionStore delays each Save call
+type slowSessionStore struct {
+	*stubSessionStore
+	delay time.Duration
+}
+
+func (ss *slowSessionStore) Save(sessionID string, senderNonce uint32) error {
+	time.Sleep(ss.delay)
+	return ss.stubSessionStore.Save(sessionID, senderNonce)
+}
+
+func TestSessionLocks_PerSession(t *testing.T) {
+	assert := assert.New(t)
+
+	var locks sessionLocks
+
+	unlockFoo := locks.lock("foo")
+	assert.Len(locks.locks, 1)
+
+	// An unrelated session is not blocked by the write lock of another session
+	done := make(chan struct{})
+	go func() {
+		unlockBar := locks.rlock("bar")
+		unlockBar()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock of unrelated session blocked")
+	}
+
+	// The same session is blocked until the write lock is released
+	acquired := make(chan struct{})
+	go func() {
+		unlock := locks.rlock("foo")
+		close(acquired)
+		unlock()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("read lock acquired while write lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockFoo()
+	<-acquired
+
+	// Locks are removed once they are released
+	locks.mu.Lock()
+	assert.Empty(locks.locks)
+	locks.mu.Unlock()
+}
+
+func TestSessionLocks_LockAll(t *testing.T) {
+	assert := assert.New(t)
+
+	var locks sessionLocks
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lockAll([]string{"a", "b", "c", "a"})
+			unlock()
+		}()
+		go func() {
+			defer wg.Done()
+			unlock := locks.lockAll([]string{"c", "b", "a"})
+			unlock()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lockAll deadlocked")
+	}
+
+	assert.Empty(locks.locks)
+}
+
+func TestSessionLifecycle_ConcurrentStartEndCreate(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	// Slow saves widen the window between a nonce being handed out and persisted
+	sender.cfg.SessionStore = &slowSessionStore{stubSessionStore: newStubSessionStore(), delay: 100 * time.Microsecond}
+
+	var params []TicketParams
+	for i := 0; i < 4; i++ {
+		params = append(params, defaultTicketParams(t, RandAddress()))
+	}
+
+	var (
+		nonces = make(map[string]map[uint32]bool)
+		dups   []uint32
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 300; i++ {
+				p := params[r.Intn(len(params))]
+				sessionID := p.RecipientRandHash.Hex()
+
+				switch r.Intn(4) {
+				case 0:
+					sender.StartSession(p)
+				case 1:
+					sender.EndSession(sessionID)
+				default:
+					batch, err := sender.CreateTicketBatch(sessionID, r.Intn(3)+1)
+					if err != nil {
+						if errors.Cause(err) != ErrUnknownSession {
+							t.Error(err)
+						}
+						continue
+					}
+
+					mu.Lock()
+					if nonces[sessionID] == nil {
+						nonces[sessionID] = make(map[uint32]bool)
+					}
+					for _, sp := range batch.SenderParams {
+						if nonces[sessionID][sp.SenderNonce] {
+							dups = append(dups, sp.SenderNonce)
+						}
+						nonces[sessionID][sp.SenderNonce] = true
+					}
+					mu.Unlock()
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	// Nonces are never reused for a session ID across restarts
+	assert.Empty(dups)
+}
@@ -0,0 +1,21 @@
+package pm
+
+// SessionStore is an interface which describes an object capable
+// of persisting the nonces of sender sessions so that nonces are
+// not reused for a session after a restart
+type SessionStore interface {
+	// Save persists the highest nonce handed out for a session ID.
+	// Implementations should ignore nonces lower than the currently persisted nonce
+	Save(sessionID string, senderNonce uint32) error
+
+	// Load fetches the persisted nonce for a session ID. If no nonce
+	// is persisted for the session ID, 0 is returned
+	Load(sessionID string) (uint32, error)
+}
+
+// SessionStoreFlusher is implemented by a SessionStore that buffers saved nonces
+// before persisting them durably
+type SessionStoreFlusher interface {
+	// Flush durably persists the saved nonce for a session ID
+	Flush(sessionID string) error
+}
@@ -0,0 +1,128 @@
+package pm
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidSignature is returned for a signature that does not have the layout of its signature format
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureFormat is the layout of the ticket signatures returned by a sender
+type SignatureFormat int
+
+const (
+	// SignatureFormatSigner returns signatures as they are produced by the signer without checking them
+	SignatureFormatSigner SignatureFormat = iota
+	// SignatureFormatRSV is the 65 byte R || S || V layout with a recovery ID V of 27 or 28, which is the
+	// layout produced by the node's signer and expected by the TicketBroker contract
+	SignatureFormatRSV
+	// SignatureFormatRSVZero is the 65 byte R || S || V layout with a recovery ID V of 0 or 1
+	SignatureFormatRSVZero
+	// SignatureFormatVRS is the 65 byte V || R || S layout with a recovery ID V of 27 or 28
+	SignatureFormatVRS
+	// SignatureFormatCompact is the 64 byte R || YParityAndS layout described in EIP-2098 in which the
+	// recovery ID is stored in the highest bit of S
+	SignatureFormatCompact
+)
+
+const (
+	signatureSize        = 65
+	compactSignatureSize = 64
+)
+
+// signature is a secp256k1 signature with a recovery ID of 0 or 1
+type signature struct {
+	r, s [32]byte
+	v    byte
+}
+
+// ConvertSignature converts a signature from one signature format to another. Signatures in
+// SignatureFormatSigner are expected to have the 65 byte R || S || V layout with a recovery ID V of 0, 1, 27 or 28
+func ConvertSignature(sig []byte, from, to SignatureFormat) ([]byte, error) {
+	parsed, err := parseSignature(sig, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.encode(to)
+}
+
+func parseSignature(sig []byte, format SignatureFormat) (*signature, error) {
+	var parsed signature
+
+	switch format {
+	case SignatureFormatSigner, SignatureFormatRSV, SignatureFormatRSVZero:
+		if len(sig) != signatureSize {
+			return nil, errors.Wrapf(ErrInvalidSignature, "signature length %v != %v", len(sig), signatureSize)
+		}
+		copy(parsed.r[:], sig[0:32])
+		copy(parsed.s[:], sig[32:64])
+		parsed.v = sig[64]
+	case SignatureFormatVRS:
+		if len(sig) != signatureSize {
+			return nil, errors.Wrapf(ErrInvalidSignature, "signature length %v != %v", len(sig), signatureSize)
+		}
+		parsed.v = sig[0]
+		copy(parsed.r[:], sig[1:33])
+		copy(parsed.s[:], sig[33:65])
+	case SignatureFormatCompact:
+		if len(sig) != compactSignatureSize {
+			return nil, errors.Wrapf(ErrInvalidSignature, "signature length %v != %v", len(sig), compactSignatureSize)
+		}
+		copy(parsed.r[:], sig[0:32])
+		copy(parsed.s[:], sig[32:64])
+		parsed.v = parsed.s[0] >> 7
+		parsed.s[0] &= 0x7f
+		return &parsed, nil
+	default:
+		return nil, fmt.Errorf("unknown signature format %v", format)
+	}
+
+	switch {
+	case parsed.v == 0 || parsed.v == 1:
+		if format == SignatureFormatRSV || format == SignatureFormatVRS {
+			return nil, errors.Wrapf(ErrInvalidSignature, "signature v value %v must be 27 or 28", parsed.v)
+		}
+	case parsed.v == 27 || parsed.v == 28:
+		if format == SignatureFormatRSVZero {
+			return nil, errors.Wrapf(ErrInvalidSignature, "signature v value %v must be 0 or 1", parsed.v)
+		}
+		parsed.v -= 27
+	default:
+		return nil, errors.Wrapf(ErrInvalidSignature, "invalid signature v value %v", parsed.v)
+	}
+
+	return &parsed, nil
+}
+
+func (sig *signature) encode(format SignatureFormat) ([]byte, error) {
+	switch format {
+	case SignatureFormatSigner, SignatureFormatRSV:
+		return append(append(sig.r[:], sig.s[:]...), sig.v+27), nil
+	case SignatureFormatRSVZero:
+		return append(append(sig.r[:], sig.s[:]...), sig.v), nil
+	case SignatureFormatVRS:
+		return append([]byte{sig.v + 27}, append(sig.r[:], sig.s[:]...)...), nil
+	case SignatureFormatCompact:
+		// The highest bit of S is always 0 for signatures with a low S value
+		if sig.s[0]&0x80 != 0 {
+			return nil, errors.Wrap(ErrInvalidSignature, "signature s value too high")
+		}
+		yParityAndS := sig.s
+		yParityAndS[0] |= sig.v << 7
+		return append(sig.r[:], yParityAndS[:]...), nil
+	default:
+		return nil, fmt.Errorf("unknown signature format %v", format)
+	}
+}
+
+// formatSignature converts a signature produced by a signer to the configured SignatureFormat
+func (s *sender) formatSignature(sig []byte) ([]byte, error) {
+	if s.cfg.SignatureFormat == SignatureFormatSigner {
+		return sig, nil
+	}
+
+	return ConvertSignature(sig, SignatureFormatSigner, s.cfg.SignatureFormat)
+}
@@ -0,0 +1,156 @@
+package pm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var signatureFormats = []SignatureFormat{
+	SignatureFormatRSV,
+	SignatureFormatRSVZero,
+	SignatureFormatVRS,
+	SignatureFormatCompact,
+}
+
+func TestSignatureFormat_CreateTicketBatch(t *testing.T) {
+	for _, format := range signatureFormats {
+		t.Run(fmt.Sprintf("Format=%v", format), func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sender := defaultSender(t)
+			info := sender.senderManager.(*stubSenderManager).info[sender.signer.Account().Address]
+			signer := newStubKeySigner()
+			sender.signer = signer
+			sender.senderManager.(*stubSenderManager).info[signer.Account().Address] = info
+			sender.cfg.SignatureFormat = format
+
+			sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+			batch, err := sender.CreateTicketBatch(sessionID, 2)
+			require.Nil(err)
+
+			for i, ticket := range batch.Tickets() {
+				sig := batch.SenderParams[i].Sig
+				if format == SignatureFormatCompact {
+					assert.Len(sig, 64)
+				} else {
+					assert.Len(sig, 65)
+				}
+
+				// The signature still recovers the signer after it is converted back
+				rsv, err := ConvertSignature(sig, format, SignatureFormatRSV)
+				require.Nil(err)
+				assert.True(crypto.VerifySig(signer.Account().Address, ticket.Hash().Bytes(), rsv))
+			}
+		})
+	}
+}
+
+func TestConvertSignature(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	signer := newStubKeySigner()
+
+	// Convert signatures until signatures with both recovery IDs were converted
+	seen := make(map[byte]bool)
+	for len(seen) < 2 {
+		msg := RandHash().Bytes()
+		raw, err := signer.Sign(msg)
+		require.Nil(err)
+		seen[raw[64]] = true
+
+		for _, from := range signatureFormats {
+			sig, err := ConvertSignature(raw, SignatureFormatSigner, from)
+			require.Nil(err)
+
+			for _, to := range signatureFormats {
+				converted, err := ConvertSignature(sig, from, to)
+				require.Nil(err, "%v -> %v", from, to)
+
+				rsv, err := ConvertSignature(converted, to, SignatureFormatRSV)
+				require.Nil(err)
+				assert.Equal(raw, rsv, "%v -> %v", from, to)
+				assert.True(crypto.VerifySig(signer.Account().Address, msg, rsv))
+			}
+		}
+	}
+
+	raw, err := signer.Sign(RandHash().Bytes())
+	require.Nil(err)
+
+	zero, err := ConvertSignature(raw, SignatureFormatSigner, SignatureFormatRSVZero)
+	require.Nil(err)
+	assert.Equal(raw[64]-27, zero[64])
+	assert.Equal(raw[:64], zero[:64])
+
+	vrs, err := ConvertSignature(raw, SignatureFormatSigner, SignatureFormatVRS)
+	require.Nil(err)
+	assert.Equal(raw[64], vrs[0])
+	assert.Equal(raw[:64], vrs[1:])
+
+	// Signers that return a recovery ID of 0 or 1 are supported
+	rsv, err := ConvertSignature(zero, SignatureFormatSigner, SignatureFormatRSV)
+	require.Nil(err)
+	assert.Equal(raw, rsv)
+}
+
+func TestConvertSignature_Errors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	raw, err := newStubKeySigner().Sign(RandHash().Bytes())
+	require.Nil(err)
+
+	_, err = ConvertSignature(raw[:64], SignatureFormatSigner, SignatureFormatRSV)
+	assert.Equal(ErrInvalidSignature, errors.Cause(err))
+	assert.EqualError(err, "signature length 64 != 65: invalid signature")
+
+	_, err = ConvertSignature(raw, SignatureFormatCompact, SignatureFormatRSV)
+	assert.EqualError(err, "signature length 65 != 64: invalid signature")
+
+	invalidV := append([]byte{}, raw...)
+	invalidV[64] = 2
+	_, err = ConvertSignature(invalidV, SignatureFormatSigner, SignatureFormatRSV)
+	assert.EqualError(err, "invalid signature v value 2: invalid signature")
+
+	zero, err := ConvertSignature(raw, SignatureFormatSigner, SignatureFormatRSVZero)
+	require.Nil(err)
+	_, err = ConvertSignature(zero, SignatureFormatRSV, SignatureFormatRSVZero)
+	assert.Equal(ErrInvalidSignature, errors.Cause(err))
+	_, err = ConvertSignature(raw, SignatureFormatRSVZero, SignatureFormatRSV)
+	assert.Equal(ErrInvalidSignature, errors.Cause(err))
+
+	// A high S value cannot be compacted
+	highS := append([]byte{}, raw...)
+	highS[32] |= 0x80
+	_, err = ConvertSignature(highS, SignatureFormatSigner, SignatureFormatCompact)
+	assert.EqualError(err, "signature s value too high: invalid signature")
+
+	_, err = ConvertSignature(raw, SignatureFormatSigner, SignatureFormat(100))
+	assert.EqualError(err, "unknown signature format 100")
+}
+
+func TestSignatureFormat_InvalidSignerOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.signer.(*stubSigner).signResponse = RandBytes(42)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// The signer's output is returned unchanged by default
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+	assert.Len(batch.SenderParams[0].Sig, 42)
+
+	sender.cfg.SignatureFormat = SignatureFormatRSV
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok := err.(SignerError)
+	assert.True(ok)
+	assert.Equal(ErrInvalidSignature, errors.Cause(err))
+}
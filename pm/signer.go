@@ -1,6 +1,15 @@
 package pm
 
-import "github.com/ethereum/go-ethereum/accounts"
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/pkg/errors"
+)
+
+// ErrNonDeterministicSignature is returned by a deterministic signer when the underlying
+// signer produces different signatures for the same message
+var ErrNonDeterministicSignature = errors.New("signer produced non-deterministic signature")
 
 // Signer supports identifying as an Ethereum account owner, by providing the
 // Account and enabling message signing.
@@ -8,3 +17,36 @@ type Signer interface {
 	Sign(msg []byte) ([]byte, error)
 	Account() accounts.Account
 }
+
+// deterministicSigner wraps a Signer and verifies that the signatures it produces
+// are reproducible for the same message and key
+type deterministicSigner struct {
+	Signer
+}
+
+// NewDeterministicSigner returns a Signer that signs each message twice with the provided signer
+// and returns ErrNonDeterministicSignature if the two signatures differ. An ECDSA signer using
+// RFC6979 nonces will always produce the same signature for the same message and key so a
+// mismatch indicates a faulty signer
+func NewDeterministicSigner(signer Signer) Signer {
+	return &deterministicSigner{Signer: signer}
+}
+
+// Sign signs a message and verifies that the signature is deterministic
+func (s *deterministicSigner) Sign(msg []byte) ([]byte, error) {
+	sig, err := s.Signer.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := s.Signer.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(sig, check) {
+		return nil, ErrNonDeterministicSignature
+	}
+
+	return sig, nil
+}
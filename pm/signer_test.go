@@ -0,0 +1,50 @@
+package pm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nonDeterministicSigner struct {
+	stubSigner
+}
+
+func (s *nonDeterministicSigner) Sign(msg []byte) ([]byte, error) {
+	return RandBytes(65), nil
+}
+
+func TestDeterministicSigner_DeterministicSigner_ReturnsSignature(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	keySigner := newStubKeySigner()
+	signer := NewDeterministicSigner(keySigner)
+	assert.Equal(keySigner.Account(), signer.Account())
+
+	hash := RandHash()
+	sig0, err := signer.Sign(hash.Bytes())
+	require.Nil(err)
+	sig1, err := signer.Sign(hash.Bytes())
+	require.Nil(err)
+	assert.Equal(sig0, sig1)
+
+	expSig, err := keySigner.Sign(hash.Bytes())
+	require.Nil(err)
+	assert.Equal(expSig, sig0)
+}
+
+func TestDeterministicSigner_NonDeterministicSigner_ReturnsError(t *testing.T) {
+	signer := NewDeterministicSigner(&nonDeterministicSigner{})
+
+	_, err := signer.Sign(RandHash().Bytes())
+	assert.Equal(t, ErrNonDeterministicSignature, err)
+}
+
+func TestDeterministicSigner_SignError_ReturnsError(t *testing.T) {
+	signer := NewDeterministicSigner(&stubSigner{signShouldFail: true})
+
+	_, err := signer.Sign(RandHash().Bytes())
+	assert.EqualError(t, err, "stub returning error as requested")
+}
@@ -0,0 +1,119 @@
+package pm
+
+import (
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// SignerSelector is an interface which describes an object capable of selecting the signer that signs
+// the tickets of a new session when the sender is configured with multiple funded accounts
+type SignerSelector interface {
+	// SelectSigner returns one of signers to sign the tickets of a session started with ticketParams.
+	// senderInfo fetches the info of the account of a signer
+	SelectSigner(ticketParams *TicketParams, signers []Signer, senderInfo func(Signer) (*SenderInfo, error)) (Signer, error)
+}
+
+// MaxDepositSignerSelector selects the signer whose account has the highest deposit that is not pending
+// withdrawal, which balances the usage of the accounts across sessions. Signers whose info cannot
+// be fetched are skipped. Ties are broken in favor of the signer that comes first
+type MaxDepositSignerSelector struct{}
+
+// SelectSigner returns the signer whose account has the highest available deposit
+func (MaxDepositSignerSelector) SelectSigner(ticketParams *TicketParams, signers []Signer, senderInfo func(Signer) (*SenderInfo, error)) (Signer, error) {
+	var (
+		selected   Signer
+		maxDeposit *big.Int
+	)
+	for _, signer := range signers {
+		info, err := senderInfo(signer)
+		if err == nil && info == nil {
+			err = errors.New("missing sender info")
+		}
+		if err != nil {
+			glog.Warningf("Error fetching sender info for signer selection sender=%v err=%v", signer.Account().Address.Hex(), err)
+			continue
+		}
+
+		deposit := availableDeposit(info)
+		if selected == nil || deposit.Cmp(maxDeposit) > 0 {
+			selected = signer
+			maxDeposit = deposit
+		}
+	}
+
+	if selected == nil {
+		return nil, errors.New("unable to fetch sender info for any signer")
+	}
+
+	return selected, nil
+}
+
+// availableDeposit returns the deposit of a sender that is not pending withdrawal. If the amount being
+// withdrawn is not known, the whole deposit is considered withdrawn
+func availableDeposit(info *SenderInfo) *big.Int {
+	if info.Deposit == nil {
+		return big.NewInt(0)
+	}
+
+	if !withdrawalPending(info) {
+		return info.Deposit
+	}
+
+	if info.PendingWithdrawal == nil {
+		return big.NewInt(0)
+	}
+
+	available := new(big.Int).Sub(info.Deposit, info.PendingWithdrawal)
+	if available.Sign() < 0 {
+		return big.NewInt(0)
+	}
+
+	return available
+}
+
+// selectSigner returns the signer for a new session using the configured SignerSelector.
+// nil is returned if no SignerSelector is configured
+func (s *sender) selectSigner(ticketParams *TicketParams) (Signer, error) {
+	if s.cfg.SignerSelector == nil {
+		return nil, nil
+	}
+
+	senderInfo := func(signer Signer) (*SenderInfo, error) {
+		return s.getSenderInfoFor(signer.Account().Address)
+	}
+
+	signer, err := s.cfg.SignerSelector.SelectSigner(ticketParams, s.signers, senderInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "error selecting signer")
+	}
+
+	if s.signerForAddress(signer.Account().Address) == nil {
+		return nil, fmt.Errorf("selected signer %v is not a signer of the sender", signer.Account().Address.Hex())
+	}
+
+	return signer, nil
+}
+
+// signerForAddress returns the signer of the sender for an address or nil if the sender has no signer for the address
+func (s *sender) signerForAddress(addr ethcommon.Address) Signer {
+	for _, signer := range s.signers {
+		if signer.Account().Address == addr {
+			return signer
+		}
+	}
+
+	return nil
+}
+
+// sessionSigner returns the signer of a session's tickets
+func (s *sender) sessionSigner(session *session) Signer {
+	if session.signer == nil {
+		return s.signer
+	}
+
+	return session.signer
+}
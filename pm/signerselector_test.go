@@ -0,0 +1,193 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiSignerSender returns a sender with a default signer and an additional signer whose accounts have the provided deposits
+func multiSignerSender(t *testing.T, deposits [2]int64, cfg SenderConfig) (*sender, [2]*stubKeySigner) {
+	signers := [2]*stubKeySigner{newStubKeySigner(), newStubKeySigner()}
+	tm := &stubTimeManager{round: big.NewInt(5), blkHash: [32]byte{5}, lastSeenBlock: big.NewInt(0)}
+	sm := newStubSenderManager()
+	for i, signer := range signers {
+		sm.info[signer.Account().Address] = &SenderInfo{
+			Deposit:       big.NewInt(deposits[i]),
+			Reserve:       &ReserveInfo{FundsRemaining: big.NewInt(10)},
+			WithdrawRound: big.NewInt(0),
+		}
+	}
+
+	cfg.Signers = []Signer{signers[1]}
+	s, err := NewSender(signers[0], tm, sm, big.NewRat(100, 1), 2, cfg)
+	require.Nil(t, err)
+	return s.(*sender), signers
+}
+
+func TestMaxDepositSignerSelector(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	signers := []Signer{newStubKeySigner(), newStubKeySigner(), newStubKeySigner()}
+	infos := map[ethcommon.Address]*SenderInfo{}
+	errs := map[ethcommon.Address]error{}
+	senderInfo := func(signer Signer) (*SenderInfo, error) {
+		addr := signer.Account().Address
+		return infos[addr], errs[addr]
+	}
+	setDeposit := func(i int, deposit int64) {
+		infos[signers[i].Account().Address] = &SenderInfo{Deposit: big.NewInt(deposit), WithdrawRound: big.NewInt(0)}
+	}
+
+	setDeposit(0, 100)
+	setDeposit(1, 300)
+	setDeposit(2, 200)
+	selected, err := MaxDepositSignerSelector{}.SelectSigner(nil, signers, senderInfo)
+	require.Nil(err)
+	assert.Equal(signers[1], selected)
+
+	// Ties are broken in favor of the first signer
+	setDeposit(2, 300)
+	selected, err = MaxDepositSignerSelector{}.SelectSigner(nil, signers, senderInfo)
+	require.Nil(err)
+	assert.Equal(signers[1], selected)
+
+	// Deposit pending withdrawal is not available
+	infos[signers[1].Account().Address].PendingWithdrawal = big.NewInt(250)
+	selected, err = MaxDepositSignerSelector{}.SelectSigner(nil, signers, senderInfo)
+	require.Nil(err)
+	assert.Equal(signers[2], selected)
+
+	infos[signers[2].Account().Address].WithdrawRound = big.NewInt(5)
+	selected, err = MaxDepositSignerSelector{}.SelectSigner(nil, signers, senderInfo)
+	require.Nil(err)
+	assert.Equal(signers[0], selected)
+
+	// Signers without info are skipped
+	errs[signers[0].Account().Address] = errors.New("GetSenderInfo error")
+	selected, err = MaxDepositSignerSelector{}.SelectSigner(nil, signers, senderInfo)
+	require.Nil(err)
+	assert.Equal(signers[1], selected)
+
+	delete(infos, signers[1].Account().Address)
+	delete(infos, signers[2].Account().Address)
+	_, err = MaxDepositSignerSelector{}.SelectSigner(nil, signers, senderInfo)
+	assert.EqualError(err, "unable to fetch sender info for any signer")
+}
+
+func TestStartSession_SignerSelector_SelectsHigherDeposit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, signers := multiSignerSender(t, [2]int64{1000, 100000}, SenderConfig{SignerSelector: MaxDepositSignerSelector{}})
+	selected := signers[1].Account().Address
+
+	// The face value is only backed by the deposit of the selected account
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(10000)
+	sessionID, err := sender.StartSessionWithPolicy(ticketParams, SessionPolicy{})
+	require.Nil(err)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	assert.Equal(selected, batch.Sender)
+	for i, ticket := range batch.Tickets() {
+		assert.Equal(selected, ticket.Sender)
+		sig, err := signers[1].Sign(ticket.Hash().Bytes())
+		require.Nil(err)
+		assert.Equal(sig, batch.SenderParams[i].Sig)
+	}
+
+	maxFaceValue, err := sender.MaxFaceValue(sessionID)
+	require.Nil(err)
+	assert.Equal(big.NewInt(50000), maxFaceValue)
+
+	// Sessions keep their signer when the deposits change
+	sender.senderManager.(*stubSenderManager).info[signers[0].Account().Address].Deposit = big.NewInt(200000)
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(selected, batch.Sender)
+
+	ticketParams = defaultTicketParams(t, RandAddress())
+	sessionID = sender.StartSession(ticketParams)
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(signers[0].Account().Address, batch.Sender)
+}
+
+func TestStartSession_NoSignerSelector_UsesSenderSigner(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, signers := multiSignerSender(t, [2]int64{100000, 200000}, SenderConfig{})
+
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(signers[0].Account().Address, batch.Sender)
+}
+
+type stubSignerSelector struct {
+	signer Signer
+	err    error
+}
+
+func (s *stubSignerSelector) SelectSigner(ticketParams *TicketParams, signers []Signer, senderInfo func(Signer) (*SenderInfo, error)) (Signer, error) {
+	return s.signer, s.err
+}
+
+func TestStartSession_SignerSelector_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	selector := &stubSignerSelector{err: errors.New("SelectSigner error")}
+	sender, _ := multiSignerSender(t, [2]int64{100000, 100000}, SenderConfig{SignerSelector: selector})
+
+	sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+	assert.EqualError(err, "error selecting signer: SelectSigner error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	unknown := newStubKeySigner()
+	selector.signer, selector.err = unknown, nil
+	_, err = sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+	assert.EqualError(err, "selected signer "+unknown.Account().Address.Hex()+" is not a signer of the sender")
+}
+
+func TestSignBuilt_MultipleSigners(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	selector := &stubSignerSelector{}
+	sender, signers := multiSignerSender(t, [2]int64{100000, 100000}, SenderConfig{SignerSelector: selector})
+
+	var sessionIDs [2]string
+	for i, signer := range signers {
+		selector.signer = signer
+		sessionID, err := sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+		require.Nil(err)
+		sessionIDs[i] = sessionID
+	}
+
+	for i, signer := range signers {
+		ticket, _, err := sender.BuildTicket(sessionIDs[i])
+		require.Nil(err)
+		assert.Equal(signer.Account().Address, ticket.Sender)
+
+		sig, err := sender.SignBuilt(ticket)
+		require.Nil(err)
+		expSig, err := signer.Sign(ticket.Hash().Bytes())
+		require.Nil(err)
+		assert.Equal(expSig, sig)
+
+		// A ticket is not signed for a session by the signer of another session
+		other := *ticket
+		other.Sender = signers[1-i].Account().Address
+		_, err = sender.SignBuilt(&other)
+		assert.EqualError(err, "ticket sender "+other.Sender.Hex()+" is not the signer of session: "+sessionIDs[i])
+	}
+}
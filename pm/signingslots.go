@@ -0,0 +1,23 @@
+package pm
+
+// newSigningSlots returns a semaphore with limit slots or nil if limit is not positive
+func newSigningSlots(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+
+	return make(chan struct{}, limit)
+}
+
+// acquireSigningSlot blocks until a slot for signing a batch of numTickets tickets is available if
+// MaxConcurrentBatches is set and returns a function that releases the slot. Single ticket batches
+// do not wait for a slot unless ThrottleSingleTickets is set
+func (s *sender) acquireSigningSlot(numTickets int) func() {
+	if s.signingSlots == nil || (numTickets == 1 && !s.cfg.ThrottleSingleTickets) {
+		return func() {}
+	}
+
+	s.signingSlots <- struct{}{}
+
+	return func() { <-s.signingSlots }
+}
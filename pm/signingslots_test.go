@@ -0,0 +1,127 @@
+package pm
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencySigner records the maximum number of concurrent Sign calls
+type concurrencySigner struct {
+	stubSigner
+	inFlight    int
+	maxInFlight int
+	mu          sync.Mutex
+}
+
+func (s *concurrencySigner) Sign(msg []byte) ([]byte, error) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mu.Unlock()
+
+	time.Sleep(50 * time.Microsecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+
+	return s.signResponse, nil
+}
+
+func TestMaxConcurrentBatches(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxConcurrentBatches = 3
+	sender.signingSlots = newSigningSlots(3)
+	signer := &concurrencySigner{stubSigner: *sender.signer.(*stubSigner)}
+	sender.signer = signer
+
+	var sessionIDs []string
+	for i := 0; i < 5; i++ {
+		sessionIDs = append(sessionIDs, sender.StartSession(defaultTicketParams(t, RandAddress())))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(sessionID string, split bool) {
+			defer wg.Done()
+
+			var err error
+			if split {
+				_, err = sender.CreateBatchSplitByRound(sessionID, 50)
+			} else {
+				_, err = sender.CreateTicketBatch(sessionID, 50)
+			}
+			assert.Nil(err)
+		}(sessionIDs[i%len(sessionIDs)], i%2 == 0)
+	}
+	wg.Wait()
+
+	// Tickets in a batch are signed one at a time so concurrent Sign calls are concurrent batches
+	assert.True(signer.maxInFlight <= 3, "max in flight %v > 3", signer.maxInFlight)
+	assert.True(signer.maxInFlight > 0)
+	assert.Len(sender.signingSlots, 0)
+}
+
+func TestMaxConcurrentBatches_SingleTickets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxConcurrentBatches = 1
+	sender.signingSlots = newSigningSlots(1)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	// Occupy the only slot
+	release := sender.acquireSigningSlot(2)
+
+	// Single tickets do not wait for a slot by default
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	_, _, err = sender.CreateTicketAtRound(sessionID, 5, [32]byte{5})
+	require.Nil(err)
+
+	// Batches wait for a slot
+	done := make(chan error)
+	go func() {
+		_, err := sender.CreateTicketBatch(sessionID, 2)
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("batch created without a signing slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-done:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("batch not created after signing slot was released")
+	}
+
+	// Single tickets wait for a slot if configured
+	sender.cfg.ThrottleSingleTickets = true
+	release = sender.acquireSigningSlot(2)
+	go func() {
+		_, err := sender.CreateTicketBatch(sessionID, 1)
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("single ticket created without a signing slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	assert.Nil(<-done)
+}
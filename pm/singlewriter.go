@@ -0,0 +1,131 @@
+package pm
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// SingleWriterSession creates tickets for a session from a single goroutine. Instead of allocating the nonces of
+// each batch from the session, it reserves blocks of nonces from the session and hands out the nonces of a block
+// with plain increments, so the session's nonce is only updated and persisted once per block.
+//
+// A SingleWriterSession is not safe for concurrent use: all of its methods must be called from the same goroutine,
+// or from goroutines that are synchronized with each other. Tickets must not be created for the session with the
+// Sender's methods while the SingleWriterSession is in use, since nonces allocated by other callers while a block
+// is reserved are not handed back when the block is released. The session can still be ended from other goroutines.
+// Close must be called once the SingleWriterSession is no longer used so that the unused nonces of the reserved
+// block are given back to the session
+type SingleWriterSession struct {
+	sender    *sender
+	sessionID string
+	blockSize int
+
+	// session is the session that the block was reserved from
+	session *session
+	// next is the next nonce of the reserved block and last is the last nonce of the block. There are no
+	// nonces left in the block if next > last
+	next, last uint32
+}
+
+// SingleWriter returns a SingleWriterSession that creates tickets for a session and reserves nonces from the session
+// in blocks of blockSize nonces. SingleWriterSession is not supported if StrictSequential is enabled because the nonces
+// of a block are allocated before the tickets that use them are signed
+func (s *sender) SingleWriter(sessionID string, blockSize int) (*SingleWriterSession, error) {
+	if blockSize < 1 {
+		return nil, errors.New("block size must be greater than 0")
+	}
+
+	if s.cfg.StrictSequential {
+		return nil, errors.New("single writer sessions are not supported with StrictSequential")
+	}
+
+	if _, err := s.loadSession(sessionID); err != nil {
+		return nil, err
+	}
+
+	return &SingleWriterSession{
+		sender:    s,
+		sessionID: sessionID,
+		blockSize: blockSize,
+	}, nil
+}
+
+// CreateTicketBatch returns a ticket batch of the specified size like Sender.CreateTicketBatch
+func (w *SingleWriterSession) CreateTicketBatch(size int) (*TicketBatch, error) {
+	return w.sender.createTicketBatch(context.Background(), w.sessionID, size, w.issueNonces)
+}
+
+// Close gives the unused nonces of the reserved block back to the session unless nonces were allocated for the
+// session after the block was reserved
+func (w *SingleWriterSession) Close() {
+	w.releaseBlock()
+}
+
+// issueNonces hands out numTickets consecutive nonces of the reserved block and calls sign with the first nonce.
+// A new block is reserved if the reserved block does not have enough nonces left. The nonces are only handed out
+// if sign succeeds so that they are used for the next batch otherwise
+func (w *SingleWriterSession) issueNonces(sessionID string, session *session, numTickets int, sign func(firstNonce uint32) error) error {
+	// The session was ended and started again since the block was reserved
+	if session != w.session {
+		w.session = session
+		w.next, w.last = 1, 0
+	}
+
+	if uint64(w.last)+1 < uint64(w.next)+uint64(numTickets) {
+		if err := w.reserveBlock(numTickets); err != nil {
+			return err
+		}
+	}
+
+	firstNonce := w.next
+	if err := session.recentNonces.check(firstNonce, numTickets); err != nil {
+		return err
+	}
+
+	if err := sign(firstNonce); err != nil {
+		return err
+	}
+
+	w.next += uint32(numTickets)
+	session.recentNonces.add(firstNonce, numTickets)
+	session.nonceFilter.add(firstNonce, numTickets)
+	atomic.AddUint64(&session.ticketsCreated, uint64(numTickets))
+
+	return nil
+}
+
+// reserveBlock releases the reserved block and reserves a new block with at least numTickets nonces
+func (w *SingleWriterSession) reserveBlock(numTickets int) error {
+	w.releaseBlock()
+
+	size := w.blockSize
+	if size < numTickets {
+		size = numTickets
+	}
+
+	lastNonce, err := w.sender.reserveNonces(w.sessionID, w.session, size)
+	if errors.Cause(err) == ErrSessionNonceLimit && size > numTickets {
+		// Reserve only the nonces that are needed if a full block would exceed MaxNoncePerSession
+		size = numTickets
+		lastNonce, err = w.sender.reserveNonces(w.sessionID, w.session, size)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.next, w.last = lastNonce-uint32(size)+1, lastNonce
+
+	return nil
+}
+
+// releaseBlock gives the unused nonces of the reserved block back to the session
+func (w *SingleWriterSession) releaseBlock() {
+	if w.session == nil || w.next > w.last {
+		return
+	}
+
+	atomic.CompareAndSwapUint32(&w.session.senderNonce, w.last, w.next-1)
+	w.next, w.last = 1, 0
+}
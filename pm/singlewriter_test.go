@@ -0,0 +1,169 @@
+package pm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleWriterSession_Sequence(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	store := newStubSessionStore()
+	sender.cfg.SessionStore = store
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	w, err := sender.SingleWriter(sessionID, 10)
+	require.Nil(err)
+
+	// Batches use consecutive nonces across blocks
+	expNonce := uint32(1)
+	for _, size := range []int{3, 4, 5, 1, 12, 2} {
+		batch, err := w.CreateTicketBatch(size)
+		require.Nil(err)
+		require.Len(batch.SenderParams, size)
+		for _, senderParams := range batch.SenderParams {
+			assert.Equal(expNonce, senderParams.SenderNonce)
+			expNonce++
+		}
+	}
+	assert.Equal(uint64(27), mustLoadSession(t, sender, sessionID).ticketsCreated)
+
+	// The session's nonce is advanced and persisted by a block at a time
+	assert.Equal(uint32(35), mustLoadSession(t, sender, sessionID).senderNonce)
+	assert.Equal(uint32(35), store.nonces[sessionID])
+
+	// A failed batch does not consume any nonces of the block
+	signer := sender.signer.(*stubSigner)
+	signer.signShouldFail = true
+	_, err = w.CreateTicketBatch(2)
+	assert.NotNil(err)
+	signer.signShouldFail = false
+
+	batch, err := w.CreateTicketBatch(1)
+	require.Nil(err)
+	assert.Equal(uint32(28), batch.SenderParams[0].SenderNonce)
+
+	// Closing gives the unused nonces of the block back to the session
+	w.Close()
+	batch, err = sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Equal(uint32(29), batch.SenderParams[0].SenderNonce)
+}
+
+func TestSingleWriterSession_RestartedSession(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	params := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(params)
+
+	w, err := sender.SingleWriter(sessionID, 10)
+	require.Nil(err)
+	_, err = w.CreateTicketBatch(2)
+	require.Nil(err)
+
+	sender.EndSession(sessionID)
+	_, err = w.CreateTicketBatch(1)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	// Nonces are reserved from the new session
+	require.Equal(sessionID, sender.StartSession(params))
+	batch, err := w.CreateTicketBatch(1)
+	require.Nil(err)
+	assert.Equal(uint32(1), batch.SenderParams[0].SenderNonce)
+}
+
+func TestSingleWriterSession_MaxNoncePerSession(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.MaxNoncePerSession = 5
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	w, err := sender.SingleWriter(sessionID, 10)
+	require.Nil(err)
+
+	// Only the needed nonces are reserved if a full block would exceed the limit
+	batch, err := w.CreateTicketBatch(5)
+	require.Nil(err)
+	assert.Equal(uint32(5), batch.SenderParams[4].SenderNonce)
+
+	_, err = w.CreateTicketBatch(1)
+	assert.Equal(ErrSessionNonceLimit, errors.Cause(err))
+}
+
+func TestSingleWriter_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.SingleWriter("foo", 10)
+	assert.Equal(ErrUnknownSession, errors.Cause(err))
+
+	_, err = sender.SingleWriter(sessionID, 0)
+	assert.EqualError(err, "block size must be greater than 0")
+
+	sender.cfg.StrictSequential = true
+	_, err = sender.SingleWriter(sessionID, 10)
+	assert.EqualError(err, "single writer sessions are not supported with StrictSequential")
+}
+
+func BenchmarkSingleWriterSession(b *testing.B) {
+	const size = 10
+
+	create := map[string]func(sender *sender, sessionID string) func() error{
+		"CreateTicketBatch": func(sender *sender, sessionID string) func() error {
+			return func() error {
+				_, err := sender.CreateTicketBatch(sessionID, size)
+				return err
+			}
+		},
+		"SingleWriterSession": func(sender *sender, sessionID string) func() error {
+			w, err := sender.SingleWriter(sessionID, 1000)
+			if err != nil {
+				return func() error { return err }
+			}
+			return func() error {
+				_, err := w.CreateTicketBatch(size)
+				return err
+			}
+		},
+	}
+
+	// The session's nonce is persisted once per batch by CreateTicketBatch and once per block by a SingleWriterSession
+	stores := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"MemoryStore", 0},
+		{"SlowStore", 100 * time.Microsecond},
+	}
+
+	for _, store := range stores {
+		for _, name := range []string{"CreateTicketBatch", "SingleWriterSession"} {
+			b.Run(fmt.Sprintf("%v/%v", name, store.name), func(b *testing.B) {
+				sender := defaultSender(nil)
+				sender.cfg.SessionStore = &slowSessionStore{stubSessionStore: newStubSessionStore(), delay: store.delay}
+				sessionID := sender.StartSession(defaultTicketParams(nil, RandAddress()))
+				createBatch := create[name](sender, sessionID)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := createBatch(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
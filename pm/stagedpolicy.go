@@ -0,0 +1,35 @@
+package pm
+
+import (
+	"github.com/golang/glog"
+)
+
+// StagedPolicyMode controls whether the checks of a SenderConfig.StagedPolicy reject ticket params
+type StagedPolicyMode int
+
+const (
+	// StagedPolicyShadow only logs and emits a ShadowValidationFailed event when the staged policy would
+	// reject ticket params so that the impact of the policy can be observed before it is enforced
+	StagedPolicyShadow StagedPolicyMode = iota
+	// StagedPolicyEnforce rejects ticket params that fail the staged policy
+	StagedPolicyEnforce
+)
+
+// validateStagedPolicy runs the configured StagedPolicy after the regular checks passed. In shadow mode
+// a rejection is logged and emitted and nil is returned
+func (s *sender) validateStagedPolicy(ticketParams *TicketParams, numTickets int, info *SenderInfo) error {
+	if s.cfg.StagedPolicy == nil {
+		return nil
+	}
+
+	err := s.cfg.StagedPolicy(ticketParams, numTickets, *info)
+	if err == nil || s.cfg.StagedPolicyMode == StagedPolicyEnforce {
+		return err
+	}
+
+	sessionID := s.sessionID(ticketParams)
+	glog.Warningf("Staged policy would reject ticket params sessionID=%v numTickets=%v err=%v", sessionID, numTickets, err)
+	s.emit(SenderEvent{Type: ShadowValidationFailed, SessionID: sessionID, Err: err})
+
+	return nil
+}
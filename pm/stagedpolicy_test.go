@@ -0,0 +1,69 @@
+package pm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStagedPolicy(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	minEV := big.NewRat(10, 1)
+	errBelowMinEV := errors.New("ticket EV below min EV")
+
+	sender := defaultSender(t)
+	sender.cfg.StagedPolicy = func(params *TicketParams, numTickets int, info SenderInfo) error {
+		if ticketEV(params.FaceValue, params.WinProb).Cmp(minEV) < 0 {
+			return errBelowMinEV
+		}
+		return nil
+	}
+
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+	drainEvents(sender)
+
+	// In shadow mode the ticket is created and the would-be rejection is emitted
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+	assert.Len(batch.SenderParams, 1)
+
+	var shadowEvents []SenderEvent
+	for len(sender.events) > 0 {
+		if event := <-sender.events; event.Type == ShadowValidationFailed {
+			shadowEvents = append(shadowEvents, event)
+		}
+	}
+	require.Len(shadowEvents, 1)
+	assert.Equal(sessionID, shadowEvents[0].SessionID)
+	assert.Equal(errBelowMinEV, shadowEvents[0].Err)
+
+	// The regular checks still reject ticket params in shadow mode
+	tooHigh := defaultTicketParams(t, RandAddress())
+	tooHigh.FaceValue = big.NewInt(50001)
+	assert.EqualError(sender.ValidateTicketParams(&tooHigh), maxFaceValueErrStr(big.NewInt(50001), big.NewInt(50000)))
+
+	// Once enforced the staged policy rejects the ticket params
+	sender.cfg.StagedPolicyMode = StagedPolicyEnforce
+	drainEvents(sender)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok := err.(ValidationError)
+	assert.True(ok)
+	assert.Equal(errBelowMinEV, err.(ValidationError).error)
+	assert.Equal(errBelowMinEV, sender.ValidateTicketParams(&ticketParams))
+	for len(sender.events) > 0 {
+		assert.NotEqual(ShadowValidationFailed, (<-sender.events).Type)
+	}
+
+	// Ticket params that pass the staged policy are accepted
+	accepted := defaultTicketParams(t, RandAddress())
+	accepted.FaceValue = big.NewInt(20)
+	accepted.WinProb = new(big.Int).Set(maxWinProb)
+	sender.maxEV = big.NewRat(100, 1)
+	assert.Nil(sender.ValidateTicketParams(&accepted))
+}
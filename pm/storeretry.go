@@ -0,0 +1,77 @@
+package pm
+
+import (
+	"time"
+)
+
+// defaultSessionStoreRetryTimeout is the default total time that a failed SessionStore call is retried for
+const defaultSessionStoreRetryTimeout = time.Second
+
+// RetryPolicy is an interface which describes an object capable of deciding whether
+// and when a failed SessionStore call is retried
+type RetryPolicy interface {
+	// Backoff returns the delay before the retry with the provided attempt number, starting at 1, of a
+	// call that failed with err and false if the call should not be retried
+	Backoff(attempt int, err error) (time.Duration, bool)
+}
+
+// ExponentialBackoff is a RetryPolicy that retries every failed call with a delay that
+// starts at InitialInterval and is doubled after every retry up to MaxInterval
+type ExponentialBackoff struct {
+	InitialInterval time.Duration
+
+	// MaxInterval is the max delay between retries. If zero, the delay is not capped
+	MaxInterval time.Duration
+}
+
+// Backoff returns InitialInterval * 2^(attempt-1) capped at MaxInterval
+func (b ExponentialBackoff) Backoff(attempt int, err error) (time.Duration, bool) {
+	delay := b.InitialInterval
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if b.MaxInterval > 0 && delay >= b.MaxInterval {
+			return b.MaxInterval, true
+		}
+	}
+
+	if b.MaxInterval > 0 && delay > b.MaxInterval {
+		return b.MaxInterval, true
+	}
+
+	return delay, true
+}
+
+// retrySessionStore calls op and retries it with the configured SessionStoreRetry policy until it succeeds, the
+// policy stops retrying, the next retry would start after SessionStoreRetryTimeout passed since the first call
+// or the sender is stopped. The error of the last call is returned if op does not succeed
+func (s *sender) retrySessionStore(op func() error) error {
+	err := op()
+	if err == nil || s.cfg.SessionStoreRetry == nil {
+		return err
+	}
+
+	timeout := s.cfg.SessionStoreRetryTimeout
+	if timeout <= 0 {
+		timeout = defaultSessionStoreRetryTimeout
+	}
+	deadline := timeNow().Add(timeout)
+
+	for attempt := 1; ; attempt++ {
+		delay, ok := s.cfg.SessionStoreRetry.Backoff(attempt, err)
+		if !ok || timeNow().Add(delay).After(deadline) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.quit:
+			timer.Stop()
+			return err
+		}
+
+		if err = op(); err == nil {
+			return nil
+		}
+	}
+}
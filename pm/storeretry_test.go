@@ -0,0 +1,102 @@
+package pm
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySessionStore fails the first failures calls to Save and Load
+type flakySessionStore struct {
+	*stubSessionStore
+	failures int
+	calls    int
+	mu       sync.Mutex
+}
+
+func (ss *flakySessionStore) fail() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.calls++
+	if ss.calls <= ss.failures {
+		return fmt.Errorf("flaky session store error")
+	}
+	return nil
+}
+
+func (ss *flakySessionStore) Save(sessionID string, senderNonce uint32) error {
+	if err := ss.fail(); err != nil {
+		return err
+	}
+	return ss.stubSessionStore.Save(sessionID, senderNonce)
+}
+
+func (ss *flakySessionStore) Load(sessionID string) (uint32, error) {
+	if err := ss.fail(); err != nil {
+		return 0, err
+	}
+	return ss.stubSessionStore.Load(sessionID)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	b := ExponentialBackoff{InitialInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond}
+	for attempt, expected := range []time.Duration{10, 20, 40, 50, 50} {
+		delay, ok := b.Backoff(attempt+1, nil)
+		assert.True(ok)
+		assert.Equal(expected*time.Millisecond, delay)
+	}
+
+	// Without MaxInterval the delay is not capped
+	delay, _ := ExponentialBackoff{InitialInterval: time.Millisecond}.Backoff(5, nil)
+	assert.Equal(16*time.Millisecond, delay)
+}
+
+func TestSessionStoreRetry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	store := &flakySessionStore{stubSessionStore: newStubSessionStore(), failures: 2}
+	sender.cfg.SessionStore = store
+	sender.cfg.StrictPersistence = true
+	sender.cfg.SessionStoreRetry = ExponentialBackoff{InitialInterval: time.Millisecond}
+
+	// The save fails twice and succeeds within the retry budget
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+	store.calls = 0
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+	assert.Len(batch.SenderParams, 2)
+	assert.Equal(3, store.calls)
+	assert.Equal(uint32(2), store.nonces[sessionID])
+
+	// The load fails twice and succeeds within the retry budget
+	store.calls = 0
+	_, err = sender.StartSessionWithPolicy(defaultTicketParams(t, RandAddress()), SessionPolicy{})
+	require.Nil(err)
+	assert.Equal(3, store.calls)
+
+	// The error is returned once the next retry would exceed the retry budget
+	store.calls = 0
+	store.failures = 100
+	sender.cfg.SessionStoreRetryTimeout = 10 * time.Millisecond
+	sender.cfg.SessionStoreRetry = ExponentialBackoff{InitialInterval: 4 * time.Millisecond}
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Contains(err.Error(), "flaky session store error")
+	// Retries after 4ms and 4+8ms would exceed the budget of 10ms
+	assert.Equal(2, store.calls)
+
+	// Without a retry policy, failed calls are not retried
+	store.calls = 0
+	sender.cfg.SessionStoreRetry = nil
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Contains(err.Error(), "flaky session store error")
+	assert.Equal(1, store.calls)
+}
@@ -1,14 +1,18 @@
 package pm
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/stretchr/testify/mock"
 )
@@ -199,6 +203,45 @@ func (v *stubValidator) IsWinningTicket(ticket *Ticket, sig []byte, recipientRan
 	return v.isWinningTicket
 }
 
+type stubSessionStore struct {
+	nonces         map[string]uint32
+	saveShouldFail bool
+	loadShouldFail bool
+	lock           sync.Mutex
+}
+
+func newStubSessionStore() *stubSessionStore {
+	return &stubSessionStore{
+		nonces: make(map[string]uint32),
+	}
+}
+
+func (ss *stubSessionStore) Save(sessionID string, senderNonce uint32) error {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if ss.saveShouldFail {
+		return fmt.Errorf("stub session store save error")
+	}
+
+	if senderNonce > ss.nonces[sessionID] {
+		ss.nonces[sessionID] = senderNonce
+	}
+
+	return nil
+}
+
+func (ss *stubSessionStore) Load(sessionID string) (uint32, error) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if ss.loadShouldFail {
+		return 0, fmt.Errorf("stub session store load error")
+	}
+
+	return ss.nonces[sessionID], nil
+}
+
 type stubSigner struct {
 	account         accounts.Account
 	saveSignRequest bool
@@ -228,6 +271,34 @@ func (s *stubSigner) Account() accounts.Account {
 	return s.account
 }
 
+type stubKeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func newStubKeySigner() *stubKeySigner {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return &stubKeySigner{key: key}
+}
+
+func (s *stubKeySigner) Sign(msg []byte) ([]byte, error) {
+	sig, err := crypto.Sign(accounts.TextHash(msg), s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the V param to 27 or 28
+	sig[64] += 27
+
+	return sig, nil
+}
+
+func (s *stubKeySigner) Account() accounts.Account {
+	return accounts.Account{Address: crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
 type stubTimeManager struct {
 	round              *big.Int
 	blkHash            [32]byte
@@ -440,6 +511,13 @@ func (m *MockSender) StartSession(ticketParams TicketParams) string {
 	return args.String(0)
 }
 
+// StartSessionWithPolicy creates a session for a given set of ticket params using a policy
+// that overrides the sender's defaults for the session
+func (m *MockSender) StartSessionWithPolicy(ticketParams TicketParams, policy SessionPolicy) (string, error) {
+	args := m.Called(ticketParams, policy)
+	return args.String(0), args.Error(1)
+}
+
 // EV returns the ticket EV for a session
 func (m *MockSender) EV(sessionID string) (*big.Rat, error) {
 	args := m.Called(sessionID)
@@ -464,8 +542,469 @@ func (m *MockSender) CreateTicketBatch(sessionID string, size int) (*TicketBatch
 	return batch, args.Error(1)
 }
 
+// CreateTicketBatchCtx returns a ticket batch of the specified size unless the context is done
+func (m *MockSender) CreateTicketBatchCtx(ctx context.Context, sessionID string, size int) (*TicketBatch, error) {
+	args := m.Called(ctx, sessionID, size)
+
+	var batch *TicketBatch
+	if args.Get(0) != nil {
+		batch = args.Get(0).(*TicketBatch)
+	}
+
+	return batch, args.Error(1)
+}
+
+// MightHaveIssued returns false if a nonce was definitely not issued for a session
+func (m *MockSender) MightHaveIssued(sessionID string, nonce uint32) bool {
+	args := m.Called(sessionID, nonce)
+	return args.Bool(0)
+}
+
+// CreateTicketBatchAsync creates a ticket batch of the specified size on a new goroutine
+func (m *MockSender) CreateTicketBatchAsync(ctx context.Context, sessionID string, size int) <-chan BatchResult {
+	args := m.Called(ctx, sessionID, size)
+	return args.Get(0).(<-chan BatchResult)
+}
+
+// CreateTicketBatchWithKey returns a ticket batch of the specified size for an idempotency key
+func (m *MockSender) CreateTicketBatchWithKey(sessionID string, size int, key string) (*TicketBatch, error) {
+	args := m.Called(sessionID, size, key)
+
+	var batch *TicketBatch
+	if args.Get(0) != nil {
+		batch = args.Get(0).(*TicketBatch)
+	}
+
+	return batch, args.Error(1)
+}
+
+// SingleWriter returns a SingleWriterSession that creates tickets for a session from a single goroutine
+func (m *MockSender) SingleWriter(sessionID string, blockSize int) (*SingleWriterSession, error) {
+	args := m.Called(sessionID, blockSize)
+
+	var w *SingleWriterSession
+	if args.Get(0) != nil {
+		w = args.Get(0).(*SingleWriterSession)
+	}
+
+	return w, args.Error(1)
+}
+
 // ValidateTicketParams checks if ticket params are acceptable
 func (m *MockSender) ValidateTicketParams(ticketParams *TicketParams) error {
 	args := m.Called(ticketParams)
 	return args.Error(0)
 }
+
+// ListSessions returns information about all sessions ordered by session ID
+func (m *MockSender) ListSessions() []SessionInfo {
+	args := m.Called()
+
+	var infos []SessionInfo
+	if args.Get(0) != nil {
+		infos = args.Get(0).([]SessionInfo)
+	}
+
+	return infos
+}
+
+// AdvanceNonce sets the nonce of a session to the provided value if it is greater
+// than the session's current nonce
+func (m *MockSender) AdvanceNonce(sessionID string, to uint32) error {
+	args := m.Called(sessionID, to)
+	return args.Error(0)
+}
+
+// WithReservedNonces reserves count consecutive nonces for a session, calls fn with the first
+// reserved nonce and rolls back the reservation if fn fails and no later nonce was issued
+func (m *MockSender) WithReservedNonces(sessionID string, count int, fn func(start uint32) error) error {
+	args := m.Called(sessionID, count, fn)
+	return args.Error(0)
+}
+
+// RecipientCommittedEV returns the total EV of the tickets created for a recipient across all sessions
+func (m *MockSender) RecipientCommittedEV(recipient ethcommon.Address) *big.Rat {
+	args := m.Called(recipient)
+	return args.Get(0).(*big.Rat)
+}
+
+// MarkTrusted disables ticket params validation when creating tickets for a session
+func (m *MockSender) MarkTrusted(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+// IssuanceLog returns the most recent tickets created for a session ordered from oldest to newest
+func (m *MockSender) IssuanceLog(sessionID string) ([]IssuanceRecord, error) {
+	args := m.Called(sessionID)
+
+	var records []IssuanceRecord
+	if args.Get(0) != nil {
+		records = args.Get(0).([]IssuanceRecord)
+	}
+
+	return records, args.Error(1)
+}
+
+// VerifyTicket checks if a ticket matches the ticket that the sender would create for a session
+func (m *MockSender) VerifyTicket(sessionID string, ticket *Ticket) error {
+	args := m.Called(sessionID, ticket)
+	return args.Error(0)
+}
+
+// EndSession removes a session
+func (m *MockSender) EndSession(sessionID string) {
+	m.Called(sessionID)
+}
+
+// Events returns a channel that receives events describing changes in the state of the sender
+func (m *MockSender) Events() <-chan SenderEvent {
+	args := m.Called()
+
+	var events <-chan SenderEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).(<-chan SenderEvent)
+	}
+
+	return events
+}
+
+// DroppedEvents returns the number of events that were dropped because the events channel was full
+func (m *MockSender) DroppedEvents() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+// RefreshBatchExcept re-signs the tickets of a batch except for the tickets with the skipped nonces
+func (m *MockSender) RefreshBatchExcept(sessionID string, oldBatch *TicketBatch, skipNonces []uint32) (*TicketBatch, error) {
+	args := m.Called(sessionID, oldBatch, skipNonces)
+
+	var batch *TicketBatch
+	if args.Get(0) != nil {
+		batch = args.Get(0).(*TicketBatch)
+	}
+
+	return batch, args.Error(1)
+}
+
+// RefreshBatch re-signs the tickets of a batch using the current round's expiration params
+func (m *MockSender) RefreshBatch(sessionID string, oldBatch *TicketBatch) (*TicketBatch, error) {
+	args := m.Called(sessionID, oldBatch)
+
+	var batch *TicketBatch
+	if args.Get(0) != nil {
+		batch = args.Get(0).(*TicketBatch)
+	}
+
+	return batch, args.Error(1)
+}
+
+// PeekTicket returns a signed sample ticket for a session that is not a valid payment
+func (m *MockSender) PeekTicket(sessionID string) (*Ticket, []byte, error) {
+	args := m.Called(sessionID)
+
+	var ticket *Ticket
+	if args.Get(0) != nil {
+		ticket = args.Get(0).(*Ticket)
+	}
+
+	var sig []byte
+	if args.Get(1) != nil {
+		sig = args.Get(1).([]byte)
+	}
+
+	return ticket, sig, args.Error(2)
+}
+
+// SigningStats returns the latency percentiles of the signer's Sign calls
+func (m *MockSender) SigningStats() SigningStats {
+	args := m.Called()
+	return args.Get(0).(SigningStats)
+}
+
+// CreateBatchSplitByRound returns ticket batches for a contiguous sequence of size nonces
+func (m *MockSender) CreateBatchSplitByRound(sessionID string, size int) ([]*TicketBatch, error) {
+	args := m.Called(sessionID, size)
+
+	var batches []*TicketBatch
+	if args.Get(0) != nil {
+		batches = args.Get(0).([]*TicketBatch)
+	}
+
+	return batches, args.Error(1)
+}
+
+// SnapshotNonces returns the current nonce of every session keyed by session ID
+func (m *MockSender) SnapshotNonces() map[string]uint32 {
+	args := m.Called()
+
+	var nonces map[string]uint32
+	if args.Get(0) != nil {
+		nonces = args.Get(0).(map[string]uint32)
+	}
+
+	return nonces
+}
+
+// DebugDump returns the state of all sessions for debugging
+func (m *MockSender) DebugDump() ([]byte, error) {
+	args := m.Called()
+
+	var dump []byte
+	if args.Get(0) != nil {
+		dump = args.Get(0).([]byte)
+	}
+
+	return dump, args.Error(1)
+}
+
+// Start initiates the helper goroutines for the sender
+func (m *MockSender) Start() {
+	m.Called()
+}
+
+// Stop signals the sender's helper goroutines to exit
+func (m *MockSender) Stop() {
+	m.Called()
+}
+
+// CreateMultiRecipientBatch creates a ticket batch for each request and groups them
+func (m *MockSender) CreateMultiRecipientBatch(requests []BatchRequest) (*MultiRecipientBatch, error) {
+	args := m.Called(requests)
+
+	var batch *MultiRecipientBatch
+	if args.Get(0) != nil {
+		batch = args.Get(0).(*MultiRecipientBatch)
+	}
+
+	return batch, args.Error(1)
+}
+
+// DepositRunway estimates how long the sender's deposit can back tickets for a session
+func (m *MockSender) DepositRunway(sessionID string) (time.Duration, error) {
+	args := m.Called(sessionID)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+// HealthScore returns a score between 0 and 1 for a session
+func (m *MockSender) HealthScore(sessionID string) (float64, error) {
+	args := m.Called(sessionID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+// ValidateTicketParamsCtx checks if ticket params are acceptable unless the context is done
+func (m *MockSender) ValidateTicketParamsCtx(ctx context.Context, ticketParams *TicketParams) error {
+	args := m.Called(ctx, ticketParams)
+	return args.Error(0)
+}
+
+// SessionsForRecipient returns information about the sessions for a recipient
+func (m *MockSender) SessionsForRecipient(recipient ethcommon.Address) []SessionInfo {
+	args := m.Called(recipient)
+
+	var infos []SessionInfo
+	if args.Get(0) != nil {
+		infos = args.Get(0).([]SessionInfo)
+	}
+
+	return infos
+}
+
+// CanIssueBatch checks whether a batch of size tickets can currently be created for a session
+func (m *MockSender) CanIssueBatch(sessionID string, size int) (bool, string, error) {
+	args := m.Called(sessionID, size)
+	return args.Bool(0), args.String(1), args.Error(2)
+}
+
+// CreateTicketAtRound returns a signed ticket for a session with expiration params for a round
+func (m *MockSender) CreateTicketAtRound(sessionID string, round int64, blockHash [32]byte) (*Ticket, []byte, error) {
+	args := m.Called(sessionID, round, blockHash)
+
+	var ticket *Ticket
+	if args.Get(0) != nil {
+		ticket = args.Get(0).(*Ticket)
+	}
+
+	var sig []byte
+	if args.Get(1) != nil {
+		sig = args.Get(1).([]byte)
+	}
+
+	return ticket, sig, args.Error(2)
+}
+
+// CreateTicketWithAux returns a signed ticket for a session that is bound to an aux data hash
+func (m *MockSender) CreateTicketWithAux(sessionID string, auxDataHash ethcommon.Hash) (*Ticket, []byte, error) {
+	args := m.Called(sessionID, auxDataHash)
+
+	var ticket *Ticket
+	if args.Get(0) != nil {
+		ticket = args.Get(0).(*Ticket)
+	}
+
+	var sig []byte
+	if args.Get(1) != nil {
+		sig = args.Get(1).([]byte)
+	}
+
+	return ticket, sig, args.Error(2)
+}
+
+// Ready checks that tickets can be created for a session
+func (m *MockSender) Ready(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+// MarkSessionStale flags a session so that creating tickets for it fails
+func (m *MockSender) MarkSessionStale(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+// EndSessionSync removes a session after its nonce is persisted
+func (m *MockSender) EndSessionSync(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+// AdaptiveBatchSize returns the batch size for a session that reaches a target win expectation
+func (m *MockSender) AdaptiveBatchSize(sessionID string, targetWinExpectation *big.Rat) (int, error) {
+	args := m.Called(sessionID, targetWinExpectation)
+	return args.Int(0), args.Error(1)
+}
+
+// EVForFaceValue returns the EV of a ticket with faceValue for a session
+func (m *MockSender) EVForFaceValue(sessionID string, faceValue *big.Int) (*big.Rat, error) {
+	args := m.Called(sessionID, faceValue)
+
+	var ev *big.Rat
+	if args.Get(0) != nil {
+		ev = args.Get(0).(*big.Rat)
+	}
+
+	return ev, args.Error(1)
+}
+
+// LastTicket returns the ticket most recently created for a session and its signature
+func (m *MockSender) LastTicket(sessionID string) (*Ticket, []byte, bool) {
+	args := m.Called(sessionID)
+
+	var ticket *Ticket
+	if args.Get(0) != nil {
+		ticket = args.Get(0).(*Ticket)
+	}
+
+	var sig []byte
+	if args.Get(1) != nil {
+		sig = args.Get(1).([]byte)
+	}
+
+	return ticket, sig, args.Bool(2)
+}
+
+// ValidateParamsBatch checks if each of a list of ticket params is acceptable
+func (m *MockSender) ValidateParamsBatch(paramsList []TicketParams) []error {
+	args := m.Called(paramsList)
+
+	var errs []error
+	if args.Get(0) != nil {
+		errs = args.Get(0).([]error)
+	}
+
+	return errs
+}
+
+// GlobalStats returns ticket creation totals across all sessions
+func (m *MockSender) GlobalStats() GlobalStats {
+	args := m.Called()
+	return args.Get(0).(GlobalStats)
+}
+
+// BuildTicket returns an unsigned ticket for a session and its nonce
+func (m *MockSender) BuildTicket(sessionID string) (*Ticket, uint32, error) {
+	args := m.Called(sessionID)
+
+	var ticket *Ticket
+	if args.Get(0) != nil {
+		ticket = args.Get(0).(*Ticket)
+	}
+
+	return ticket, args.Get(1).(uint32), args.Error(2)
+}
+
+// SignBuilt signs a ticket returned by BuildTicket
+func (m *MockSender) SignBuilt(ticket *Ticket) ([]byte, error) {
+	args := m.Called(ticket)
+
+	var sig []byte
+	if args.Get(0) != nil {
+		sig = args.Get(0).([]byte)
+	}
+
+	return sig, args.Error(1)
+}
+
+// CreateBatchesAtomic creates a ticket batch for every request or for none of them
+func (m *MockSender) CreateBatchesAtomic(requests []BatchRequest) ([]*TicketBatch, error) {
+	args := m.Called(requests)
+
+	var batches []*TicketBatch
+	if args.Get(0) != nil {
+		batches = args.Get(0).([]*TicketBatch)
+	}
+
+	return batches, args.Error(1)
+}
+
+// TicketHash returns the hash of a ticket that is signed by the sender
+func (m *MockSender) TicketHash(ticket *Ticket) ethcommon.Hash {
+	args := m.Called(ticket)
+	return args.Get(0).(ethcommon.Hash)
+}
+
+// Domain returns the scheme under which the sender hashes and signs tickets
+func (m *MockSender) Domain() TicketDomain {
+	args := m.Called()
+	return args.Get(0).(TicketDomain)
+}
+
+// DroppedAudits returns the number of validation decisions that were not recorded
+func (m *MockSender) DroppedAudits() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+// RequiredDeposit returns the minimum deposit needed to back a batch of size tickets for a session
+func (m *MockSender) RequiredDeposit(sessionID string, size int) (*big.Int, error) {
+	args := m.Called(sessionID, size)
+
+	var deposit *big.Int
+	if args.Get(0) != nil {
+		deposit = args.Get(0).(*big.Int)
+	}
+
+	return deposit, args.Error(1)
+}
+
+// MaxFaceValue returns the highest face value that is accepted for tickets of a session
+func (m *MockSender) MaxFaceValue(sessionID string) (*big.Int, error) {
+	args := m.Called(sessionID)
+
+	var maxFaceValue *big.Int
+	if args.Get(0) != nil {
+		maxFaceValue = args.Get(0).(*big.Int)
+	}
+
+	return maxFaceValue, args.Error(1)
+}
+
+// Freeze stops ticket creation for all sessions
+func (m *MockSender) Freeze() {
+	m.Called()
+}
+
+// Unfreeze resumes ticket creation after Freeze
+func (m *MockSender) Unfreeze() {
+	m.Called()
+}
@@ -0,0 +1,162 @@
+package pm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ticketVectorsFile contains the golden test vectors for ticket hashes and signatures. The vectors lock the
+// wire-level hash of tickets, so any change to them breaks compatibility with tickets signed by earlier versions.
+// Other implementations of the ticket hash can use the file to check that they are compatible with the hash
+// signed by the sender. The key in the file is a well known test key and must never be used to hold funds
+const ticketVectorsFile = "testdata/ticket_vectors.json"
+
+// ticketVectors is the contents of ticketVectorsFile
+type ticketVectors struct {
+	// Version is the version of the ticket hashing scheme that produced the hashes of the vectors
+	Version string `json:"version"`
+	// Key is the hex encoded private key used to produce the signatures of the vectors
+	Key string `json:"key"`
+	// Sender is the address of Key and the sender of most vectors
+	Sender  ethcommon.Address `json:"sender"`
+	Vectors []ticketVector    `json:"vectors"`
+}
+
+// ticketVector maps the inputs of NewTicket to the expected ticket hash and to the expected signature
+// over the hash by the vectors' key
+type ticketVector struct {
+	Name string `json:"name"`
+
+	Recipient              ethcommon.Address `json:"recipient"`
+	FaceValue              vectorBigInt      `json:"faceValue"`
+	WinProb                vectorBigInt      `json:"winProb"`
+	RecipientRandHash      ethcommon.Hash    `json:"recipientRandHash"`
+	CreationRound          int64             `json:"creationRound"`
+	CreationRoundBlockHash ethcommon.Hash    `json:"creationRoundBlockHash"`
+	Sender                 ethcommon.Address `json:"sender"`
+	SenderNonce            uint32            `json:"senderNonce"`
+
+	// Hash is the expected result of Ticket.Hash
+	Hash ethcommon.Hash `json:"hash"`
+	// Sig is the expected signature of Hash by the vectors' key using the Ethereum signed message
+	// prefix with a recovery ID of 27 or 28, which is the signature produced by the node's signer
+	Sig hexutil.Bytes `json:"sig"`
+}
+
+func (v *ticketVector) params() TicketParams {
+	return TicketParams{
+		Recipient:         v.Recipient,
+		FaceValue:         v.FaceValue.Int,
+		WinProb:           v.WinProb.Int,
+		RecipientRandHash: v.RecipientRandHash,
+	}
+}
+
+func (v *ticketVector) expirationParams() TicketExpirationParams {
+	return TicketExpirationParams{
+		CreationRound:          v.CreationRound,
+		CreationRoundBlockHash: v.CreationRoundBlockHash,
+	}
+}
+
+// vectorBigInt is a big.Int that is encoded as a decimal string
+type vectorBigInt struct {
+	*big.Int
+}
+
+func (x *vectorBigInt) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("invalid test vector integer %v", s)
+	}
+	x.Int = n
+
+	return nil
+}
+
+func loadTicketVectors(t *testing.T) *ticketVectors {
+	data, err := ioutil.ReadFile(ticketVectorsFile)
+	require.Nil(t, err)
+
+	var vectors ticketVectors
+	require.Nil(t, json.Unmarshal(data, &vectors))
+
+	return &vectors
+}
+
+func vectorSigner(t *testing.T, vectors *ticketVectors) *stubKeySigner {
+	key, err := crypto.HexToECDSA(vectors.Key)
+	require.Nil(t, err)
+	return &stubKeySigner{key: key}
+}
+
+func TestTicketVectors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	vectors := loadTicketVectors(t)
+	signer := vectorSigner(t, vectors)
+	require.Equal(vectors.Sender, signer.Account().Address)
+	require.True(len(vectors.Vectors) >= 12)
+
+	names := make(map[string]bool)
+	for _, v := range vectors.Vectors {
+		assert.False(names[v.Name], "duplicate vector %v", v.Name)
+		names[v.Name] = true
+
+		params := v.params()
+		expirationParams := v.expirationParams()
+		ticket := NewTicket(&params, &expirationParams, v.Sender, v.SenderNonce)
+		assert.Equal(v.Hash, ticket.Hash(), v.Name)
+
+		sig, err := signer.Sign(ticket.Hash().Bytes())
+		require.Nil(err)
+		assert.Equal([]byte(v.Sig), sig, v.Name)
+	}
+}
+
+func TestTicketVectors_Sender(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	vectors := loadTicketVectors(t)
+
+	numVectors := 0
+	for _, v := range vectors.Vectors {
+		// The first nonce issued by a sender is 1
+		if v.Sender != vectors.Sender || v.SenderNonce < 1 {
+			continue
+		}
+		numVectors++
+
+		sender := defaultSender(t)
+		sender.signer = vectorSigner(t, vectors)
+
+		params := v.params()
+		params.ExpirationBlock = big.NewInt(100)
+		sessionID := sender.StartSession(params)
+		require.Nil(sender.MarkTrusted(sessionID))
+		require.Nil(sender.AdvanceNonce(sessionID, v.SenderNonce-1))
+
+		ticket, sig, err := sender.CreateTicketAtRound(sessionID, v.CreationRound, v.CreationRoundBlockHash)
+		require.Nil(err, v.Name)
+		assert.Equal(v.SenderNonce, ticket.SenderNonce, v.Name)
+		assert.Equal(v.Hash, ticket.Hash(), v.Name)
+		assert.Equal([]byte(v.Sig), sig, v.Name)
+	}
+	assert.NotZero(numVectors)
+}
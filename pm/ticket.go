@@ -1,10 +1,15 @@
 package pm
 
 import (
+	"encoding/binary"
+	"fmt"
 	"math/big"
+	"math/bits"
+	"sync"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
 )
 
 // Constants for byte sizes of Solidity types
@@ -54,6 +59,21 @@ func (p *TicketParams) WinProbRat() *big.Rat {
 	return winProbRat(p.WinProb)
 }
 
+// redactedSeed replaces the seed when formatting ticket params because the seed
+// is a secret that the recipient uses to derive its recipientRand
+const redactedSeed = "<redacted>"
+
+// String returns a representation of the ticket params for logging with the seed redacted
+func (p TicketParams) String() string {
+	return fmt.Sprintf("TicketParams{Recipient: %x, FaceValue: %v, WinProb: %v, RecipientRandHash: %x, Seed: %v, ExpirationBlock: %v, PricePerPixel: %v, ExpirationParams: %v}",
+		p.Recipient, p.FaceValue, p.WinProb, p.RecipientRandHash, redactedSeed, p.ExpirationBlock, p.PricePerPixel, p.ExpirationParams.String())
+}
+
+// GoString returns the same representation as String so that the seed is also redacted when formatted with %#v
+func (p TicketParams) GoString() string {
+	return p.String()
+}
+
 // TicketExpirationParams indicates when/how a ticket expires
 type TicketExpirationParams struct {
 	CreationRound int64
@@ -61,6 +81,15 @@ type TicketExpirationParams struct {
 	CreationRoundBlockHash ethcommon.Hash
 }
 
+// String returns a representation of the expiration params for logging
+func (p *TicketExpirationParams) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("{CreationRound: %v, CreationRoundBlockHash: %x}", p.CreationRound, p.CreationRoundBlockHash)
+}
+
 // TicketSenderParams identifies a unique ticket based on a sender's nonce and signature over a ticket hash
 type TicketSenderParams struct {
 	SenderNonce uint32
@@ -99,6 +128,171 @@ func (b *TicketBatch) Tickets() []*Ticket {
 	return tickets
 }
 
+// Flatten returns the tickets in the batch with their signatures. The tickets include the expiration block
+// and price per pixel of the batch's ticket params. The RecipientRand of the signed tickets is not set
+func (b *TicketBatch) Flatten() []SignedTicket {
+	var signed []SignedTicket
+	for i, ticket := range b.Tickets() {
+		ticket.ParamsExpirationBlock = b.ExpirationBlock
+		ticket.PricePerPixel = b.PricePerPixel
+		signed = append(signed, SignedTicket{Ticket: ticket, Sig: b.SenderParams[i].Sig})
+	}
+
+	return signed
+}
+
+// Collapse returns the batch for a list of signed tickets, which is the inverse of TicketBatch.Flatten.
+// An error is returned if the list is empty or if the tickets do not share the same params, sender and
+// expiration params. The Seed and ExpirationParams of the batch's ticket params are not set because
+// they are not included in tickets and the RecipientRand of the signed tickets is ignored
+func Collapse(signed []SignedTicket) (*TicketBatch, error) {
+	if len(signed) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	if signed[0].Ticket == nil {
+		return nil, errors.New("missing ticket 0")
+	}
+
+	first := signed[0].Ticket
+	batch := &TicketBatch{
+		TicketParams: &TicketParams{
+			Recipient:         first.Recipient,
+			FaceValue:         first.FaceValue,
+			WinProb:           first.WinProb,
+			RecipientRandHash: first.RecipientRandHash,
+			ExpirationBlock:   first.ParamsExpirationBlock,
+			PricePerPixel:     first.PricePerPixel,
+		},
+		TicketExpirationParams: first.expirationParams(),
+		Sender:                 first.Sender,
+	}
+
+	for i, st := range signed {
+		if st.Ticket == nil {
+			return nil, fmt.Errorf("missing ticket %v", i)
+		}
+
+		if err := sharesBatchParams(first, st.Ticket); err != nil {
+			return nil, errors.Wrapf(err, "ticket %v does not match ticket 0", i)
+		}
+
+		batch.SenderParams = append(batch.SenderParams, &TicketSenderParams{SenderNonce: st.Ticket.SenderNonce, Sig: st.Sig})
+	}
+
+	return batch, nil
+}
+
+// sharesBatchParams returns an error describing the first field that differs between
+// two tickets other than the sender nonce
+func sharesBatchParams(a, b *Ticket) error {
+	switch {
+	case a.Recipient != b.Recipient:
+		return fmt.Errorf("recipient %x != %x", b.Recipient, a.Recipient)
+	case a.Sender != b.Sender:
+		return fmt.Errorf("sender %x != %x", b.Sender, a.Sender)
+	case !bigIntEqual(a.FaceValue, b.FaceValue):
+		return fmt.Errorf("faceValue %v != %v", b.FaceValue, a.FaceValue)
+	case !bigIntEqual(a.WinProb, b.WinProb):
+		return fmt.Errorf("winProb %v != %v", b.WinProb, a.WinProb)
+	case a.RecipientRandHash != b.RecipientRandHash:
+		return fmt.Errorf("recipientRandHash %x != %x", b.RecipientRandHash, a.RecipientRandHash)
+	case a.CreationRound != b.CreationRound:
+		return fmt.Errorf("creationRound %v != %v", b.CreationRound, a.CreationRound)
+	case a.CreationRoundBlockHash != b.CreationRoundBlockHash:
+		return fmt.Errorf("creationRoundBlockHash %x != %x", b.CreationRoundBlockHash, a.CreationRoundBlockHash)
+	case !bigIntEqual(a.ParamsExpirationBlock, b.ParamsExpirationBlock):
+		return fmt.Errorf("paramsExpirationBlock %v != %v", b.ParamsExpirationBlock, a.ParamsExpirationBlock)
+	case !bigRatEqual(a.PricePerPixel, b.PricePerPixel):
+		return fmt.Errorf("pricePerPixel %v != %v", b.PricePerPixel, a.PricePerPixel)
+	}
+
+	return nil
+}
+
+// BatchID returns a deterministic identifier for the batch that the sender and recipient can both compute.
+// The ID is the keccak256 hash of the concatenation of:
+// [0:20] = Sender
+// [20:52] = RecipientRandHash
+// [52:84] = nonce of the first ticket in the batch (left padded with zero bytes)
+// [84:116] = nonce of the last ticket in the batch (left padded with zero bytes)
+// [116:148] = CreationRound (left padded with zero bytes)
+// The nonces are 0 for a batch without tickets, the RecipientRandHash is zero if the batch has no
+// TicketParams and the CreationRound is 0 if the batch has no TicketExpirationParams
+func (b *TicketBatch) BatchID() [32]byte {
+	var recipientRandHash ethcommon.Hash
+	if b.TicketParams != nil {
+		recipientRandHash = b.RecipientRandHash
+	}
+
+	var firstNonce, lastNonce uint32
+	if len(b.SenderParams) > 0 {
+		firstNonce = b.SenderParams[0].SenderNonce
+		lastNonce = b.SenderParams[len(b.SenderParams)-1].SenderNonce
+	}
+
+	var creationRound int64
+	if b.TicketExpirationParams != nil {
+		creationRound = b.CreationRound
+	}
+
+	buf := make([]byte, addressSize+bytes32Size+uint256Size+uint256Size+uint256Size)
+	i := copy(buf[0:], b.Sender.Bytes())
+	i += copy(buf[i:], recipientRandHash.Bytes())
+	i += copy(buf[i:], ethcommon.LeftPadBytes(new(big.Int).SetUint64(uint64(firstNonce)).Bytes(), uint256Size))
+	i += copy(buf[i:], ethcommon.LeftPadBytes(new(big.Int).SetUint64(uint64(lastNonce)).Bytes(), uint256Size))
+	copy(buf[i:], ethcommon.LeftPadBytes(big.NewInt(creationRound).Bytes(), uint256Size))
+
+	return crypto.Keccak256Hash(buf)
+}
+
+// ReconcileBatch compares the nonces of a batch with the nonces that a recipient acknowledged accepting.
+// missing contains the nonces of the batch that were not accepted in batch order and extra contains
+// the accepted nonces that are not in the batch in the order they were acknowledged. Duplicate nonces
+// are reported once
+func ReconcileBatch(batch *TicketBatch, acceptedNonces []uint32) (missing []uint32, extra []uint32) {
+	accepted := make(map[uint32]bool, len(acceptedNonces))
+	for _, nonce := range acceptedNonces {
+		accepted[nonce] = true
+	}
+
+	inBatch := make(map[uint32]bool)
+	if batch != nil {
+		for _, params := range batch.SenderParams {
+			nonce := params.SenderNonce
+			if !accepted[nonce] && !inBatch[nonce] {
+				missing = append(missing, nonce)
+			}
+			inBatch[nonce] = true
+		}
+	}
+
+	for _, nonce := range acceptedNonces {
+		if !inBatch[nonce] {
+			extra = append(extra, nonce)
+			// Only report duplicates once
+			inBatch[nonce] = true
+		}
+	}
+
+	return missing, extra
+}
+
+// String returns a representation of the batch for logging with the seed of the ticket params redacted
+func (b TicketBatch) String() string {
+	params := "<nil>"
+	if b.TicketParams != nil {
+		params = b.TicketParams.String()
+	}
+
+	return fmt.Sprintf("TicketBatch{%v, ExpirationParams: %v, Sender: %x, Tickets: %v}", params, b.TicketExpirationParams.String(), b.Sender, len(b.SenderParams))
+}
+
+// GoString returns the same representation as String so that the seed is also redacted when formatted with %#v
+func (b TicketBatch) GoString() string {
+	return b.String()
+}
+
 // Ticket is lottery ticket payment in a probabilistic micropayment protocol
 // The expected value of the ticket constitutes the payment and can be
 // calculated using the ticket's face value and winning probability
@@ -136,6 +330,11 @@ type Ticket struct {
 	ParamsExpirationBlock *big.Int
 
 	PricePerPixel *big.Rat
+
+	// AuxDataHash is the hash of an off-chain artifact that the ticket is bound to, e.g. a segment hash.
+	// It is only set for tickets created with CreateTicketWithAux, which are signed over HashWithAux
+	// instead of Hash. See TicketAuxHashVersion
+	AuxDataHash ethcommon.Hash
 }
 
 // NewTicket creates a Ticket instance
@@ -171,6 +370,9 @@ func (t *Ticket) Hash() ethcommon.Hash {
 	return crypto.Keccak256Hash(t.flatten())
 }
 
+// HashFunc returns the hash of a ticket that is signed by a sender
+type HashFunc func(ticket *Ticket) ethcommon.Hash
+
 // AuxData returns the ticket's CreationRound and CreationRoundBlockHash encoded into a byte array:
 // [0:31] = CreationRound (left padded with zero bytes)
 // [32..63] = CreationRoundBlockHash
@@ -189,7 +391,37 @@ func (t *Ticket) expirationParams() *TicketExpirationParams {
 	}
 }
 
+// flatten encodes the ticket's fields into a single buffer that is the only allocation of the encoding
 func (t *Ticket) flatten() []byte {
+	if t.FaceValue.BitLen() > uint256Size*8 || t.WinProb.BitLen() > uint256Size*8 {
+		return t.flattenOversized()
+	}
+
+	auxDataSize := 0
+	if t.CreationRound != 0 || (t.CreationRoundBlockHash != ethcommon.Hash{}) {
+		auxDataSize = uint256Size + bytes32Size
+	}
+
+	buf := make([]byte, addressSize+addressSize+uint256Size+uint256Size+uint256Size+bytes32Size+auxDataSize)
+	i := copy(buf[0:], t.Recipient.Bytes())
+	i += copy(buf[i:], t.Sender.Bytes())
+	i += putUint256(buf[i:], t.FaceValue)
+	i += putUint256(buf[i:], t.WinProb)
+	binary.BigEndian.PutUint32(buf[i+uint256Size-4:], t.SenderNonce)
+	i += uint256Size
+	i += copy(buf[i:], t.RecipientRandHash.Bytes())
+
+	if auxDataSize > 0 {
+		putAuxData(buf[i:], t.CreationRound, t.CreationRoundBlockHash)
+	}
+
+	return buf
+}
+
+// flattenOversized encodes a ticket whose FaceValue or WinProb does not fit in a uint256. Such values are
+// copied unpadded and the fields following them are shifted or truncated, which keeps the encoding of
+// tickets with untrusted values the same as before flatten stopped using ethcommon.LeftPadBytes
+func (t *Ticket) flattenOversized() []byte {
 	auxData := t.AuxData()
 
 	buf := make([]byte, addressSize+addressSize+uint256Size+uint256Size+uint256Size+bytes32Size+len(auxData))
@@ -199,10 +431,7 @@ func (t *Ticket) flatten() []byte {
 	i += copy(buf[i:], ethcommon.LeftPadBytes(t.WinProb.Bytes(), uint256Size))
 	i += copy(buf[i:], ethcommon.LeftPadBytes(new(big.Int).SetUint64(uint64(t.SenderNonce)).Bytes(), uint256Size))
 	i += copy(buf[i:], t.RecipientRandHash.Bytes())
-
-	if len(auxData) > 0 {
-		copy(buf[i:], auxData)
-	}
+	copy(buf[i:], auxData)
 
 	return buf
 }
@@ -213,16 +442,115 @@ func (e *TicketExpirationParams) AuxData() []byte {
 		return []byte{}
 	}
 
-	return append(
-		ethcommon.LeftPadBytes(big.NewInt(e.CreationRound).Bytes(), uint256Size),
-		e.CreationRoundBlockHash.Bytes()...,
-	)
+	auxData := make([]byte, uint256Size+bytes32Size)
+	putAuxData(auxData, e.CreationRound, e.CreationRoundBlockHash)
+
+	return auxData
+}
+
+// putAuxData writes the aux data of a creation round and block hash into the first 64 bytes of buf.
+// The round is encoded as its absolute value like big.Int.Bytes
+func putAuxData(buf []byte, creationRound int64, creationRoundBlockHash ethcommon.Hash) {
+	round := uint64(creationRound)
+	if creationRound < 0 {
+		round = -round
+	}
+
+	binary.BigEndian.PutUint64(buf[uint256Size-8:], round)
+	copy(buf[uint256Size:], creationRoundBlockHash.Bytes())
+}
+
+// putUint256 writes x left padded to 32 bytes into the zeroed buf without allocating and returns the number
+// of bytes written. x must fit in a uint256 and buf must have at least 32 bytes
+func putUint256(buf []byte, x *big.Int) int {
+	const wordSize = bits.UintSize / 8
+	for n, w := range x.Bits() {
+		for j := 0; j < wordSize; j++ {
+			buf[uint256Size-1-n*wordSize-j] = byte(w >> (8 * uint(j)))
+		}
+	}
+
+	return uint256Size
 }
 
 func ticketEV(faceValue *big.Int, winProb *big.Int) *big.Rat {
 	return new(big.Rat).Mul(new(big.Rat).SetInt(faceValue), new(big.Rat).SetFrac(winProb, maxWinProb))
 }
 
+// evScratch holds the values reused by the EV checks of ticket params so that the checks do not allocate
+// once the values have grown to the size of the operands
+type evScratch struct {
+	ev, bound, n, total big.Int
+}
+
+var evScratchPool = sync.Pool{
+	New: func() interface{} { return new(evScratch) },
+}
+
+// cmpTicketEV compares the total EV of numTickets tickets with faceValue and winProb to bound and returns
+// -1, 0 or +1 like big.Rat.Cmp. The comparison is done on the cross products of the fractions so that,
+// unlike comparing the result of ticketEV, no fractions are normalized and no values are allocated
+func cmpTicketEV(faceValue *big.Int, winProb *big.Int, numTickets int, bound *big.Rat) int {
+	sc := evScratchPool.Get().(*evScratch)
+	defer evScratchPool.Put(sc)
+
+	// faceValue * winProb * numTickets / maxWinProb cmp bound.Num / bound.Denom
+	ev := sc.ev.Mul(faceValue, winProb)
+	if numTickets != 1 {
+		ev.Mul(ev, sc.n.SetInt64(int64(numTickets)))
+	}
+
+	return sc.cmpScaledEV(ev, bound)
+}
+
+// cmpScaledEV compares an EV scaled by maxWinProb to bound and returns -1, 0 or +1 like big.Rat.Cmp.
+// The scaled EV is overwritten
+func (sc *evScratch) cmpScaledEV(ev *big.Int, bound *big.Rat) int {
+	if !bound.IsInt() {
+		ev.Mul(ev, bound.Denom())
+	}
+
+	return ev.Cmp(sc.bound.Mul(bound.Num(), maxWinProb))
+}
+
+// ParamsForEV returns ticket params with the provided face value and the win probability required for a
+// ticket to have the target EV. Only FaceValue and WinProb are set in the returned params. The win probability
+// is rounded down, so the EV of the params is at most targetEV and is lower by less than faceValue / (2^256 - 1)
+// if targetEV * (2^256 - 1) is not a multiple of faceValue
+func ParamsForEV(targetEV *big.Rat, faceValue *big.Int) (TicketParams, error) {
+	if faceValue == nil || faceValue.Sign() <= 0 {
+		return TicketParams{}, fmt.Errorf("face value must be greater than 0, but %v provided", faceValue)
+	}
+
+	if targetEV == nil || targetEV.Sign() <= 0 {
+		return TicketParams{}, fmt.Errorf("target EV must be greater than 0, but %v provided", targetEV)
+	}
+
+	if targetEV.Cmp(new(big.Rat).SetInt(faceValue)) > 0 {
+		return TicketParams{}, fmt.Errorf("target EV %v > face value %v", targetEV.FloatString(5), faceValue)
+	}
+
+	// winProb = targetEV * maxWinProb / faceValue
+	num := new(big.Int).Mul(targetEV.Num(), maxWinProb)
+	denom := new(big.Int).Mul(targetEV.Denom(), faceValue)
+
+	return TicketParams{
+		FaceValue: new(big.Int).Set(faceValue),
+		WinProb:   num.Quo(num, denom),
+	}, nil
+}
+
+// WinProbFloat returns a WinProb as a float in the range [0, 1] for display purposes.
+// The result is the nearest float64 to winProb / maxWinProb. A nil winProb returns 0
+func WinProbFloat(winProb *big.Int) float64 {
+	if winProb == nil {
+		return 0
+	}
+
+	f, _ := winProbRat(winProb).Float64()
+	return f
+}
+
 func winProbRat(winProb *big.Int) *big.Rat {
 	return new(big.Rat).SetFrac(winProb, maxWinProb)
 }
@@ -1,12 +1,15 @@
 package pm
 
 import (
+	"fmt"
 	"math"
 	"math/big"
 	"testing"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEV(t *testing.T) {
@@ -69,6 +72,121 @@ func TestWinProbRat(t *testing.T) {
 	assert.Equal("1", ticket.WinProbRat().FloatString(0))
 }
 
+func TestWinProbFloat(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0.0, WinProbFloat(big.NewInt(0)))
+	assert.Equal(0.5, WinProbFloat(new(big.Int).Div(maxWinProb, big.NewInt(2))))
+	assert.Equal(0.25, WinProbFloat(new(big.Int).Div(maxWinProb, big.NewInt(4))))
+	assert.Equal(1.0, WinProbFloat(maxWinProb))
+
+	// Very small win probabilities are not rounded to 0
+	assert.InEpsilon(1.0/1e18, WinProbFloat(new(big.Int).Div(maxWinProb, big.NewInt(1e18))), 1e-9)
+
+	// Incomplete ticket params do not panic
+	assert.Equal(0.0, WinProbFloat(nil))
+}
+
+func TestParamsForEV(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Exact EV if targetEV * maxWinProb is a multiple of faceValue
+	params, err := ParamsForEV(big.NewRat(100, 1), big.NewInt(300))
+	require.Nil(err)
+	assert.Equal(big.NewInt(300), params.FaceValue)
+	assert.Equal(new(big.Int).Div(maxWinProb, big.NewInt(3)), params.WinProb)
+	assert.Zero(ticketEV(params.FaceValue, params.WinProb).Cmp(big.NewRat(100, 1)))
+
+	params, err = ParamsForEV(big.NewRat(3, 17), big.NewInt(1))
+	require.Nil(err)
+	assert.Zero(ticketEV(params.FaceValue, params.WinProb).Cmp(big.NewRat(3, 17)))
+
+	params, err = ParamsForEV(big.NewRat(10, 1), big.NewInt(10))
+	require.Nil(err)
+	assert.Equal(maxWinProb, params.WinProb)
+	assert.Zero(ticketEV(params.FaceValue, params.WinProb).Cmp(big.NewRat(10, 1)))
+
+	// Inexact EV is rounded down by less than faceValue / maxWinProb
+	targetEV := big.NewRat(1, 1)
+	faceValue := big.NewInt(7)
+	params, err = ParamsForEV(targetEV, faceValue)
+	require.Nil(err)
+	ev := ticketEV(params.FaceValue, params.WinProb)
+	assert.True(ev.Cmp(targetEV) < 0)
+	diff := new(big.Rat).Sub(targetEV, ev)
+	assert.True(diff.Cmp(new(big.Rat).SetFrac(faceValue, maxWinProb)) < 0)
+
+	// Face value is copied
+	faceValue.SetInt64(8)
+	assert.Equal(big.NewInt(7), params.FaceValue)
+}
+
+func TestParamsForEV_InvalidInputs_ReturnsError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParamsForEV(big.NewRat(1, 1), nil)
+	assert.EqualError(err, "face value must be greater than 0, but <nil> provided")
+
+	_, err = ParamsForEV(big.NewRat(1, 1), big.NewInt(0))
+	assert.EqualError(err, "face value must be greater than 0, but 0 provided")
+
+	_, err = ParamsForEV(big.NewRat(0, 1), big.NewInt(1))
+	assert.EqualError(err, "target EV must be greater than 0, but 0/1 provided")
+
+	_, err = ParamsForEV(big.NewRat(-1, 1), big.NewInt(1))
+	assert.EqualError(err, "target EV must be greater than 0, but -1/1 provided")
+
+	_, err = ParamsForEV(big.NewRat(3, 2), big.NewInt(1))
+	assert.EqualError(err, "target EV 1.50000 > face value 1")
+}
+
+func TestTicketParams_String_RedactsSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	seed, ok := new(big.Int).SetString("123456789123456789123456789", 10)
+	assert.True(ok)
+	params := TicketParams{
+		Recipient:         ethcommon.Address{1},
+		FaceValue:         big.NewInt(100),
+		WinProb:           big.NewInt(5),
+		RecipientRandHash: ethcommon.Hash{2},
+		Seed:              seed,
+		ExpirationBlock:   big.NewInt(7),
+		PricePerPixel:     big.NewRat(1, 3),
+		ExpirationParams:  &TicketExpirationParams{CreationRound: 8, CreationRoundBlockHash: ethcommon.Hash{9}},
+	}
+
+	batch := &TicketBatch{
+		TicketParams:           &params,
+		TicketExpirationParams: params.ExpirationParams,
+		SenderParams:           []*TicketSenderParams{{SenderNonce: 1}},
+	}
+	info := SessionInfo{SessionID: "foo", TicketParams: params}
+
+	for _, s := range []string{
+		params.String(),
+		fmt.Sprintf("%v", params),
+		fmt.Sprintf("%+v", &params),
+		fmt.Sprintf("%#v", params),
+		fmt.Sprintf("%v", batch),
+		fmt.Sprintf("%#v", batch),
+		fmt.Sprintf("%+v", info),
+	} {
+		assert.NotContains(s, seed.String())
+		assert.NotContains(s, fmt.Sprintf("%x", seed.Bytes()))
+		assert.Contains(s, redactedSeed)
+	}
+
+	assert.Contains(params.String(), "FaceValue: 100")
+	assert.Contains(params.String(), "ExpirationParams: {CreationRound: 8")
+	assert.Contains(batch.String(), "Tickets: 1")
+
+	params.ExpirationParams = nil
+	assert.Contains(params.String(), "ExpirationParams: <nil>")
+	assert.Contains(TicketBatch{}.String(), "TicketBatch{<nil>, ExpirationParams: <nil>")
+}
+
 func TestAuxData(t *testing.T) {
 	round := int64(5)
 	blkHash := ethcommon.BytesToHash(ethcommon.FromHex("7624778dedc75f8b322b9fa1632a610d40b85e106c7d9bf0e743a9ce291b9c6f"))
@@ -304,3 +422,177 @@ func TestTickets(t *testing.T) {
 		checkTicket(batch, i, tickets[i])
 	}
 }
+
+func TestReconcileBatch(t *testing.T) {
+	batch := &TicketBatch{
+		SenderParams: []*TicketSenderParams{{SenderNonce: 1}, {SenderNonce: 2}, {SenderNonce: 3}},
+	}
+
+	tests := []struct {
+		name     string
+		batch    *TicketBatch
+		accepted []uint32
+		missing  []uint32
+		extra    []uint32
+	}{
+		{"perfect match", batch, []uint32{1, 2, 3}, nil, nil},
+		{"perfect match out of order", batch, []uint32{3, 1, 2}, nil, nil},
+		{"missing", batch, []uint32{2}, []uint32{1, 3}, nil},
+		{"none accepted", batch, nil, []uint32{1, 2, 3}, nil},
+		{"extra", batch, []uint32{1, 2, 3, 7, 5}, nil, []uint32{7, 5}},
+		{"missing and extra", batch, []uint32{1, 4}, []uint32{2, 3}, []uint32{4}},
+		{"duplicate accepted nonces", batch, []uint32{1, 1, 2, 3, 4, 4}, nil, []uint32{4}},
+		{"nil batch", nil, []uint32{1}, nil, []uint32{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing, extra := ReconcileBatch(tt.batch, tt.accepted)
+			assert.Equal(t, tt.missing, missing)
+			assert.Equal(t, tt.extra, extra)
+		})
+	}
+}
+
+func TestBatchID(t *testing.T) {
+	assert := assert.New(t)
+
+	newBatch := func() *TicketBatch {
+		return &TicketBatch{
+			TicketParams: &TicketParams{
+				Recipient:         ethcommon.HexToAddress("0x73AEd7b5dEb30222fabE4F0a3D22F4B8e8A2D7fC"),
+				FaceValue:         big.NewInt(100),
+				WinProb:           big.NewInt(50),
+				RecipientRandHash: ethcommon.BytesToHash([]byte("foo")),
+				Seed:              big.NewInt(7),
+			},
+			TicketExpirationParams: &TicketExpirationParams{CreationRound: 10, CreationRoundBlockHash: ethcommon.BytesToHash([]byte("bar"))},
+			Sender:                 ethcommon.HexToAddress("0x1dE8DdF432400E8A8E0a6D0F5b09e4789394C95D"),
+			SenderParams:           []*TicketSenderParams{{SenderNonce: 3, Sig: []byte("a")}, {SenderNonce: 4, Sig: []byte("b")}, {SenderNonce: 5, Sig: []byte("c")}},
+		}
+	}
+
+	id := newBatch().BatchID()
+
+	// The ID matches the documented derivation
+	buf := append(newBatch().Sender.Bytes(), newBatch().RecipientRandHash.Bytes()...)
+	buf = append(buf, ethcommon.LeftPadBytes([]byte{3}, 32)...)
+	buf = append(buf, ethcommon.LeftPadBytes([]byte{5}, 32)...)
+	buf = append(buf, ethcommon.LeftPadBytes([]byte{10}, 32)...)
+	assert.Equal([32]byte(crypto.Keccak256Hash(buf)), id)
+
+	// Identical batches have identical IDs
+	assert.Equal(id, newBatch().BatchID())
+
+	// Fields that are not part of the ID do not change it
+	batch := newBatch()
+	batch.FaceValue = big.NewInt(1)
+	batch.Seed = big.NewInt(1)
+	batch.CreationRoundBlockHash = ethcommon.Hash{}
+	batch.SenderParams[1].SenderNonce = 9
+	batch.SenderParams[0].Sig = []byte("d")
+	assert.Equal(id, batch.BatchID())
+
+	// Fields that are part of the ID change it
+	changes := []func(b *TicketBatch){
+		func(b *TicketBatch) { b.Sender = ethcommon.HexToAddress("0x1") },
+		func(b *TicketBatch) { b.RecipientRandHash = ethcommon.BytesToHash([]byte("baz")) },
+		func(b *TicketBatch) { b.SenderParams[0].SenderNonce = 2 },
+		func(b *TicketBatch) { b.SenderParams = b.SenderParams[:2] },
+		func(b *TicketBatch) { b.CreationRound = 11 },
+		func(b *TicketBatch) { b.TicketExpirationParams = nil },
+		func(b *TicketBatch) { b.SenderParams = nil },
+	}
+	ids := map[[32]byte]bool{id: true}
+	for _, change := range changes {
+		batch := newBatch()
+		change(batch)
+		changed := batch.BatchID()
+		assert.False(ids[changed])
+		ids[changed] = true
+	}
+
+	// Batches without params do not panic
+	assert.NotEqual([32]byte{}, (&TicketBatch{}).BatchID())
+}
+
+func TestFlattenCollapse_RoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	batch := &TicketBatch{
+		TicketParams: &TicketParams{
+			Recipient:         RandAddress(),
+			FaceValue:         big.NewInt(1000),
+			WinProb:           big.NewInt(5),
+			RecipientRandHash: RandHash(),
+			ExpirationBlock:   big.NewInt(100),
+			PricePerPixel:     big.NewRat(1, 3),
+		},
+		TicketExpirationParams: &TicketExpirationParams{CreationRound: 10, CreationRoundBlockHash: RandHash()},
+		Sender:                 RandAddress(),
+		SenderParams: []*TicketSenderParams{
+			{SenderNonce: 1, Sig: RandBytes(42)},
+			{SenderNonce: 2, Sig: RandBytes(42)},
+			{SenderNonce: 5, Sig: RandBytes(42)},
+		},
+	}
+
+	signed := batch.Flatten()
+	require.Len(signed, 3)
+	for i, st := range signed {
+		assert.Equal(batch.SenderParams[i].SenderNonce, st.SenderNonce)
+		assert.Equal(batch.SenderParams[i].Sig, st.Sig)
+		assert.Equal(batch.Tickets()[i].Hash(), st.Hash())
+		assert.Equal(batch.ExpirationBlock, st.ParamsExpirationBlock)
+		assert.Equal(batch.PricePerPixel, st.PricePerPixel)
+	}
+
+	collapsed, err := Collapse(signed)
+	require.Nil(err)
+	assert.Equal(batch, collapsed)
+	assert.Equal(batch.BatchID(), collapsed.BatchID())
+	assert.Equal(signed, collapsed.Flatten())
+
+	assert.Empty((&TicketBatch{}).Flatten())
+}
+
+func TestCollapse_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Collapse(nil)
+	assert.Equal(ErrEmptyBatch, err)
+
+	newSigned := func() []SignedTicket {
+		return (&TicketBatch{
+			TicketParams:           &TicketParams{Recipient: RandAddress(), FaceValue: big.NewInt(1000), WinProb: big.NewInt(5), ExpirationBlock: big.NewInt(100)},
+			TicketExpirationParams: &TicketExpirationParams{CreationRound: 10},
+			SenderParams:           []*TicketSenderParams{{SenderNonce: 1}, {SenderNonce: 2}},
+		}).Flatten()
+	}
+
+	signed := newSigned()
+	signed[0].Ticket = nil
+	_, err = Collapse(signed)
+	assert.EqualError(err, "missing ticket 0")
+
+	signed = newSigned()
+	signed[1].Ticket = nil
+	_, err = Collapse(signed)
+	assert.EqualError(err, "missing ticket 1")
+
+	signed = newSigned()
+	signed[1].FaceValue = big.NewInt(999)
+	_, err = Collapse(signed)
+	assert.EqualError(err, "ticket 1 does not match ticket 0: faceValue 999 != 1000")
+
+	signed = newSigned()
+	signed[1].CreationRound = 11
+	_, err = Collapse(signed)
+	assert.EqualError(err, "ticket 1 does not match ticket 0: creationRound 11 != 10")
+
+	signed = newSigned()
+	signed[1].ParamsExpirationBlock = nil
+	_, err = Collapse(signed)
+	assert.EqualError(err, "ticket 1 does not match ticket 0: paramsExpirationBlock <nil> != 100")
+}
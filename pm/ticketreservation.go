@@ -0,0 +1,68 @@
+package pm
+
+// reserveOpts are the steps of reserveTickets that some ways of creating tickets skip
+type reserveOpts struct {
+	// skipQuota does not count the tickets towards the session's round quota
+	skipQuota bool
+
+	// skipSigningSlot does not wait for a signing slot, e.g. because the tickets are not signed right away
+	// or because a single slot is acquired for the tickets of multiple sessions
+	skipSigningSlot bool
+}
+
+// ticketReservation is the capacity reserved for creating tickets for a session by reserveTickets
+type ticketReservation struct {
+	quota       *quotaReservation
+	recipientEV *recipientEVReservation
+	releaseSlot func()
+}
+
+// reserveTickets loads a session that tickets can be issued for, validates the session for numTickets tickets, reserves
+// the tickets from the session's round quota, reserves their EV from the session's recipient and waits for a signing
+// slot. Every way of creating tickets calls it before allocating nonces so that they all apply the same checks.
+// The reservation must be released once the tickets were created. The reserved quota and EV are given back by release
+// unless commit was called after the tickets were issued. If an error is returned, nothing is reserved
+func (s *sender) reserveTickets(sessionID string, numTickets int, opts reserveOpts) (*session, *ticketReservation, error) {
+	session, err := s.loadIssuableSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.validateSession(sessionID, session, numTickets); err != nil {
+		return nil, nil, err
+	}
+
+	r := &ticketReservation{}
+	if !opts.skipQuota {
+		if r.quota, err = s.reserveRoundQuota(session, numTickets); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if r.recipientEV, err = s.reserveRecipientEV(session, numTickets); err != nil {
+		r.release()
+		return nil, nil, err
+	}
+
+	if !opts.skipSigningSlot {
+		r.releaseSlot = s.acquireSigningSlot(numTickets)
+	}
+
+	return session, r, nil
+}
+
+// commit keeps the reserved quota and EV because the tickets were issued
+func (r *ticketReservation) commit() {
+	r.quota.keep()
+	r.recipientEV.keep()
+}
+
+// release releases the signing slot and gives back the reserved quota and EV unless commit was called
+func (r *ticketReservation) release() {
+	if r.releaseSlot != nil {
+		r.releaseSlot()
+		r.releaseSlot = nil
+	}
+	r.recipientEV.release()
+	r.quota.release()
+}
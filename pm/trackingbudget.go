@@ -0,0 +1,135 @@
+package pm
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Estimated number of bytes retained for each entry of the per-session tracking structures
+// charged against the TrackingMemoryBudget including the sequence number of the entry
+const (
+	// issuanceRecordBytes covers the record, its face value and its hash
+	issuanceRecordBytes = 128
+	// recentNonceBytes covers the nonce in the ring and its entry in the set of counts
+	recentNonceBytes = 32
+)
+
+// trackedStructure is a per-session tracking structure whose entries are charged against a trackingBudget
+type trackedStructure interface {
+	// oldestSeq returns the sequence number of the oldest entry of the structure if it has any entries
+	oldestSeq() (uint64, bool)
+
+	// evict removes entries starting with the oldest entry until at least bytes are freed or the oldest
+	// remaining entry has a sequence number of at least before. At least one entry is removed if the
+	// structure has any entries. It returns the number of bytes freed
+	evict(before uint64, bytes int64) int64
+}
+
+// trackingBudget caps the memory of the tracking structures of all sessions of a sender.
+// Every entry added to a structure is assigned a sequence number that orders the entries of all structures
+// so that the oldest entries across sessions are evicted first when the budget is exceeded
+type trackingBudget struct {
+	// seq is the sequence number of the last entry. It is the first field so that it
+	// is 64-bit aligned for atomic access on 32-bit platforms
+	seq uint64
+
+	// used is the number of bytes currently charged against the budget, accessed atomically
+	used int64
+
+	max int64
+
+	// mu serializes evictions and guards structures. It must not be acquired while holding the lock of a
+	// structure because evictions acquire the locks of the structures while holding mu
+	mu         sync.Mutex
+	structures map[trackedStructure]struct{}
+}
+
+// newTrackingBudget returns a budget of max bytes or nil if max is not positive
+func newTrackingBudget(max int64) *trackingBudget {
+	if max <= 0 {
+		return nil
+	}
+
+	return &trackingBudget{
+		max:        max,
+		structures: make(map[trackedStructure]struct{}),
+	}
+}
+
+// nextSeq returns the sequence number for a new entry
+func (b *trackingBudget) nextSeq() uint64 {
+	return atomic.AddUint64(&b.seq, 1)
+}
+
+// charge adds bytes to the memory charged against the budget. It can be called while holding the lock of a
+// structure. enforce must be called after the lock is released
+func (b *trackingBudget) charge(bytes int64) {
+	atomic.AddInt64(&b.used, bytes)
+}
+
+// register adds a structure to the structures that entries are evicted from
+func (b *trackingBudget) register(t trackedStructure) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.structures[t] = struct{}{}
+}
+
+// unregister removes a structure from the structures that entries are evicted from.
+// The memory of the structure must have been released with charge before
+func (b *trackingBudget) unregister(t trackedStructure) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.structures, t)
+}
+
+// enforce evicts the oldest entries across all structures until the charged memory is within the budget
+func (b *trackingBudget) enforce() {
+	if b == nil || atomic.LoadInt64(&b.used) <= b.max {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for {
+		excess := atomic.LoadInt64(&b.used) - b.max
+		if excess <= 0 {
+			return
+		}
+
+		// Find the structure with the oldest entry and the oldest entry of all other structures
+		// so that the entries of the structure can be evicted up to that entry at once
+		var oldest trackedStructure
+		oldestSeq, nextSeq := uint64(math.MaxUint64), uint64(math.MaxUint64)
+		for t := range b.structures {
+			seq, ok := t.oldestSeq()
+			if !ok {
+				continue
+			}
+
+			if oldest == nil || seq < oldestSeq {
+				oldest, oldestSeq, nextSeq = t, seq, oldestSeq
+			} else if seq < nextSeq {
+				nextSeq = seq
+			}
+		}
+
+		if oldest == nil {
+			return
+		}
+
+		atomic.AddInt64(&b.used, -oldest.evict(nextSeq, excess))
+	}
+}
+
+// usage returns the number of bytes currently charged against the budget
+func (b *trackingBudget) usage() int64 {
+	if b == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&b.used)
+}
@@ -0,0 +1,99 @@
+package pm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackingMemoryBudget(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	budget := int64(4096)
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 100
+	sender.cfg.RecentNonceWindow = 100
+	sender.trackingBudget = newTrackingBudget(budget)
+
+	var sessionIDs []string
+	for i := 0; i < 20; i++ {
+		sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+		sessionIDs = append(sessionIDs, sessionID)
+
+		for j := 0; j < 5; j++ {
+			_, err := sender.CreateTicketBatch(sessionID, 5)
+			require.Nil(err)
+			assert.True(sender.GlobalStats().TrackedMemory <= budget)
+		}
+	}
+	assert.True(sender.GlobalStats().TrackedMemory > budget-issuanceRecordBytes-recentNonceBytes)
+
+	// The oldest data across sessions was evicted first
+	first, err := sender.loadSession(sessionIDs[0])
+	require.Nil(err)
+	assert.Empty(first.issuanceLog.list())
+	assert.Nil(first.recentNonces.check(1, 25))
+
+	last, err := sender.loadSession(sessionIDs[len(sessionIDs)-1])
+	require.Nil(err)
+	records := last.issuanceLog.list()
+	require.NotEmpty(records)
+	assert.Equal(uint32(25), records[len(records)-1].SenderNonce)
+	assert.NotNil(last.recentNonces.check(25, 1))
+
+	// Ending and restarting sessions releases their memory
+	sender.StartSession(last.ticketParams)
+	for _, sessionID := range sessionIDs {
+		sender.EndSession(sessionID)
+	}
+	assert.Zero(sender.GlobalStats().TrackedMemory)
+	assert.Empty(sender.trackingBudget.structures)
+}
+
+func TestTrackingMemoryBudget_NoBudget(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 10
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 20)
+	require.Nil(err)
+	assert.Zero(sender.GlobalStats().TrackedMemory)
+
+	session, err := sender.loadSession(sessionID)
+	require.Nil(err)
+	assert.Len(session.issuanceLog.list(), 10)
+}
+
+func TestTrackingMemoryBudget_Concurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	budget := int64(8192)
+	sender := defaultSender(t)
+	sender.cfg.IssuanceLogSize = 50
+	sender.cfg.RecentNonceWindow = 50
+	sender.trackingBudget = newTrackingBudget(budget)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+			for j := 0; j < 20; j++ {
+				_, err := sender.CreateTicketBatch(sessionID, 3)
+				assert.Nil(err)
+			}
+			sender.EndSession(sessionID)
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(sender.GlobalStats().TrackedMemory)
+}
@@ -0,0 +1,67 @@
+package pm
+
+import (
+	"math/big"
+	"time"
+)
+
+// validationCacheEntry records a successful validation of a session's ticket params
+type validationCacheEntry struct {
+	// info is the sender info that the ticket params were validated with
+	info *SenderInfo
+	// numTickets is the number of tickets that were validated
+	numTickets int
+	expiresAt  time.Time
+}
+
+// cachedSenderInfo returns the sender info of the session's cached validation if the session has a
+// cached validation for at least numTickets tickets that has not expired. Otherwise nil is returned
+func (s *sender) cachedSenderInfo(session *session, numTickets int) *SenderInfo {
+	if s.cfg.ValidationCacheTTL <= 0 {
+		return nil
+	}
+
+	session.validationMu.Lock()
+	defer session.validationMu.Unlock()
+
+	entry := session.validation
+	if entry == nil || numTickets > entry.numTickets || !timeNow().Before(entry.expiresAt) {
+		return nil
+	}
+
+	return entry.info
+}
+
+// cacheValidation records a successful validation of numTickets tickets for a session with the sender info
+func (s *sender) cacheValidation(session *session, numTickets int, info *SenderInfo) {
+	if s.cfg.ValidationCacheTTL <= 0 {
+		return
+	}
+
+	// Copy the info so that the cached info is not changed by the SenderManager
+	cached := *info
+
+	session.validationMu.Lock()
+	defer session.validationMu.Unlock()
+
+	session.validation = &validationCacheEntry{
+		info:       &cached,
+		numTickets: numTickets,
+		expiresAt:  timeNow().Add(s.cfg.ValidationCacheTTL),
+	}
+}
+
+// clearValidation removes the cached validation of a session if the sender's deposit
+// differs from the deposit at the time of the validation. A nil deposit always clears the cache
+func (s *sender) clearValidation(session *session, deposit *big.Int) {
+	session.validationMu.Lock()
+	defer session.validationMu.Unlock()
+
+	if session.validation == nil {
+		return
+	}
+
+	if cached := session.validation.info.Deposit; deposit == nil || cached == nil || cached.Cmp(deposit) != 0 {
+		session.validation = nil
+	}
+}
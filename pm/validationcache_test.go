@@ -0,0 +1,130 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationCache_SkipsSenderInfoWithinTTL(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	sender := defaultSender(t)
+	sender.cfg.ValidationCacheTTL = time.Minute
+	sm := sender.senderManager.(*stubSenderManager)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(err)
+
+	// Cached validation does not fetch the sender info
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 2)
+	assert.Nil(err)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	// Cached validation does not cover larger batches
+	_, err = sender.CreateTicketBatch(sessionID, 3)
+	assert.Equal(sm.err, errors.Cause(err))
+
+	// Cached validation expires after the TTL
+	now = now.Add(time.Minute)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(sm.err, errors.Cause(err))
+}
+
+func TestValidationCache_ChecksParamsExpiration(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ValidationCacheTTL = time.Minute
+	tm := sender.timeManager.(*stubTimeManager)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	tm.lastSeenBlock = big.NewInt(100)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(ErrTicketParamsExpired, errors.Cause(err))
+}
+
+func TestValidationCache_ValidatesSenderWithCachedInfo(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ValidationCacheTTL = time.Minute
+	tm := sender.timeManager.(*stubTimeManager)
+	sm := sender.senderManager.(*stubSenderManager)
+	info := sm.info[sender.signer.Account().Address]
+	info.WithdrawRound = big.NewInt(7)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	// The cached info is not changed by updates to the fetched info
+	info.WithdrawRound = big.NewInt(0)
+	sm.err = errors.New("GetSenderInfo error")
+
+	// The deposit of the cached info unlocks in the next round
+	tm.round = big.NewInt(6)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok := errors.Cause(err).(ErrSenderValidation)
+	assert.True(ok)
+	assert.Contains(err.Error(), "deposit and reserve is set to unlock soon")
+}
+
+func TestValidationCache_DepositChange_ClearsCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sender.cfg.ValidationCacheTTL = time.Minute
+	sm := sender.senderManager.(*stubSenderManager)
+	info := sm.info[sender.signer.Account().Address]
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	// Reconciling with an unchanged deposit keeps the cached validation
+	sender.reconcile()
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+
+	sm.err = nil
+	info.Deposit = big.NewInt(50000)
+	sender.reconcile()
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(sm.err, errors.Cause(err))
+}
+
+func TestValidationCache_Disabled(t *testing.T) {
+	require := require.New(t)
+
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(err)
+
+	sm.err = errors.New("GetSenderInfo error")
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Equal(t, sm.err, errors.Cause(err))
+}
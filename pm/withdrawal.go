@@ -0,0 +1,63 @@
+package pm
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ErrWithdrawalPending is returned when validating ticket params while the sender has a pending withdrawal
+// and the sender is configured with WithdrawalReject
+var ErrWithdrawalPending = errors.New("sender has a pending withdrawal")
+
+// WithdrawalAction is the action taken by the sender when validating ticket params while the sender
+// has initiated a withdrawal of its deposit
+type WithdrawalAction int
+
+const (
+	// WithdrawalIgnore uses the full deposit to validate ticket params. Ticket params are still rejected
+	// if the deposit unlocks in the next round
+	WithdrawalIgnore WithdrawalAction = iota
+	// WithdrawalSubtract subtracts the pending withdrawal from the deposit used to compute the max face value.
+	// If the SenderManager does not report the amount being withdrawn, the whole deposit is considered withdrawn
+	WithdrawalSubtract
+	// WithdrawalReject rejects ticket params with ErrWithdrawalPending while a withdrawal is pending
+	WithdrawalReject
+)
+
+// withdrawalPending returns true if the sender has initiated a withdrawal
+func withdrawalPending(info *SenderInfo) bool {
+	if info.WithdrawRound != nil && info.WithdrawRound.Sign() != 0 {
+		return true
+	}
+
+	return info.PendingWithdrawal != nil && info.PendingWithdrawal.Sign() > 0
+}
+
+// validateWithdrawal returns ErrWithdrawalPending if the sender has a pending withdrawal
+// and the sender is configured with WithdrawalReject
+func (s *sender) validateWithdrawal(info *SenderInfo) error {
+	if s.cfg.WithdrawalAction != WithdrawalReject || !withdrawalPending(info) {
+		return nil
+	}
+
+	return ErrSenderValidation{errors.Wrapf(ErrWithdrawalPending, "withdraw round %v pending withdrawal %v", info.WithdrawRound, info.PendingWithdrawal)}
+}
+
+// usableDeposit returns the part of the sender's deposit that can back tickets
+func (s *sender) usableDeposit(info *SenderInfo) *big.Int {
+	if s.cfg.WithdrawalAction != WithdrawalSubtract || !withdrawalPending(info) {
+		return info.Deposit
+	}
+
+	if info.PendingWithdrawal == nil {
+		return big.NewInt(0)
+	}
+
+	usable := new(big.Int).Sub(info.Deposit, info.PendingWithdrawal)
+	if usable.Sign() < 0 {
+		return big.NewInt(0)
+	}
+
+	return usable
+}
@@ -0,0 +1,59 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTicketParams_PendingWithdrawal(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	info := sender.senderManager.(*stubSenderManager).info[sender.signer.Account().Address]
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(30000)
+
+	// The withdrawal unlocks after the next round and 40000 of the deposit of 100000 is being withdrawn
+	info.WithdrawRound = big.NewInt(10)
+	info.PendingWithdrawal = big.NewInt(40000)
+
+	// The full deposit is used by default
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+
+	// The max face value is (100000 - 40000) / 2 = 30000
+	sender.cfg.WithdrawalAction = WithdrawalSubtract
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+	ticketParams.FaceValue = big.NewInt(30001)
+	assert.EqualError(sender.ValidateTicketParams(&ticketParams), maxFaceValueErrStr(big.NewInt(30001), big.NewInt(30000)))
+
+	// The whole deposit is considered withdrawn if the amount is not reported
+	info.PendingWithdrawal = nil
+	ticketParams.FaceValue = big.NewInt(1)
+	assert.EqualError(sender.ValidateTicketParams(&ticketParams), maxFaceValueErrStr(big.NewInt(1), big.NewInt(0)))
+
+	// Withdrawals of more than the deposit leave no usable deposit
+	info.PendingWithdrawal = big.NewInt(200000)
+	assert.EqualError(sender.ValidateTicketParams(&ticketParams), maxFaceValueErrStr(big.NewInt(1), big.NewInt(0)))
+
+	sender.cfg.WithdrawalAction = WithdrawalReject
+	err := sender.ValidateTicketParams(&ticketParams)
+	_, ok := err.(ErrSenderValidation)
+	assert.True(ok)
+	assert.Equal(ErrWithdrawalPending, errors.Cause(err.(ErrSenderValidation).error))
+	assert.EqualError(err, "withdraw round 10 pending withdrawal 200000: "+ErrWithdrawalPending.Error())
+
+	// Tickets are only created for a session once the withdrawal is cancelled
+	sessionID := sender.StartSession(ticketParams)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	_, ok = err.(ValidationError)
+	assert.True(ok)
+
+	info.WithdrawRound = big.NewInt(0)
+	info.PendingWithdrawal = nil
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+}
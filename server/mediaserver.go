@@ -840,6 +840,6 @@ func (s *LivepeerServer) LatestPlaylist() core.PlaylistManager {
 }
 
 func shouldStopStream(err error) bool {
-	_, ok := err.(pm.ErrSenderValidation)
-	return ok
+	var validationErr pm.ErrSenderValidation
+	return errors.As(err, &validationErr)
 }
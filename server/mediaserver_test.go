@@ -894,6 +894,10 @@ func TestShouldStopStream(t *testing.T) {
 	assert.False(ok)
 	ok = shouldStopStream(pm.ErrSenderValidation{})
 	assert.True(ok)
+	ok = shouldStopStream(pm.ValidationError{pm.ErrSenderValidation{}})
+	assert.True(ok)
+	ok = shouldStopStream(pm.ValidationError{fmt.Errorf("some random error string")})
+	assert.False(ok)
 }
 
 func TestParseManifestID(t *testing.T) {